@@ -39,9 +39,14 @@ func (buffer *Buffer) isPinned() bool {
 	return buffer.pins > 0
 }
 
+// SetModified
+// an lsn of -1, for a change that was not logged, keeps the lsn of the
+// last change logged, the log has to be flushed up to it all the same
 func (buffer *Buffer) SetModified(txNum int, lsn int) {
 	buffer.txNum = txNum
-	buffer.lsn = lsn
+	if lsn >= 0 {
+		buffer.lsn = lsn
+	}
 }
 
 func (buffer *Buffer) pin() {
@@ -54,7 +59,7 @@ func (buffer *Buffer) unpin() {
 
 func (buffer *Buffer) flush() error {
 	if buffer.txNum >= 0 {
-		if err := buffer.logManager.Flush(buffer.lsn); err != nil {
+		if err := buffer.logManager.FlushNow(buffer.lsn); err != nil {
 			return err
 		}
 		if err := buffer.fileManager.Write(buffer.block, buffer.contents); err != nil {
@@ -65,6 +70,20 @@ func (buffer *Buffer) flush() error {
 	return nil
 }
 
+// writeCached
+// writes the buffer for a commit without durability. The block only
+// reaches the operating system, after the log the commit wrote there,
+// so the log is not forced to disk for it
+func (buffer *Buffer) writeCached() error {
+	if buffer.txNum >= 0 {
+		if err := buffer.fileManager.WriteCached(buffer.block, buffer.contents); err != nil {
+			return err
+		}
+		buffer.txNum = -1
+	}
+	return nil
+}
+
 func (buffer *Buffer) assignToBlock(block *file.BlockId) error {
 	if err := buffer.flush(); err != nil {
 		return err
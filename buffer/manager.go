@@ -3,6 +3,7 @@ package buffer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"jadb/file"
 	"jadb/log"
 	"sync"
@@ -17,6 +18,12 @@ type Manager struct {
 	available   int
 	lock        sync.Mutex
 	conditional *sync.Cond
+	fm          *file.Manager
+	lm          *log.Manager
+	// unsynced
+	// the files every transaction had blocks written to since it last
+	// flushed its buffers, to be synced before it commits
+	unsynced map[int]map[string]bool
 }
 
 func NewBufferManager(fm *file.Manager, lm *log.Manager, bufferPoolCount int) (*Manager, error) {
@@ -27,6 +34,9 @@ func NewBufferManager(fm *file.Manager, lm *log.Manager, bufferPoolCount int) (*
 	manager := &Manager{
 		bufferPool: pool,
 		available:  len(pool),
+		fm:         fm,
+		lm:         lm,
+		unsynced:   make(map[int]map[string]bool),
 	}
 	cond := sync.NewCond(&manager.lock)
 	manager.conditional = cond
@@ -81,42 +91,139 @@ func (manager *Manager) Available() int {
 	return manager.available
 }
 
+// FlushAll
+// writes the buffers modified by the transaction and, unless the log is
+// not durable either, syncs every file it wrote blocks to. Recovery only
+// undoes, so the blocks of a transaction have to be on disk before its
+// commit record is. The log is forced and the files synced without the
+// lock, so concurrent commits share a group of the log and do not keep
+// the buffers from being pinned meanwhile
 func (manager *Manager) FlushAll(txNum int) error {
+	manager.lock.Lock()
+	lsn := -1
+	for _, buffer := range manager.bufferPool {
+		if buffer.modifyingTx() == txNum {
+			lsn = max(lsn, buffer.lsn)
+		}
+	}
+	manager.lock.Unlock()
+	if lsn >= 0 {
+		if err := manager.lm.Flush(lsn); err != nil {
+			return err
+		}
+	}
+	files, err := manager.writeAll(txNum)
+	if err != nil || manager.lm.Durability() == log.DurableNone {
+		return err
+	}
+	for filename := range files {
+		if err := manager.fm.Sync(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAll
+// writes the buffers modified by the transaction, their log records
+// already forced, and returns the files it wrote blocks to since it last
+// flushed its buffers. Without durability the blocks are only handed to
+// the operating system like the log
+func (manager *Manager) writeAll(txNum int) (map[string]bool, error) {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
+	cached := manager.lm.Durability() == log.DurableNone
 	for _, buffer := range manager.bufferPool {
 		if buffer.modifyingTx() == txNum {
-			if err := buffer.flush(); err != nil {
-				return err
+			manager.noteWrite(buffer)
+			write := buffer.flush
+			if cached {
+				write = buffer.writeCached
+			}
+			if err := write(); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return nil
+	files := manager.unsynced[txNum]
+	delete(manager.unsynced, txNum)
+	return files, nil
+}
+
+// noteWrite
+// remembers the file of a modified buffer about to be written
+func (manager *Manager) noteWrite(buffer *Buffer) {
+	txNum := buffer.modifyingTx()
+	if txNum < 0 {
+		return
+	}
+	if manager.unsynced[txNum] == nil {
+		manager.unsynced[txNum] = make(map[string]bool)
+	}
+	manager.unsynced[txNum][buffer.block.GetFileName()] = true
 }
 
 func (manager *Manager) tryToPin(block *file.BlockId) (*Buffer, error) {
 	buffer := manager.findExistingBuffer(block)
-	if buffer != nil {
-		return buffer, nil
+	if buffer == nil {
+		buffer = manager.chooseUnpinnedBuffer()
+		if buffer == nil {
+			return nil, nil
+		}
+		manager.noteWrite(buffer)
+		if err := buffer.assignToBlock(block); err != nil {
+			return nil, err
+		}
 	}
+	if !buffer.isPinned() {
+		manager.available--
+	}
+	buffer.pin()
+	return buffer, nil
+}
+
+func (manager *Manager) findExistingBuffer(block *file.BlockId) *Buffer {
 	for _, buffer := range manager.bufferPool {
-		if !buffer.isPinned() {
-			if err := buffer.assignToBlock(block); err != nil {
-				return nil, err
-			}
-			manager.available--
-			buffer.pin()
-			return buffer, nil
+		if buffer.block != nil && buffer.block.Equals(block) {
+			return buffer
 		}
 	}
-	return nil, nil
+	return nil
 }
 
-func (manager *Manager) findExistingBuffer(block *file.BlockId) *Buffer {
+func (manager *Manager) chooseUnpinnedBuffer() *Buffer {
 	for _, buffer := range manager.bufferPool {
-		if buffer.block != nil && buffer.block.Equals(block) && buffer.isPinned() {
+		if !buffer.isPinned() {
 			return buffer
 		}
 	}
 	return nil
 }
+
+// Discard
+// unassigns the buffers holding blocks of the file without writing them,
+// so a file created later with the same name is read from disk. None of
+// them can be pinned
+func (manager *Manager) Discard(filename string) error {
+	return manager.DiscardFrom(filename, 0)
+}
+
+// DiscardFrom
+// unassigns the buffers holding blocks of the file from block first on
+// without writing them, for a file about to be truncated
+func (manager *Manager) DiscardFrom(filename string, first int) error {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	for _, buffer := range manager.bufferPool {
+		if buffer.block == nil || buffer.block.GetFileName() != filename || buffer.block.GetBlockNumber() < first {
+			continue
+		}
+		if buffer.isPinned() {
+			return fmt.Errorf("block %d of %s is still pinned", buffer.block.GetBlockNumber(), filename)
+		}
+		buffer.block = nil
+		buffer.txNum = -1
+		buffer.lsn = -1
+	}
+	return nil
+}
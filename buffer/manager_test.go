@@ -61,6 +61,57 @@ func TestBufferManager(t *testing.T) {
 			"expected %d buffer pool, got %d", env.bufferPoolCount, bm.Available)
 	})
 
+	t.Run("FlushAllSyncsFiles", func(t *testing.T) {
+		bm, err := NewBufferManager(env.fm, env.lm, 2)
+		assert.NoError(err)
+		//a buffer written out for another block still has its file synced
+		buffer, err := bm.Pin(file.NewBlock(env.databaseFile, 0))
+		assert.NoError(err)
+		buffer.SetModified(7, -1)
+		bm.Unpin(buffer)
+		for _, blockNumber := range []int{1, 2} {
+			buffer, err = bm.Pin(file.NewBlock(env.databaseFile, blockNumber))
+			assert.NoError(err)
+			bm.Unpin(buffer)
+		}
+		assert.Equal(map[int]map[string]bool{7: {env.databaseFile: true}}, bm.unsynced)
+		assert.NoError(bm.FlushAll(7))
+		assert.Empty(bm.unsynced)
+	})
+
+	t.Run("DurableNoneCommitsWithoutSync", func(t *testing.T) {
+		lm, err := log.NewLogManagerWithOptions(env.fm, "none.log", log.Options{Durability: log.DurableNone})
+		assert.NoError(err)
+		bm, err := NewBufferManager(env.fm, lm, 2)
+		assert.NoError(err)
+		modify := func(txNum int, blockNumber int, val int) {
+			buffer, err := bm.Pin(file.NewBlock(env.databaseFile, blockNumber))
+			assert.NoError(err)
+			lsn, err := lm.Append([]byte("change"))
+			assert.NoError(err)
+			buffer.Contents().SetInt(0, val)
+			buffer.SetModified(txNum, lsn)
+			bm.Unpin(buffer)
+		}
+
+		//a commit hands its pages to the operating system without a sync
+		modify(11, 0, 42)
+		assert.NoError(bm.FlushAll(11))
+		assert.Equal(0, lm.Syncs())
+		page := file.NewPage(env.fm.BlockSize())
+		assert.NoError(env.fm.Read(file.NewBlock(env.databaseFile, 0), page))
+		assert.Equal(42, page.GetInt(0))
+
+		//a page written back for another block forces the log first
+		modify(12, 0, 43)
+		for _, blockNumber := range []int{1, 2} {
+			buffer, err := bm.Pin(file.NewBlock(env.databaseFile, blockNumber))
+			assert.NoError(err)
+			bm.Unpin(buffer)
+		}
+		assert.Equal(1, lm.Syncs())
+	})
+
 	t.Run("PinUnpinTest", func(t *testing.T) {
 		bm, err := NewBufferManager(env.fm, env.lm, env.bufferPoolCount)
 		assert.NoError(err)
@@ -2,3 +2,9 @@ package constants
 
 // IntSize 8 bytes integer size for pages
 const IntSize = 8
+
+// BoolSize 1 byte boolean size for pages
+const BoolSize = 1
+
+// DoubleSize 8 bytes IEEE 754 double size for pages
+const DoubleSize = 8
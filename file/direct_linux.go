@@ -0,0 +1,7 @@
+package file
+
+import "syscall"
+
+// directFlag
+// the flag opening a file for direct i/o
+const directFlag = syscall.O_DIRECT
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package file
+
+// directFlag
+// direct i/o is only supported on linux
+const directFlag = 0
@@ -1,22 +1,113 @@
 package file
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"unsafe"
 )
 
+// checksumSize
+// every block is stored with a crc32 checksum of its page after it, so a
+// block takes blockSize+checksumSize bytes on disk
+const checksumSize = 4
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CorruptionError
+// a block whose checksum does not match its contents, left by a torn write
+// or a disk that changed it
+type CorruptionError struct {
+	Block *BlockId
+}
+
+func (err *CorruptionError) Error() string {
+	return fmt.Sprintf("block %d of %s is corrupted, its checksum does not match", err.Block.GetBlockNumber(), err.Block.GetFileName())
+}
+
+// SyncPolicy
+// when the writes of a Manager are forced to disk
+type SyncPolicy int
+
+const (
+	// SyncEveryWrite
+	// every write is on disk when Write returns
+	SyncEveryWrite SyncPolicy = iota
+	// SyncOnRequest
+	// writes are only forced to disk by Sync, so they can be lost in a
+	// crash of the machine until then
+	SyncOnRequest
+)
+
+// directAlignment
+// the alignment of the offsets, lengths and buffers of direct i/o
+const directAlignment = 4096
+
+// Options
+// how a Manager does its i/o. DirectIO opens files with O_DIRECT so pages
+// bypass the cache of the operating system, which needs blocks that take
+// a multiple of 4096 bytes on disk with their checksum, a block size of
+// 4092 for instance
+type Options struct {
+	DirectIO   bool
+	SyncPolicy SyncPolicy
+}
+
+// openFile
+// a file of the database. Reads and writes of its blocks go on in
+// parallel under the read lock, appending and truncating take the write
+// lock as they change its size, and so does a scrub so it reads no block
+// half written
+type openFile struct {
+	file *os.File
+	lock sync.RWMutex
+	// closed
+	// the file was removed after it was taken from the open files
+	closed bool
+	// policy
+	// the sync policy of the manager unless SetSyncPolicy changed it
+	policy SyncPolicy
+	// created
+	// the file was created and its directory entry is not synced yet
+	created bool
+}
+
+// Manager
+// reads and writes blocks at their position in the file, so transactions
+// using different blocks do not wait for each other. The lock only guards
+// the map of open files
 type Manager struct {
 	dbDirectory string
 	blockSize   int
-	openFiles   map[string]*os.File
+	options     Options
+	openFiles   map[string]*openFile
 	lock        sync.Mutex
+	// directoryLock
+	// taken while syncing the directory, which can happen under the lock
+	// of a file
+	directoryLock sync.Mutex
 }
 
 func NewFileManager(directory string, blockSize int) (*Manager, error) {
+	return NewFileManagerWithOptions(directory, blockSize, Options{})
+}
+
+func NewFileManagerWithOptions(directory string, blockSize int, options Options) (*Manager, error) {
+	if options.DirectIO {
+		if directFlag == 0 {
+			return nil, fmt.Errorf("direct i/o is not supported on this platform")
+		}
+		if (blockSize+checksumSize)%directAlignment != 0 {
+			return nil, fmt.Errorf("direct i/o needs blocks of a multiple of %d bytes with their %d byte checksum, got a block size of %d",
+				directAlignment, checksumSize, blockSize)
+		}
+	}
 	if _, err := os.Stat(directory); os.IsNotExist(err) {
 		err := os.MkdirAll(directory, 0755)
 		if err != nil {
@@ -41,96 +132,86 @@ func NewFileManager(directory string, blockSize int) (*Manager, error) {
 	return &Manager{
 		dbDirectory: directory,
 		blockSize:   blockSize,
-		openFiles:   make(map[string]*os.File),
+		options:     options,
+		openFiles:   make(map[string]*openFile),
 	}, nil
 }
 
+// Read
+// the page of the block, a *CorruptionError when its checksum does not match
 func (manager *Manager) Read(block *BlockId, page *Page) error {
-	manager.lock.Lock()
-	defer manager.lock.Unlock()
-
-	file, err := manager.GetFile(block.GetFileName())
+	f, err := manager.open(block.GetFileName())
 	if err != nil {
 		return err
 	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return manager.read(f, block, page)
+}
 
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("could not stat file : %v", err)
-	}
-	size := int(stat.Size())
-
-	blockOffset := block.GetBlockNumber() * manager.blockSize
-	if size < blockOffset+manager.blockSize {
-		return fmt.Errorf("the block %d does not exist", block.GetBlockNumber())
-	}
-
-	if _, err := file.Seek(int64(blockOffset), io.SeekStart); err != nil {
-		return fmt.Errorf("could not seek to offset :%v", err)
-	}
-
-	n, err := file.Read(page.Contents())
-	if err != nil {
+func (manager *Manager) read(f *openFile, block *BlockId, page *Page) error {
+	stored := manager.buffer()
+	n, err := f.file.ReadAt(stored, int64(block.GetBlockNumber()*manager.diskBlockSize()))
+	if n < len(stored) {
+		if err == nil || errors.Is(err, io.EOF) {
+			return fmt.Errorf("the block %d does not exist", block.GetBlockNumber())
+		}
 		return fmt.Errorf("could not read block into page: %v", err)
 	}
-
-	if n != manager.blockSize {
-		return fmt.Errorf("got %d bytes expected %d", n, manager.blockSize)
+	if binary.BigEndian.Uint32(stored[manager.blockSize:]) != checksum(stored[:manager.blockSize]) {
+		return &CorruptionError{NewBlock(block.GetFileName(), block.GetBlockNumber())}
 	}
+	copy(page.Contents(), stored[:manager.blockSize])
 	return nil
-
 }
 
+// Write
+// the page with its checksum to the block, in a single write
 func (manager *Manager) Write(block *BlockId, page *Page) error {
-	manager.lock.Lock()
-	defer manager.lock.Unlock()
+	return manager.write(block, page, true)
+}
 
-	file, err := manager.GetFile(block.GetFileName())
+// WriteCached
+// like Write, but the block only reaches the operating system whatever
+// the sync policy of its file
+func (manager *Manager) WriteCached(block *BlockId, page *Page) error {
+	return manager.write(block, page, false)
+}
+
+func (manager *Manager) write(block *BlockId, page *Page, sync bool) error {
+	f, err := manager.open(block.GetFileName())
 	if err != nil {
 		return err
 	}
-	blockOffset := block.GetBlockNumber() * manager.blockSize
-	if _, err := file.Seek(int64(blockOffset), io.SeekStart); err != nil {
-		return fmt.Errorf("could not seek to offset %d: %v", blockOffset, err)
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	blockOffset := block.GetBlockNumber() * manager.diskBlockSize()
+	if _, err := f.file.WriteAt(manager.stored(page.Contents()), int64(blockOffset)); err != nil {
+		return fmt.Errorf("could not write page to file at offset %d: %v", blockOffset, err)
 	}
-	n, err := file.Write(page.Contents())
-	if err != nil {
-		return fmt.Errorf("could not write page to file: %v", err)
+	if !sync {
+		return nil
 	}
-	if n != manager.blockSize {
-		return fmt.Errorf("expected %d bytes, wrote %d bytes", manager.blockSize, n)
-	}
-	if err := file.Sync(); err != nil {
-		return fmt.Errorf("fsync error: %v", err)
-	}
-	return nil
+	return manager.syncWrite(f)
 }
 
 func (manager *Manager) Append(filename string) (*BlockId, error) {
-	manager.lock.Lock()
-	defer manager.lock.Unlock()
-	file, err := manager.GetFile(filename)
+	f, err := manager.open(filename)
 	if err != nil {
 		return nil, err
 	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
 
-	blockCount, err := manager.Length(filename)
+	blockNumber, err := manager.length(f)
 	if err != nil {
 		return nil, err
 	}
-
-	blockNumber := blockCount
-
-	if _, err := file.Seek(0, io.SeekEnd); err != nil {
-		return nil, fmt.Errorf("could not seek to end of file %s : %v", filename, err)
-	}
-	n, err := file.Write(make([]byte, manager.blockSize))
-	if err != nil {
+	if _, err := f.file.WriteAt(manager.stored(make([]byte, manager.blockSize)), int64(blockNumber*manager.diskBlockSize())); err != nil {
 		return nil, fmt.Errorf("could not append block to file %s : %v", filename, err)
 	}
-
-	if n != manager.blockSize {
-		return nil, fmt.Errorf("wrote %d bytes, expected to write %d bytes", manager.blockSize, n)
+	if err := manager.syncWrite(f); err != nil {
+		return nil, err
 	}
 
 	block := &BlockId{
@@ -140,33 +221,281 @@ func (manager *Manager) Append(filename string) (*BlockId, error) {
 	return block, nil
 }
 
+// Truncate
+// cuts the file down to its first size blocks and syncs it
+func (manager *Manager) Truncate(filename string, size int) error {
+	f, err := manager.open(filename)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err := f.file.Truncate(int64(size) * int64(manager.diskBlockSize())); err != nil {
+		return fmt.Errorf("could not truncate file %s : %v", filename, err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("fsync error: %v", err)
+	}
+	return nil
+}
+
+// Sync
+// forces the writes to the file to disk, where those of a file synced on
+// every write already are, together with the directory entry of a file
+// created since the last sync. A file that is not open, as it was removed,
+// has nothing to sync
+func (manager *Manager) Sync(filename string) error {
+	manager.lock.Lock()
+	f, exists := manager.openFiles[filename]
+	manager.lock.Unlock()
+	if !exists {
+		return nil
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if f.closed {
+		return nil
+	}
+	if f.policy == SyncEveryWrite {
+		return manager.syncDirectory(f)
+	}
+	return manager.sync(f)
+}
+
+// SetSyncPolicy
+// overrides the sync policy of the manager for one file, for the log
+// manager to decide itself when the log is forced to disk
+func (manager *Manager) SetSyncPolicy(filename string, policy SyncPolicy) error {
+	f, err := manager.open(filename)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.policy = policy
+	return nil
+}
+
 func (manager *Manager) Length(filename string) (int, error) {
-	file, err := manager.GetFile(filename)
+	f, err := manager.open(filename)
 	if err != nil {
 		return -1, err
 	}
-	stat, err := file.Stat()
+	return manager.length(f)
+}
+
+func (manager *Manager) length(f *openFile) (int, error) {
+	stat, err := f.file.Stat()
 	if err != nil {
-		return -1, fmt.Errorf("could not stat file %s : %v", filename, err)
+		return -1, fmt.Errorf("could not stat file %s : %v", f.file.Name(), err)
 	}
-	size := int(stat.Size())
-	blockCount := size / manager.blockSize
-	return blockCount, nil
+	return int(stat.Size()) / manager.diskBlockSize(), nil
 }
 
 func (manager *Manager) GetFile(filename string) (*os.File, error) {
-	if file, exists := manager.openFiles[filename]; exists {
-		return file, nil
+	f, err := manager.open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return f.file, nil
+}
+
+// open
+// the open file of filename, opened and created when it is not yet
+func (manager *Manager) open(filename string) (*openFile, error) {
+	return manager.openWith(filename, true)
+}
+
+// openWith
+// the open file of filename, opened when it is not yet. Without create a
+// file that does not exist is nil
+func (manager *Manager) openWith(filename string, create bool) (*openFile, error) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	if f, exists := manager.openFiles[filename]; exists {
+		return f, nil
+	}
+	flags := os.O_RDWR
+	if create {
+		flags |= os.O_CREATE
+	}
+	if manager.options.DirectIO {
+		flags |= directFlag
 	}
 	filePath := filepath.Join(manager.dbDirectory, filename)
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_SYNC, 0755)
+	_, err := os.Stat(filePath)
+	created := os.IsNotExist(err)
+	file, err := os.OpenFile(filePath, flags, 0755)
+	if !create && os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not create file : %v", err)
 	}
-	manager.openFiles[filename] = file
-	return file, nil
+	f := &openFile{file: file, policy: manager.options.SyncPolicy, created: created}
+	manager.openFiles[filename] = f
+	return f, nil
+}
+
+// syncWrite
+// forces a write of the file to disk when its policy asks for it
+func (manager *Manager) syncWrite(f *openFile) error {
+	if f.policy != SyncEveryWrite {
+		return nil
+	}
+	return manager.sync(f)
+}
+
+func (manager *Manager) sync(f *openFile) error {
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("fsync error: %v", err)
+	}
+	return manager.syncDirectory(f)
+}
+
+// syncDirectory
+// forces the directory entry of a newly created file to disk, without it
+// the file can be missing after a crash however often it was synced
+func (manager *Manager) syncDirectory(f *openFile) error {
+	manager.directoryLock.Lock()
+	defer manager.directoryLock.Unlock()
+	if !f.created {
+		return nil
+	}
+	directory, err := os.Open(manager.dbDirectory)
+	if err != nil {
+		return fmt.Errorf("cannot open directory %v", err)
+	}
+	defer directory.Close()
+	if err := directory.Sync(); err != nil {
+		return fmt.Errorf("fsync error on directory: %v", err)
+	}
+	f.created = false
+	return nil
 }
 
 func (manager *Manager) BlockSize() int {
 	return manager.blockSize
 }
+
+// diskBlockSize
+// the bytes a block takes in its file, the page and its checksum
+func (manager *Manager) diskBlockSize() int {
+	return manager.blockSize + checksumSize
+}
+
+// buffer
+// room for a block as it is stored on disk, aligned for direct i/o
+func (manager *Manager) buffer() []byte {
+	if !manager.options.DirectIO {
+		return make([]byte, manager.diskBlockSize())
+	}
+	buffer := make([]byte, manager.diskBlockSize()+directAlignment)
+	shift := int(uintptr(unsafe.Pointer(&buffer[0])) % directAlignment)
+	if shift != 0 {
+		shift = directAlignment - shift
+	}
+	return buffer[shift : shift+manager.diskBlockSize()]
+}
+
+// stored
+// contents followed by their checksum, as a block is written to disk
+func (manager *Manager) stored(contents []byte) []byte {
+	stored := manager.buffer()
+	copy(stored, contents)
+	binary.BigEndian.PutUint32(stored[manager.blockSize:], checksum(contents))
+	return stored
+}
+
+func checksum(contents []byte) uint32 {
+	return crc32.Checksum(contents, checksumTable)
+}
+
+// Scrub
+// reads every block of every file in the directory and checks its
+// checksum. Returns the number of blocks checked and the ones that are
+// corrupted, a file ending in part of a block has that block corrupted.
+// A file removed since the directory was read is skipped
+func (manager *Manager) Scrub() (int, []*CorruptionError, error) {
+	entries, err := os.ReadDir(manager.dbDirectory)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot read directory %v", err)
+	}
+	checked := 0
+	var corrupted []*CorruptionError
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		f, err := manager.openWith(entry.Name(), false)
+		if err != nil {
+			return 0, nil, err
+		}
+		if f == nil {
+			continue
+		}
+		count, found, err := manager.scrubFile(f, entry.Name())
+		if err != nil {
+			return 0, nil, err
+		}
+		checked += count
+		corrupted = append(corrupted, found...)
+	}
+	return checked, corrupted, nil
+}
+
+// scrubFile
+// checks the blocks of one file, nothing is written to it meanwhile
+func (manager *Manager) scrubFile(f *openFile, filename string) (int, []*CorruptionError, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return 0, nil, nil
+	}
+	stat, err := f.file.Stat()
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not stat file %s : %v", filename, err)
+	}
+	size := int(stat.Size())
+	checked := 0
+	var corrupted []*CorruptionError
+	page := NewPage(manager.blockSize)
+	for blockNumber := 0; blockNumber*manager.diskBlockSize() < size; blockNumber++ {
+		checked++
+		block := NewBlock(filename, blockNumber)
+		if (blockNumber+1)*manager.diskBlockSize() > size {
+			corrupted = append(corrupted, &CorruptionError{block})
+			continue
+		}
+		var corruption *CorruptionError
+		if err := manager.read(f, block, page); errors.As(err, &corruption) {
+			corrupted = append(corrupted, corruption)
+		} else if err != nil {
+			return 0, nil, err
+		}
+	}
+	return checked, corrupted, nil
+}
+
+// Remove
+// closes the file if it is open and deletes it, a file that does not exist
+// is not an error
+func (manager *Manager) Remove(filename string) error {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	if f, exists := manager.openFiles[filename]; exists {
+		delete(manager.openFiles, filename)
+		f.lock.Lock()
+		err := f.file.Close()
+		f.closed = true
+		f.lock.Unlock()
+		if err != nil {
+			return fmt.Errorf("could not close file %s : %v", filename, err)
+		}
+	}
+	err := os.Remove(filepath.Join(manager.dbDirectory, filename))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove file %s : %v", filename, err)
+	}
+	return nil
+}
@@ -3,9 +3,11 @@ package file
 import (
 	"fmt"
 	assertPkg "github.com/stretchr/testify/assert"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -79,6 +81,145 @@ func TestFileManager(t *testing.T) {
 		assert.ErrorIs(err, os.ErrNotExist, "file manager could not clear temp files: %s", tempFilename)
 	})
 
+	t.Run("Checksums", func(t *testing.T) {
+		filename := "checksums.db"
+		manager, err := NewFileManager(filepath.Join(directory, "checksums"), blockSize)
+		assert.NoError(err)
+		for i := 0; i < 3; i++ {
+			block, err := manager.Append(filename)
+			assert.NoError(err)
+			page := NewPage(blockSize)
+			assert.NoError(page.SetString(0, fmt.Sprintf("block %d", i)))
+			assert.NoError(manager.Write(block, page))
+		}
+		checked, corrupted, err := manager.Scrub()
+		assert.NoError(err)
+		assert.Equal(3, checked)
+		assert.Empty(corrupted)
+
+		//a changed byte and a write that stopped halfway are both found
+		file, err := manager.GetFile(filename)
+		assert.NoError(err)
+		_, err = file.WriteAt([]byte{'B'}, int64(manager.diskBlockSize()))
+		assert.NoError(err)
+		torn := manager.stored(make([]byte, blockSize))
+		_, err = file.WriteAt(torn[:blockSize/2], int64(2*manager.diskBlockSize()))
+		assert.NoError(err)
+		var corruption *CorruptionError
+		err = manager.Read(NewBlock(filename, 1), NewPage(blockSize))
+		assert.ErrorAs(err, &corruption)
+		assert.True(corruption.Block.Equals(NewBlock(filename, 1)))
+		assert.EqualError(err, "block 1 of checksums.db is corrupted, its checksum does not match")
+		assert.NoError(manager.Read(NewBlock(filename, 0), NewPage(blockSize)))
+
+		//a file ending in part of a block has that block corrupted too
+		_, err = file.WriteAt([]byte("partial"), int64(3*manager.diskBlockSize()))
+		assert.NoError(err)
+		checked, corrupted, err = manager.Scrub()
+		assert.NoError(err)
+		assert.Equal(4, checked)
+		assert.Equal([]*CorruptionError{{NewBlock(filename, 1)}, {NewBlock(filename, 2)}, {NewBlock(filename, 3)}}, corrupted)
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		_, err := NewFileManagerWithOptions(directory, blockSize, Options{DirectIO: true})
+		assert.ErrorContains(err, "direct i/o needs blocks of a multiple of 4096 bytes")
+		for _, options := range []Options{{DirectIO: true}, {SyncPolicy: SyncOnRequest}} {
+			if options.DirectIO && directFlag == 0 {
+				continue
+			}
+			manager, err := NewFileManagerWithOptions(filepath.Join(directory, "options"), 4096-checksumSize, options)
+			assert.NoError(err)
+			filename := fmt.Sprintf("options%v.db", options.DirectIO)
+			block, err := manager.Append(filename)
+			assert.NoError(err)
+			page := NewPage(manager.BlockSize())
+			assert.NoError(page.SetString(0, "options"))
+			assert.NoError(manager.Write(block, page))
+			assert.NoError(manager.Sync(filename))
+			page = NewPage(manager.BlockSize())
+			assert.NoError(manager.Read(block, page))
+			assert.Equal("options", page.GetString(0))
+			_, err = os.Stat(filepath.Join(directory, "options", filename))
+			assert.NoError(err)
+		}
+	})
+
+	t.Run("SyncPolicy", func(t *testing.T) {
+		manager, err := NewFileManagerWithOptions(filepath.Join(directory, "sync"), blockSize, Options{SyncPolicy: SyncOnRequest})
+		assert.NoError(err)
+		filename := "sync.db"
+		_, err = manager.Append(filename)
+		assert.NoError(err)
+		//the directory entry of a new file is synced with the file
+		f, err := manager.open(filename)
+		assert.NoError(err)
+		assert.True(f.created)
+		assert.NoError(manager.Sync(filename))
+		assert.False(f.created)
+
+		//a file synced on every write has its directory entry synced too
+		_, err = manager.Append("other.db")
+		assert.NoError(err)
+		other, err := manager.open("other.db")
+		assert.NoError(err)
+		assert.True(other.created)
+		assert.NoError(manager.SetSyncPolicy("other.db", SyncEveryWrite))
+		_, err = manager.Append("other.db")
+		assert.NoError(err)
+		assert.False(other.created)
+
+		//a removed file is not created again by a sync
+		assert.NoError(manager.Remove(filename))
+		assert.NoError(manager.Sync(filename))
+		_, err = os.Stat(filepath.Join(directory, "sync", filename))
+		assert.ErrorIs(err, os.ErrNotExist)
+	})
+
+	t.Run("ScrubWhileWriting", func(t *testing.T) {
+		manager, err := NewFileManager(filepath.Join(directory, "scrub"), blockSize)
+		assert.NoError(err)
+		filename := "scrub.db"
+		block, err := manager.Append(filename)
+		assert.NoError(err)
+
+		//a scrub reads no block half written and changes no policy in use
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page := NewPage(blockSize)
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				assert.NoError(page.SetString(0, fmt.Sprintf("write %d", i)))
+				assert.NoError(manager.SetSyncPolicy(filename, SyncPolicy(i%2)))
+				assert.NoError(manager.Write(block, page))
+				assert.NoError(manager.Sync(filename))
+			}
+		}()
+		for range 50 {
+			checked, corrupted, err := manager.Scrub()
+			assert.NoError(err)
+			assert.Equal(1, checked)
+			assert.Empty(corrupted)
+		}
+		close(done)
+		wg.Wait()
+
+		//nor creates a file removed since the directory was read
+		assert.NoError(manager.Remove(filename))
+		f, err := manager.openWith(filename, false)
+		assert.NoError(err)
+		assert.Nil(f)
+		_, err = os.Stat(filepath.Join(directory, "scrub", filename))
+		assert.ErrorIs(err, os.ErrNotExist)
+	})
+
 	t.Run("Concurrent", func(t *testing.T) {
 		filename := "concurrent.db"
 
@@ -117,3 +258,67 @@ func TestFileManager(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+// benchmarkParallel
+// reads or writes random blocks of several files from parallel goroutines
+func benchmarkParallel(b *testing.B, manager *Manager, write bool) {
+	blockSize := manager.BlockSize()
+	files, blocks := 8, 64
+	for f := 0; f < files; f++ {
+		for i := 0; i < blocks; i++ {
+			if _, err := manager.Append(fmt.Sprintf("bench%d.db", f)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	var next atomic.Int64
+	b.SetBytes(int64(blockSize))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		worker := int(next.Add(1))
+		filename := fmt.Sprintf("bench%d.db", worker%files)
+		random := rand.New(rand.NewSource(int64(worker)))
+		page := NewPage(blockSize)
+		for pb.Next() {
+			block := NewBlock(filename, random.Intn(blocks))
+			var err error
+			if write {
+				err = manager.Write(block, page)
+			} else {
+				err = manager.Read(block, page)
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkManagerParallel(b *testing.B) {
+	cases := []struct {
+		name    string
+		write   bool
+		options Options
+	}{
+		{"Read", false, Options{}},
+		{"Write", true, Options{}},
+		{"WriteSyncOnRequest", true, Options{SyncPolicy: SyncOnRequest}},
+		{"ReadDirect", false, Options{DirectIO: true}},
+		{"WriteDirect", true, Options{DirectIO: true}},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			if c.options.DirectIO && directFlag == 0 {
+				b.Skip("direct i/o is not supported")
+			}
+			directory := filepath.Join(os.TempDir(), "bench")
+			defer os.RemoveAll(directory)
+			manager, err := NewFileManagerWithOptions(directory, 4096-checksumSize, c.options)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkParallel(b, manager, c.write)
+		})
+	}
+}
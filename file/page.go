@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"jadb/constants"
+	"math"
+	"time"
 	"unicode/utf8"
 )
 
@@ -31,6 +33,37 @@ func (page *Page) GetInt(offset int) int {
 	return int(binary.BigEndian.Uint64(page.page[offset:]))
 }
 
+func (page *Page) SetBool(offset int, data bool) {
+	page.page[offset] = 0
+	if data {
+		page.page[offset] = 1
+	}
+}
+
+func (page *Page) GetBool(offset int) bool {
+	return page.page[offset] != 0
+}
+
+func (page *Page) SetDouble(offset int, data float64) {
+	binary.BigEndian.PutUint64(page.page[offset:], math.Float64bits(data))
+}
+
+func (page *Page) GetDouble(offset int) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(page.page[offset:]))
+}
+
+// SetTime
+// stores the microseconds since the unix epoch, finer precision is lost
+func (page *Page) SetTime(offset int, data time.Time) {
+	page.SetInt(offset, int(data.UnixMicro()))
+}
+
+// GetTime
+// the time in UTC
+func (page *Page) GetTime(offset int) time.Time {
+	return time.UnixMicro(int64(page.GetInt(offset))).UTC()
+}
+
 func (page *Page) SetString(offset int, data string) error {
 	if !utf8.ValidString(data) {
 		return fmt.Errorf("not a valid utf-8 string %s", data)
@@ -56,6 +89,18 @@ func (page *Page) GetBytes(offset int) []byte {
 	return page.page[offset : offset+size]
 }
 
+// SetRaw
+// copies data into the page as it is, without a length
+func (page *Page) SetRaw(offset int, data []byte) {
+	copy(page.page[offset:], data)
+}
+
+// GetRaw
+// a copy of the length bytes at offset
+func (page *Page) GetRaw(offset int, length int) []byte {
+	return append([]byte(nil), page.page[offset:offset+length]...)
+}
+
 func MaxLength(strlen int) int {
 	return constants.IntSize + (strlen * utf8.UTFMax)
 }
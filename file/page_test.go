@@ -3,6 +3,7 @@ package file
 import (
 	assertPkg "github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestPage(t *testing.T) {
@@ -40,4 +41,19 @@ func TestPage(t *testing.T) {
 		assert.NoErrorf(err, "Could not get string in page buffer : %v", err)
 		assert.Equalf(page.GetString(offset), stringData, "String data does not match in page at offset %d", offset)
 	})
+
+	t.Run("TypedDataTest", func(t *testing.T) {
+		page.SetBool(0, true)
+		assert.True(page.GetBool(0))
+		page.SetBool(0, false)
+		assert.False(page.GetBool(0))
+		page.SetDouble(8, -2.5)
+		assert.Equal(-2.5, page.GetDouble(8))
+		//times keep microseconds and come back in UTC
+		local := time.Date(2024, 2, 29, 13, 4, 5, 123456789, time.FixedZone("x", 3600))
+		page.SetTime(16, local)
+		assert.Equal(time.Date(2024, 2, 29, 12, 4, 5, 123456000, time.UTC), page.GetTime(16))
+		page.SetRaw(24, []byte{1, 2, 3})
+		assert.Equal([]byte{1, 2, 3}, page.GetRaw(24, 3))
+	})
 }
@@ -37,9 +37,7 @@ func (h *HashIndex) BeforeFirst(searchKey any) error {
 	if err != nil {
 		return err
 	}
-	bucket := hashCode % NUM_BUCKETS
-	tblName := fmt.Sprintf("%s%d", h.idxName, bucket)
-	ts, err := scan_types.NewTableScan(h.txn, tblName, h.layout)
+	ts, err := scan_types.NewTableScan(h.txn, bucketName(h.idxName, int(hashCode%NUM_BUCKETS)), h.layout)
 	if err != nil {
 		return err
 	}
@@ -122,6 +120,27 @@ func (h *HashIndex) Close() {
 	}
 }
 
+// Remove
+// deletes the bucket files of the index and their free space maps when the
+// transaction commits
+func Remove(txn *tx.Transaction, idxName string) error {
+	for bucket := 0; bucket < NUM_BUCKETS; bucket++ {
+		if err := txn.Remove(scan_types.TableFileName(bucketName(idxName, bucket))); err != nil {
+			return err
+		}
+		if err := txn.Remove(scan_types.FreeSpaceFileName(bucketName(idxName, bucket))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bucketName
+// the table holding the entries of a bucket of the index
+func bucketName(idxName string, bucket int) string {
+	return fmt.Sprintf("%s%d", idxName, bucket)
+}
+
 func SearchCost(numBlocks int, rpb int) int {
 	return numBlocks / NUM_BUCKETS
 }
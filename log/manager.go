@@ -4,8 +4,60 @@ import (
 	"jadb/constants"
 	"jadb/file"
 	"sync"
+	"time"
 )
 
+// Durability
+// when a flush of the log is forced to disk
+type Durability int
+
+const (
+	// DurableCommit
+	// every flush is on disk when it returns, so is every commit
+	DurableCommit Durability = iota
+	// DurableGroup
+	// like DurableCommit, but concurrent flushes are forced to disk
+	// together by one sync
+	DurableGroup
+	// DurableNone
+	// commits only reach the operating system, the log and the pages they
+	// write alike, so a commit never waits for the disk. A crash of the
+	// process loses nothing, a crash of the machine can lose the last
+	// commits or keep part of them. The log is still forced to disk before
+	// a page is written back for another block, so recovery can undo the
+	// changes of a transaction that did not commit
+	DurableNone
+)
+
+// Options
+// the durability of the log. With DurableGroup the flush leading a group
+// waits up to GroupWindow for more flushes to join before it syncs, or
+// until MaxGroup flushes have joined when that is not 0. Without a window
+// a group is the flushes arriving while the log is being synced
+type Options struct {
+	Durability  Durability
+	GroupWindow time.Duration
+	MaxGroup    int
+}
+
+// GroupStats
+// the syncs of group commit and the flushes they made durable, Largest is
+// the most flushes one sync made durable
+type GroupStats struct {
+	Syncs   int
+	Flushes int
+	Largest int
+}
+
+// Average
+// the flushes made durable per sync
+func (stats GroupStats) Average() float64 {
+	if stats.Syncs == 0 {
+		return 0
+	}
+	return float64(stats.Flushes) / float64(stats.Syncs)
+}
+
 type Manager struct {
 	fileManager  *file.Manager
 	logFile      string
@@ -14,9 +66,42 @@ type Manager struct {
 	lastSavedLSN int
 	latestLSN    int
 	lock         sync.Mutex
+	options      Options
+	// syncedLSN
+	// the latest lsn forced to disk
+	syncedLSN int
+	// syncing
+	// a flush is leading a group, the others wait on synced for it
+	syncing bool
+	synced  *sync.Cond
+	// gathering
+	// the leading flush is waiting for more flushes to join, the one
+	// making the group full tells it on full
+	gathering bool
+	full      chan struct{}
+	// waiting
+	// the flushes waiting for the log to be synced that are not in a group
+	// yet, the leading one too. A group takes them all and starts the next
+	// generation
+	waiting    int
+	generation int
+	stats      GroupStats
+	// syncs
+	// the times the log was forced to disk
+	syncs int
 }
 
 func NewLogManager(fileManager *file.Manager, logFile string) (*Manager, error) {
+	return NewLogManagerWithOptions(fileManager, logFile, Options{})
+}
+
+// NewLogManagerWithOptions
+// the log file is synced by the log manager as the options ask, not on
+// every write
+func NewLogManagerWithOptions(fileManager *file.Manager, logFile string, options Options) (*Manager, error) {
+	if err := fileManager.SetSyncPolicy(logFile, file.SyncOnRequest); err != nil {
+		return nil, err
+	}
 	fileLength, err := fileManager.Length(logFile)
 	if err != nil {
 		return nil, err
@@ -38,6 +123,9 @@ func NewLogManager(fileManager *file.Manager, logFile string) (*Manager, error)
 		if err = fileManager.Write(block, logPage); err != nil {
 			return nil, err
 		}
+		if err = fileManager.Sync(logFile); err != nil {
+			return nil, err
+		}
 	} else {
 		//load last block into logPage
 		lastBlockNumber := fileLength - 1
@@ -50,14 +138,39 @@ func NewLogManager(fileManager *file.Manager, logFile string) (*Manager, error)
 			latestLSN = logPage.GetInt(boundary)
 		}
 	}
-	return &Manager{
+	manager := &Manager{
 		fileManager:  fileManager,
 		logFile:      logFile,
 		logPage:      logPage,
 		currentBlock: block,
 		lastSavedLSN: -1,
 		latestLSN:    latestLSN,
-	}, nil
+		options:      options,
+		syncedLSN:    -1,
+		full:         make(chan struct{}, 1),
+	}
+	manager.synced = sync.NewCond(&manager.lock)
+	return manager, nil
+}
+
+func (manager *Manager) Durability() Durability {
+	return manager.options.Durability
+}
+
+// Syncs
+// the times the log was forced to disk so far, whatever the durability
+func (manager *Manager) Syncs() int {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	return manager.syncs
+}
+
+// GroupStats
+// the syncs made by group commit so far
+func (manager *Manager) GroupStats() GroupStats {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+	return manager.stats
 }
 
 func (manager *Manager) GetIterator() (*Iterator, error) {
@@ -110,11 +223,128 @@ func (manager *Manager) flush() error {
 	return nil
 }
 
+// Flush
+// writes the log up to lsn and forces it to disk as the durability of the
+// manager asks, with DurableGroup together with the concurrent flushes
 func (manager *Manager) Flush(lsn int) error {
+	return manager.force(lsn, true)
+}
+
+// FlushNow
+// forces the log up to lsn to disk whatever the durability, for a page
+// about to be written back while its buffer manager is locked. It never
+// waits for more flushes to join a group
+func (manager *Manager) FlushNow(lsn int) error {
+	return manager.force(lsn, false)
+}
+
+// force
+// forces the log up to lsn. A flush of a commit may gather a group for
+// the window, and without durability only reaches the operating system
+func (manager *Manager) force(lsn int, commit bool) error {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
+	switch manager.options.Durability {
+	case DurableNone:
+		if commit {
+			if lsn > manager.lastSavedLSN {
+				return manager.flush()
+			}
+			return nil
+		}
+	case DurableGroup:
+		return manager.groupFlush(lsn, commit)
+	}
+	if lsn <= manager.syncedLSN {
+		return nil
+	}
 	if lsn > manager.lastSavedLSN {
-		return manager.flush()
+		if err := manager.flush(); err != nil {
+			return err
+		}
+	}
+	if err := manager.fileManager.Sync(manager.logFile); err != nil {
+		return err
+	}
+	manager.syncedLSN = manager.lastSavedLSN
+	manager.syncs++
+	return nil
+}
+
+// groupFlush
+// waits for the group being synced when lsn is in it, or leads the next
+// group. The leading flush gathers more flushes for the window, then
+// writes the log and syncs it with the lock let go, so the flushes
+// arriving meanwhile gather for the group after. A flush not gathering
+// ends the window at once
+func (manager *Manager) groupFlush(lsn int, gather bool) error {
+	if lsn <= manager.syncedLSN {
+		return nil
+	}
+	manager.waiting++
+	generation := manager.generation
+	defer func() {
+		if manager.generation == generation {
+			manager.waiting--
+		}
+	}()
+	if manager.gathering && (manager.isFull() || !gather) {
+		select {
+		case manager.full <- struct{}{}:
+		default:
+		}
+	}
+	for manager.syncing && lsn > manager.syncedLSN {
+		manager.synced.Wait()
+	}
+	if lsn <= manager.syncedLSN {
+		return nil
+	}
+	manager.syncing = true
+	defer func() {
+		manager.syncing = false
+		manager.synced.Broadcast()
+	}()
+	if gather && manager.options.GroupWindow > 0 && !manager.isFull() {
+		select {
+		case <-manager.full:
+		default:
+		}
+		manager.gathering = true
+		manager.lock.Unlock()
+		timer := time.NewTimer(manager.options.GroupWindow)
+		select {
+		case <-manager.full:
+		case <-timer.C:
+		}
+		timer.Stop()
+		manager.lock.Lock()
+		manager.gathering = false
+	}
+	if manager.latestLSN > manager.lastSavedLSN {
+		if err := manager.flush(); err != nil {
+			return err
+		}
 	}
+	target, group := manager.lastSavedLSN, manager.waiting
+	manager.waiting = 0
+	manager.generation++
+	manager.lock.Unlock()
+	err := manager.fileManager.Sync(manager.logFile)
+	manager.lock.Lock()
+	if err != nil {
+		return err
+	}
+	manager.syncedLSN = max(manager.syncedLSN, target)
+	manager.syncs++
+	manager.stats.Syncs++
+	manager.stats.Flushes += group
+	manager.stats.Largest = max(manager.stats.Largest, group)
 	return nil
 }
+
+// isFull
+// enough flushes are waiting for the group to be synced at once
+func (manager *Manager) isFull() bool {
+	return manager.options.MaxGroup > 0 && manager.waiting >= manager.options.MaxGroup
+}
@@ -7,7 +7,9 @@ import (
 	"jadb/file"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestLogManager(t *testing.T) {
@@ -65,7 +67,144 @@ func TestLogManager(t *testing.T) {
 			i--
 		}
 	})
+
+	t.Run("Durability", func(t *testing.T) {
+		for _, durability := range []Durability{DurableCommit, DurableGroup, DurableNone} {
+			testLogFileName := fmt.Sprintf("durability%d.log", durability)
+			logManager, err := NewLogManagerWithOptions(fileManager, testLogFileName, Options{Durability: durability})
+			assert.NoError(err)
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					lsn, err := logManager.Append([]byte(fmt.Sprintf("commit %d", i)))
+					assert.NoError(err)
+					assert.NoError(logManager.Flush(lsn))
+				}()
+			}
+			wg.Wait()
+			assert.Equal(20, logManager.lastSavedLSN)
+			if durability == DurableNone {
+				assert.Equal(-1, logManager.syncedLSN)
+			} else {
+				assert.Equal(20, logManager.syncedLSN)
+			}
+			//the log is on disk before a page is written back
+			assert.NoError(logManager.FlushNow(20))
+			assert.Equal(20, logManager.syncedLSN)
+
+			//every flushed record is in the file for the next log manager
+			logManager, err = NewLogManagerWithOptions(fileManager, testLogFileName, Options{Durability: durability})
+			assert.NoError(err)
+			iterator, err := logManager.GetIterator()
+			assert.NoError(err)
+			count := 0
+			for iterator.HasNext() {
+				_, err := iterator.Next()
+				assert.NoError(err)
+				count++
+			}
+			assert.Equal(20, count)
+		}
+	})
+
+	t.Run("GroupCommit", func(t *testing.T) {
+		logManager, err := NewLogManagerWithOptions(fileManager, "group.log", Options{
+			Durability:  DurableGroup,
+			GroupWindow: time.Minute,
+			MaxGroup:    8,
+		})
+		assert.NoError(err)
+		//the window is long, so only a full group lets the flushes finish
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lsn, err := logManager.Append([]byte(fmt.Sprintf("commit %d", i)))
+				assert.NoError(err)
+				assert.NoError(logManager.Flush(lsn))
+			}()
+		}
+		wg.Wait()
+		stats := logManager.GroupStats()
+		assert.Equal(GroupStats{Syncs: 1, Flushes: 8, Largest: 8}, stats)
+		assert.Equal(8.0, stats.Average())
+		assert.Equal(8, logManager.syncedLSN)
+
+		//a flush already durable does not sync again
+		assert.NoError(logManager.Flush(3))
+		assert.Equal(stats, logManager.GroupStats())
+
+		//a page written back does not wait out the window of a commit
+		commitLSN, err := logManager.Append([]byte("commit"))
+		assert.NoError(err)
+		committed := make(chan error)
+		go func() { committed <- logManager.Flush(commitLSN) }()
+		gathering := func() bool {
+			logManager.lock.Lock()
+			defer logManager.lock.Unlock()
+			return logManager.gathering
+		}
+		for !gathering() {
+			time.Sleep(time.Millisecond)
+		}
+		pageLSN, err := logManager.Append([]byte("page"))
+		assert.NoError(err)
+		start := time.Now()
+		assert.NoError(logManager.FlushNow(pageLSN))
+		assert.NoError(<-committed)
+		assert.Less(time.Since(start), time.Minute/2)
+		assert.Equal(pageLSN, logManager.syncedLSN)
+	})
 	if err := os.RemoveAll(tempDir); err != nil {
 		t.Errorf("could not remove temp files: %v", err)
 	}
 }
+
+func BenchmarkGroupCommit(b *testing.B) {
+	cases := []struct {
+		name    string
+		options Options
+	}{
+		{"Commit", Options{Durability: DurableCommit}},
+		{"Group", Options{Durability: DurableGroup}},
+		{"GroupWindow", Options{Durability: DurableGroup, GroupWindow: 200 * time.Microsecond, MaxGroup: 16}},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			directory := filepath.Join(os.TempDir(), "bench")
+			defer os.RemoveAll(directory)
+			fileManager, err := file.NewFileManager(directory, 4096-4)
+			if err != nil {
+				b.Fatal(err)
+			}
+			logManager, err := NewLogManagerWithOptions(fileManager, "bench.log", c.options)
+			if err != nil {
+				b.Fatal(err)
+			}
+			record := make([]byte, 64)
+			//commits mostly wait on the disk, so many of them run at once
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					lsn, err := logManager.Append(record)
+					if err == nil {
+						err = logManager.Flush(lsn)
+					}
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			if stats := logManager.GroupStats(); stats.Syncs > 0 {
+				b.ReportMetric(stats.Average(), "flushes/sync")
+				b.ReportMetric(float64(stats.Largest), "largest")
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package metadata
+
+import (
+	"fmt"
+	"jadb/record"
+	"jadb/scan_types"
+	"jadb/tx"
+	"unicode/utf8"
+)
+
+// MAX_CON_DEF
+// the longest definition of a check or default
+const MAX_CON_DEF = 100
+
+// ConstraintManager
+// keeps the constraints of the tables in concat, a row for every field of
+// a constraint in the order of the fields. The row of a field of a foreign
+// key also has the field it refers to, and every row of a check or default
+// has its definition as text that parses again
+type ConstraintManager struct {
+	layout *record.Layout
+}
+
+// Reference
+// a foreign key of a table referring to another table, or to its own
+type Reference struct {
+	table      string
+	constraint *record.Constraint
+}
+
+func (r Reference) Table() string {
+	return r.table
+}
+
+func (r Reference) Constraint() *record.Constraint {
+	return r.constraint
+}
+
+func NewConstraintManager(isNew bool, tblMgr *TableManager, txn *tx.Transaction) (*ConstraintManager, error) {
+	if isNew {
+		schema := record.NewSchema()
+		schema.AddStringField("conname", MAX_NAME)
+		schema.AddStringField("tblname", MAX_NAME)
+		schema.AddIntField("contype")
+		schema.AddStringField("fldname", MAX_NAME)
+		schema.AddStringField("reftable", MAX_NAME)
+		schema.AddStringField("reffield", MAX_NAME)
+		schema.AddIntField("ondelete")
+		schema.AddStringField("condef", MAX_CON_DEF)
+		if err := tblMgr.createTable("concat", schema, txn); err != nil {
+			return nil, err
+		}
+	}
+	layout, err := tblMgr.getLayout("concat", txn)
+	if err != nil {
+		return nil, err
+	}
+	return &ConstraintManager{layout}, nil
+}
+
+func (manager *ConstraintManager) createConstraint(tblName string, constraint *record.Constraint, txn *tx.Transaction) error {
+	if utf8.RuneCountInString(constraint.Definition()) > MAX_CON_DEF {
+		return fmt.Errorf("definition of constraint %s is longer than %d", constraint.Name(), MAX_CON_DEF)
+	}
+	ts, err := scan_types.NewTableScan(txn, "concat", manager.layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	for i, fldName := range constraint.Fields() {
+		if err := ts.Insert(); err != nil {
+			return err
+		}
+		if err := ts.SetString("conname", constraint.Name()); err != nil {
+			return err
+		}
+		if err := ts.SetString("tblname", tblName); err != nil {
+			return err
+		}
+		if err := ts.SetInt("contype", int(constraint.Type())); err != nil {
+			return err
+		}
+		if err := ts.SetString("fldname", fldName); err != nil {
+			return err
+		}
+		refField := ""
+		if i < len(constraint.RefFields()) {
+			refField = constraint.RefFields()[i]
+		}
+		if err := ts.SetString("reftable", constraint.RefTable()); err != nil {
+			return err
+		}
+		if err := ts.SetString("reffield", refField); err != nil {
+			return err
+		}
+		if err := ts.SetInt("ondelete", int(constraint.OnDelete())); err != nil {
+			return err
+		}
+		if err := ts.SetString("condef", constraint.Definition()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getConstraints
+// the constraints of the table in the order they were created
+func (manager *ConstraintManager) getConstraints(tblName string, txn *tx.Transaction) ([]*record.Constraint, error) {
+	references, err := manager.readConstraints(func(ts *scan_types.TableScan) (bool, error) {
+		tableName, err := ts.GetString("tblname")
+		return tableName == tblName, err
+	}, txn)
+	if err != nil {
+		return nil, err
+	}
+	constraints := make([]*record.Constraint, len(references))
+	for i, reference := range references {
+		constraints[i] = reference.constraint
+	}
+	return constraints, nil
+}
+
+// getReferences
+// the foreign keys referring to the table
+func (manager *ConstraintManager) getReferences(tblName string, txn *tx.Transaction) ([]Reference, error) {
+	return manager.readConstraints(func(ts *scan_types.TableScan) (bool, error) {
+		refTable, err := ts.GetString("reftable")
+		return refTable == tblName, err
+	}, txn)
+}
+
+// readConstraints
+// the constraints with a row of concat for which match is true, with their
+// tables
+func (manager *ConstraintManager) readConstraints(match func(*scan_types.TableScan) (bool, error),
+	txn *tx.Transaction) ([]Reference, error) {
+	ts, err := scan_types.NewTableScan(txn, "concat", manager.layout)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+	type entry struct {
+		table     string
+		kind      record.ConstraintType
+		fields    []string
+		refTable  string
+		refFields []string
+		onDelete  record.ReferentialAction
+		def       string
+	}
+	var keys []string
+	entries := make(map[string]*entry)
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		if err != nil {
+			return nil, err
+		}
+		matched, err := match(ts)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		values := make(map[string]string)
+		for _, fldName := range []string{"conname", "tblname", "fldname", "reftable", "reffield", "condef"} {
+			if values[fldName], err = ts.GetString(fldName); err != nil {
+				return nil, err
+			}
+		}
+		kind, err := ts.GetInt("contype")
+		if err != nil {
+			return nil, err
+		}
+		onDelete, err := ts.GetInt("ondelete")
+		if err != nil {
+			return nil, err
+		}
+		// constraint names are only unique within a table
+		key := values["tblname"] + "." + values["conname"]
+		e, seen := entries[key]
+		if !seen {
+			e = &entry{table: values["tblname"], kind: record.ConstraintType(kind), refTable: values["reftable"],
+				onDelete: record.ReferentialAction(onDelete), def: values["condef"]}
+			entries[key] = e
+			keys = append(keys, key)
+		}
+		e.fields = append(e.fields, values["fldname"])
+		if e.kind == record.ForeignKey {
+			e.refFields = append(e.refFields, values["reffield"])
+		}
+	}
+	references := make([]Reference, len(keys))
+	for i, key := range keys {
+		e := entries[key]
+		name := key[len(e.table)+1:]
+		var constraint *record.Constraint
+		switch e.kind {
+		case record.ForeignKey:
+			constraint = record.NewForeignKey(name, e.fields, e.refTable, e.refFields, e.onDelete)
+		case record.Check:
+			constraint = record.NewCheck(name, e.fields, e.def)
+		case record.Default:
+			constraint = record.NewDefault(name, e.fields[0], e.def)
+		default:
+			constraint = record.NewConstraint(name, e.kind, e.fields)
+		}
+		references[i] = Reference{e.table, constraint}
+	}
+	return references, nil
+}
+
+// dropConstraint
+// deletes the constraint of the table, or all its constraints when name is
+// empty
+func (manager *ConstraintManager) dropConstraint(tblName string, name string, txn *tx.Transaction) error {
+	return manager.updateEntries(tblName, func(ts *scan_types.TableScan) error {
+		conName, err := ts.GetString("conname")
+		if err != nil || (name != "" && conName != name) {
+			return err
+		}
+		return ts.Delete()
+	}, txn)
+}
+
+// renameTable
+// moves the constraints of the table and the foreign keys referring to it
+// to its new name
+func (manager *ConstraintManager) renameTable(oldName string, newName string, txn *tx.Transaction) error {
+	return manager.updateEntries("", func(ts *scan_types.TableScan) error {
+		for _, fldName := range []string{"tblname", "reftable"} {
+			tblName, err := ts.GetString(fldName)
+			if err != nil {
+				return err
+			}
+			if tblName != oldName {
+				continue
+			}
+			if err := ts.SetString(fldName, newName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, txn)
+}
+
+// renameField
+// renames the field in the constraints of the table and in the foreign
+// keys referring to it
+func (manager *ConstraintManager) renameField(tblName string, oldName string, newName string, txn *tx.Transaction) error {
+	return manager.updateEntries("", func(ts *scan_types.TableScan) error {
+		for _, names := range [][2]string{{"tblname", "fldname"}, {"reftable", "reffield"}} {
+			tableName, err := ts.GetString(names[0])
+			if err != nil {
+				return err
+			}
+			fldName, err := ts.GetString(names[1])
+			if err != nil {
+				return err
+			}
+			if tableName != tblName || fldName != oldName {
+				continue
+			}
+			if err := ts.SetString(names[1], newName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, txn)
+}
+
+// updateEntries
+// calls update on every row of concat that belongs to the table, or on
+// every row when tblName is empty
+func (manager *ConstraintManager) updateEntries(tblName string, update func(*scan_types.TableScan) error,
+	txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, "concat", manager.layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		if err != nil {
+			return err
+		}
+		tableName, err := ts.GetString("tblname")
+		if err != nil {
+			return err
+		}
+		if tblName != "" && tableName != tblName {
+			continue
+		}
+		if err := update(ts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
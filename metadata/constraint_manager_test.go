@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestConstraintManager(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	tblMgr, err := NewTableManager(true, txn)
+	assert.NoError(err)
+	conMgr, err := NewConstraintManager(true, tblMgr, txn)
+	assert.NoError(err)
+
+	pkey := record.NewConstraint("link_pkey", record.PrimaryKey, []string{"a", "b"})
+	notNull := record.NewConstraint("link_c_not_null", record.NotNull, []string{"c"})
+	other := record.NewConstraint("other_key", record.Unique, []string{"a"})
+	assert.NoError(conMgr.createConstraint("link", pkey, txn))
+	assert.NoError(conMgr.createConstraint("other", other, txn))
+	assert.NoError(conMgr.createConstraint("link", notNull, txn))
+	assert.NoError(txn.Commit())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	conMgr, err = NewConstraintManager(false, tblMgr, txn)
+	assert.NoError(err)
+	constraints, err := conMgr.getConstraints("link", txn)
+	assert.NoError(err)
+	assert.Equal([]*record.Constraint{pkey, notNull}, constraints)
+
+	assert.NoError(conMgr.renameField("link", "b", "z", txn))
+	assert.NoError(conMgr.renameTable("link", "chain", txn))
+	assert.NoError(conMgr.dropConstraint("chain", "chain_c_not_null", txn))
+	constraints, err = conMgr.getConstraints("chain", txn)
+	assert.NoError(err)
+	assert.Equal([]*record.Constraint{
+		record.NewConstraint("link_pkey", record.PrimaryKey, []string{"a", "z"}),
+		notNull,
+	}, constraints)
+
+	assert.NoError(conMgr.dropConstraint("chain", "link_c_not_null", txn))
+	assert.NoError(conMgr.dropConstraint("chain", "", txn))
+	constraints, err = conMgr.getConstraints("chain", txn)
+	assert.NoError(err)
+	assert.Empty(constraints)
+	constraints, err = conMgr.getConstraints("other", txn)
+	assert.NoError(err)
+	assert.Equal([]*record.Constraint{other}, constraints)
+
+	fkey := record.NewForeignKey("emp_dept_fkey", []string{"dept", "loc"}, "dept", []string{"id", "loc"}, record.Cascade)
+	self := record.NewForeignKey("dept_parent_fkey", []string{"parent"}, "dept", []string{"id"}, record.SetNull)
+	assert.NoError(conMgr.createConstraint("emp", fkey, txn))
+	assert.NoError(conMgr.createConstraint("dept", self, txn))
+	constraints, err = conMgr.getConstraints("emp", txn)
+	assert.NoError(err)
+	assert.Equal([]*record.Constraint{fkey}, constraints)
+	references, err := conMgr.getReferences("dept", txn)
+	assert.NoError(err)
+	assert.Equal([]Reference{{"emp", fkey}, {"dept", self}}, references)
+
+	//renaming the table referred to or its fields updates the foreign keys
+	assert.NoError(conMgr.renameField("dept", "id", "num", txn))
+	assert.NoError(conMgr.renameTable("dept", "unit", txn))
+	references, err = conMgr.getReferences("unit", txn)
+	assert.NoError(err)
+	assert.Equal([]Reference{
+		{"emp", record.NewForeignKey("emp_dept_fkey", []string{"dept", "loc"}, "unit", []string{"num", "loc"}, record.Cascade)},
+		{"unit", record.NewForeignKey("dept_parent_fkey", []string{"parent"}, "unit", []string{"num"}, record.SetNull)},
+	}, references)
+	references, err = conMgr.getReferences("dept", txn)
+	assert.NoError(err)
+	assert.Empty(references)
+
+	check := record.NewCheck("item_range", []string{"low", "qty"}, "low<=qty and qty<100")
+	def := record.NewDefault("item_name_default", "name", "'n/a'")
+	assert.NoError(conMgr.createConstraint("item", check, txn))
+	assert.NoError(conMgr.createConstraint("item", def, txn))
+	constraints, err = conMgr.getConstraints("item", txn)
+	assert.NoError(err)
+	assert.Equal([]*record.Constraint{check, def}, constraints)
+	assert.NoError(txn.Commit())
+	clearEnv(t, env)
+}
@@ -26,12 +26,17 @@ func NewIndexInfo(idxName string, fldName string, tableLayout *record.Layout, tx
 	}
 }
 
+func (info IndexInfo) IndexName() string {
+	return info.idxName
+}
+
 func (info IndexInfo) Open() index.Index {
 	return hash.NewHashIndex(info.txn, info.idxName, info.indexLayout)
 }
 
 func (info IndexInfo) blocksAccessed() int {
-	rpb := info.txn.BlockSize() / info.indexLayout.SlotSize()
+	//a record larger than a block keeps its long values in overflow blocks
+	rpb := max(info.txn.BlockSize()/info.indexLayout.SlotSize(), 1)
 	numBlocks := info.si.RecordsOutput() / rpb
 	return hash.SearchCost(numBlocks, rpb)
 }
@@ -51,10 +56,10 @@ func createIdxLayout(tableLayout *record.Layout, fldName string) *record.Layout
 	schema := record.NewSchema()
 	schema.AddIntField("block")
 	schema.AddIntField("id")
-	if tableLayout.Schema().Type(fldName) == record.INTEGER {
-		schema.AddIntField("dataval")
-	} else {
+	if tableLayout.Schema().Type(fldName) == record.VARCHAR {
 		schema.AddStringField("dataval", tableLayout.Schema().Length(fldName))
+	} else {
+		schema.AddField("dataval", tableLayout.Schema().Type(fldName), tableLayout.Schema().Length(fldName))
 	}
 	return record.NewLayout(schema)
 }
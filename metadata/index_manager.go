@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"fmt"
+	"jadb/index/hash"
 	"jadb/record"
 	"jadb/scan_types"
 	"jadb/tx"
@@ -102,3 +104,123 @@ func (manager *IndexManager) getIndexInfo(tblName string, txn *tx.Transaction) (
 	}
 	return result, nil
 }
+
+// dropIndex
+// deletes the catalog entry of the index, its buckets are removed when the
+// transaction commits
+func (manager *IndexManager) dropIndex(idxName string, txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, "idxcat", manager.layout)
+	if err != nil {
+		return err
+	}
+	count, err := deleteRecords(ts, "indexname", idxName)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("index %s not found", idxName)
+	}
+	return hash.Remove(txn, idxName)
+}
+
+// dropIndexes
+// drops every index of the table
+func (manager *IndexManager) dropIndexes(tblName string, txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, "idxcat", manager.layout)
+	if err != nil {
+		return err
+	}
+	var idxNames []string
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		if err != nil {
+			ts.Close()
+			return err
+		}
+		tableName, err := ts.GetString("tablename")
+		if err != nil {
+			ts.Close()
+			return err
+		}
+		if tableName != tblName {
+			continue
+		}
+		idxName, err := ts.GetString("indexname")
+		if err != nil {
+			ts.Close()
+			return err
+		}
+		idxNames = append(idxNames, idxName)
+	}
+	ts.Close()
+	for _, idxName := range idxNames {
+		if err := manager.dropIndex(idxName, txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTable
+// moves the indexes of the table to its new name
+func (manager *IndexManager) renameTable(oldName string, newName string, txn *tx.Transaction) error {
+	return manager.updateEntries(oldName, "", "tablename", newName, txn)
+}
+
+// renameField
+// moves the indexes on the field of the table to its new name
+func (manager *IndexManager) renameField(tblName string, oldName string, newName string, txn *tx.Transaction) error {
+	return manager.updateEntries(tblName, oldName, "fieldname", newName, txn)
+}
+
+// updateEntries
+// sets fldName to val in the entries of the indexes of the table, only the
+// ones on field unless it is empty
+func (manager *IndexManager) updateEntries(tblName string, field string, fldName string, val string,
+	txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, "idxcat", manager.layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		if err != nil {
+			return err
+		}
+		tableName, err := ts.GetString("tablename")
+		if err != nil {
+			return err
+		}
+		fieldName, err := ts.GetString("fieldname")
+		if err != nil {
+			return err
+		}
+		if tableName != tblName || (field != "" && fieldName != field) {
+			continue
+		}
+		if err := ts.SetString(fldName, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (manager *IndexManager) hasIndex(idxName string, txn *tx.Transaction) (bool, error) {
+	ts, err := scan_types.NewTableScan(txn, "idxcat", manager.layout)
+	if err != nil {
+		return false, err
+	}
+	defer ts.Close()
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		if err != nil {
+			return false, err
+		}
+		name, err := ts.GetString("indexname")
+		if err != nil {
+			return false, err
+		}
+		if name == idxName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
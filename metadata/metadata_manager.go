@@ -10,14 +10,17 @@ type MetadataManager struct {
 	statMgr  *StatManager
 	indexMgr *IndexManager
 	viewMgr  *ViewManager
+	conMgr   *ConstraintManager
 }
 
-func NewMetadataManager(tblMgr *TableManager, statMgr *StatManager, indexMgr *IndexManager, viewMgr *ViewManager) *MetadataManager {
+func NewMetadataManager(tblMgr *TableManager, statMgr *StatManager, indexMgr *IndexManager, viewMgr *ViewManager,
+	conMgr *ConstraintManager) *MetadataManager {
 	return &MetadataManager{
 		tblMgr,
 		statMgr,
 		indexMgr,
 		viewMgr,
+		conMgr,
 	}
 }
 
@@ -28,10 +31,85 @@ func (manager *MetadataManager) CreateTable(tblName string, schema *record.Schem
 	return nil
 }
 
+// DropTable
+// drops the table, its indexes and its constraints
+func (manager *MetadataManager) DropTable(tblName string, txn *tx.Transaction) error {
+	if err := manager.indexMgr.dropIndexes(tblName, txn); err != nil {
+		return err
+	}
+	if err := manager.conMgr.dropConstraint(tblName, "", txn); err != nil {
+		return err
+	}
+	if err := manager.tblMgr.dropTable(tblName, txn); err != nil {
+		return err
+	}
+	manager.statMgr.invalidate(tblName)
+	return nil
+}
+
+// AlterTable
+// replaces the schema of the table, its records have to be rewritten for
+// the new layout
+func (manager *MetadataManager) AlterTable(tblName string, schema *record.Schema, txn *tx.Transaction) error {
+	if err := manager.tblMgr.alterTable(tblName, schema, txn); err != nil {
+		return err
+	}
+	manager.statMgr.invalidate(tblName)
+	return nil
+}
+
+// RenameTable
+// moves the table, its records, its indexes and its constraints to the new
+// name
+func (manager *MetadataManager) RenameTable(oldName string, newName string, txn *tx.Transaction) error {
+	if err := manager.tblMgr.renameTable(oldName, newName, txn); err != nil {
+		return err
+	}
+	if err := manager.indexMgr.renameTable(oldName, newName, txn); err != nil {
+		return err
+	}
+	if err := manager.conMgr.renameTable(oldName, newName, txn); err != nil {
+		return err
+	}
+	manager.statMgr.invalidate(oldName)
+	return nil
+}
+
+// RenameField
+// renames the field of the table in its schema, indexes and constraints,
+// the layout of the table stays the same
+func (manager *MetadataManager) RenameField(tblName string, oldName string, newName string, txn *tx.Transaction) error {
+	layout, err := manager.tblMgr.getLayout(tblName, txn)
+	if err != nil {
+		return err
+	}
+	schema := record.NewSchema()
+	for _, fldName := range layout.Schema().Fields() {
+		name := fldName
+		if fldName == oldName {
+			name = newName
+		}
+		schema.AddField(name, layout.Schema().Type(fldName), layout.Schema().Length(fldName))
+	}
+	if err := manager.tblMgr.alterTable(tblName, schema, txn); err != nil {
+		return err
+	}
+	if err := manager.indexMgr.renameField(tblName, oldName, newName, txn); err != nil {
+		return err
+	}
+	return manager.conMgr.renameField(tblName, oldName, newName, txn)
+}
+
 func (manager *MetadataManager) GetLayout(tblName string, txn *tx.Transaction) (*record.Layout, error) {
 	return manager.tblMgr.getLayout(tblName, txn)
 }
 
+// GetTableNames
+// the names of every table, the catalog tables among them
+func (manager *MetadataManager) GetTableNames(txn *tx.Transaction) ([]string, error) {
+	return manager.tblMgr.tableNames(txn)
+}
+
 func (manager *MetadataManager) CreateView(viewName string, viewDef string, txn *tx.Transaction) error {
 	return manager.viewMgr.createView(viewName, viewDef, txn)
 }
@@ -40,10 +118,30 @@ func (manager *MetadataManager) GetViewDef(viewName string, txn *tx.Transaction)
 	return manager.viewMgr.getViewDef(viewName, txn)
 }
 
+// GetViewNames
+// the names of every view
+func (manager *MetadataManager) GetViewNames(txn *tx.Transaction) ([]string, error) {
+	return manager.viewMgr.viewNames(txn)
+}
+
+func (manager *MetadataManager) DropView(viewName string, txn *tx.Transaction) error {
+	return manager.viewMgr.dropView(viewName, txn)
+}
+
 func (manager *MetadataManager) CreateIndex(indexName string, tableName string, fieldName string, txn *tx.Transaction) error {
 	return manager.indexMgr.createIndex(indexName, tableName, fieldName, txn)
 }
 
+func (manager *MetadataManager) DropIndex(indexName string, txn *tx.Transaction) error {
+	return manager.indexMgr.dropIndex(indexName, txn)
+}
+
+// HasIndex
+// true when an index of any table has the name
+func (manager *MetadataManager) HasIndex(indexName string, txn *tx.Transaction) (bool, error) {
+	return manager.indexMgr.hasIndex(indexName, txn)
+}
+
 func (manager *MetadataManager) GetIndexInfo(tableName string, txn *tx.Transaction) (map[string]IndexInfo, error) {
 	return manager.indexMgr.getIndexInfo(tableName, txn)
 }
@@ -51,3 +149,21 @@ func (manager *MetadataManager) GetIndexInfo(tableName string, txn *tx.Transacti
 func (manager *MetadataManager) GetStatInfo(tableName string, layout *record.Layout, txn *tx.Transaction) (*StatInfo, error) {
 	return manager.statMgr.getStatInfo(tableName, layout, txn)
 }
+
+func (manager *MetadataManager) CreateConstraint(tableName string, constraint *record.Constraint, txn *tx.Transaction) error {
+	return manager.conMgr.createConstraint(tableName, constraint, txn)
+}
+
+func (manager *MetadataManager) GetConstraints(tableName string, txn *tx.Transaction) ([]*record.Constraint, error) {
+	return manager.conMgr.getConstraints(tableName, txn)
+}
+
+// GetReferences
+// the foreign keys of any table referring to the table
+func (manager *MetadataManager) GetReferences(tableName string, txn *tx.Transaction) ([]Reference, error) {
+	return manager.conMgr.getReferences(tableName, txn)
+}
+
+func (manager *MetadataManager) DropConstraint(tableName string, name string, txn *tx.Transaction) error {
+	return manager.conMgr.dropConstraint(tableName, name, txn)
+}
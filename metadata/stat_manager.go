@@ -46,6 +46,15 @@ func (manager *StatManager) getStatInfo(tableName string, layout *record.Layout,
 	return &si, nil
 }
 
+// invalidate
+// forgets the statistics of the table, they are calculated again when
+// they are asked for
+func (manager *StatManager) invalidate(tableName string) {
+	manager.infoLock.Lock()
+	defer manager.infoLock.Unlock()
+	delete(manager.tableStats, tableName)
+}
+
 func (manager *StatManager) refreshStatistics(txn *tx.Transaction) error {
 	manager.refreshLock.Lock()
 	defer manager.refreshLock.Unlock()
@@ -61,11 +61,6 @@ func TestStatManager(t *testing.T) {
 	assert.NoError(err)
 
 	testRecordCount := 1000
-	requiredBytes := testTableLayout.SlotSize() * testRecordCount
-	requiredBlocks := int(requiredBytes / env.blockSize)
-	if requiredBytes%env.blockSize > 0 {
-		requiredBlocks++
-	}
 	for i := 0; i < testRecordCount; i++ {
 		err = ts.Insert()
 		assert.NoError(err)
@@ -74,6 +69,8 @@ func TestStatManager(t *testing.T) {
 		assert.NoError(ts.SetInt("age", i*2))
 	}
 	ts.Close()
+	requiredBlocks, err := txn.Size(scan_types.TableFileName(testTableName))
+	assert.NoError(err)
 	assert.NoError(txn.Commit())
 
 	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
@@ -119,11 +116,6 @@ func TestStatsUpdateAfter100Calls(t *testing.T) {
 	assert.NoError(err)
 
 	testRecordCount := 100
-	requiredBytes := testTableLayout.SlotSize() * testRecordCount
-	requiredBlocks := int(requiredBytes / env.blockSize)
-	if requiredBytes%env.blockSize > 0 {
-		requiredBlocks++
-	}
 	for i := 0; i < testRecordCount; i++ {
 		err = ts.Insert()
 		assert.NoError(err)
@@ -132,6 +124,8 @@ func TestStatsUpdateAfter100Calls(t *testing.T) {
 		assert.NoError(ts.SetInt("age", i*2))
 	}
 	ts.Close()
+	requiredBlocks, err := txn.Size(scan_types.TableFileName(testTableName))
+	assert.NoError(err)
 	assert.NoError(txn.Commit())
 
 	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
@@ -148,11 +142,6 @@ func TestStatsUpdateAfter100Calls(t *testing.T) {
 	// add more records in the table
 
 	extraRecordsCount := 300
-	extraBytes := extraRecordsCount * testTableLayout.SlotSize()
-	extraBlocks := extraBytes / env.blockSize
-	if extraBytes%env.blockSize != 0 {
-		extraBlocks++
-	}
 	for i := 0; i < extraRecordsCount; i++ {
 		assert.NoError(ts.Insert())
 		assert.NoError(ts.SetInt("id", i+1))
@@ -160,6 +149,9 @@ func TestStatsUpdateAfter100Calls(t *testing.T) {
 		assert.NoError(ts.SetInt("age", i+1))
 	}
 	ts.Close()
+	totalBlocks, err := txn.Size(scan_types.TableFileName(testTableName))
+	assert.NoError(err)
+	assert.Greater(totalBlocks, requiredBlocks)
 
 	//check if stats manager shows old stats
 	//100 calls
@@ -177,7 +169,7 @@ func TestStatsUpdateAfter100Calls(t *testing.T) {
 	assert.NoError(err)
 
 	assert.Equal(testTableStats.numRecs, testRecordCount+extraRecordsCount)
-	assert.Equal(testTableStats.numBlocks, requiredBlocks+extraBlocks)
+	assert.Equal(testTableStats.numBlocks, totalBlocks)
 	assert.NoError(txn.Commit())
 
 	clearEnv(t, env)
@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"fmt"
+	"jadb/file"
 	"jadb/record"
 	"jadb/scan_types"
 	"jadb/tx"
@@ -153,3 +154,151 @@ func (tblMgr *TableManager) getLayout(tblname string, txn *tx.Transaction) (*rec
 	tblLayout := record.NewLayout1(tblSchema, offsets, slotsize)
 	return &tblLayout, nil
 }
+
+// dropTable
+// deletes the catalog entries of the table, its file and free space map
+// are removed when the transaction commits
+func (tblMgr *TableManager) dropTable(tblName string, txn *tx.Transaction) error {
+	if err := tblMgr.deleteEntries(tblName, txn); err != nil {
+		return err
+	}
+	if err := txn.Remove(scan_types.TableFileName(tblName)); err != nil {
+		return err
+	}
+	return txn.Remove(scan_types.FreeSpaceFileName(tblName))
+}
+
+// alterTable
+// replaces the catalog entries of the table with the ones of schema, the
+// records of the table have to be rewritten when its layout changes
+func (tblMgr *TableManager) alterTable(tblName string, schema *record.Schema, txn *tx.Transaction) error {
+	if err := tblMgr.deleteEntries(tblName, txn); err != nil {
+		return err
+	}
+	return tblMgr.createTable(tblName, schema, txn)
+}
+
+// renameTable
+// moves the catalog entries and the blocks of the table to the new name.
+// Every record keeps its block and slot, so the indexes stay valid. The
+// free space map is dropped, the new one fills in as records are inserted
+func (tblMgr *TableManager) renameTable(oldName string, newName string, txn *tx.Transaction) error {
+	layout, err := tblMgr.getLayout(oldName, txn)
+	if err != nil {
+		return err
+	}
+	if err := tblMgr.deleteEntries(oldName, txn); err != nil {
+		return err
+	}
+	if err := tblMgr.createTable(newName, layout.Schema(), txn); err != nil {
+		return err
+	}
+	oldFile := scan_types.TableFileName(oldName)
+	newFile := scan_types.TableFileName(newName)
+	oldSize, err := txn.Size(oldFile)
+	if err != nil {
+		return err
+	}
+	// a rolled back rename can leave cleared blocks behind in the new file
+	newSize, err := txn.Size(newFile)
+	if err != nil {
+		return err
+	}
+	for blockNumber := 0; blockNumber < max(oldSize, newSize); blockNumber++ {
+		dest := file.NewBlock(newFile, blockNumber)
+		if blockNumber >= newSize {
+			if dest, err = txn.Append(newFile); err != nil {
+				return err
+			}
+		}
+		if blockNumber >= oldSize {
+			err = record.ClearBlock(txn, dest)
+		} else {
+			err = record.CopyBlock(txn, file.NewBlock(oldFile, blockNumber), dest)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := txn.Remove(oldFile); err != nil {
+		return err
+	}
+	if err := txn.Remove(scan_types.FreeSpaceFileName(oldName)); err != nil {
+		return err
+	}
+	return record.NewFreeSpaceMap(txn, scan_types.FreeSpaceFileName(newName)).Clear()
+}
+
+// tableNames
+// the names of the tables in tblcat, the catalog tables among them
+func (tblMgr *TableManager) tableNames(txn *tx.Transaction) ([]string, error) {
+	ts, err := scan_types.NewTableScan(txn, "tblcat", tblMgr.tableCatalogLayout)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+	var names []string
+	for {
+		hasNext, err := ts.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !hasNext {
+			return names, nil
+		}
+		name, err := ts.GetString("tblname")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+}
+
+// deleteEntries
+// deletes the rows of the table from tblcat and fldcat
+func (tblMgr *TableManager) deleteEntries(tblName string, txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, "tblcat", tblMgr.tableCatalogLayout)
+	if err != nil {
+		return err
+	}
+	count, err := deleteRecords(ts, "tblname", tblName)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("no table named %s exists in catalog", tblName)
+	}
+	if ts, err = scan_types.NewTableScan(txn, "fldcat", tblMgr.fldCatalogLayout); err != nil {
+		return err
+	}
+	_, err = deleteRecords(ts, "tblname", tblName)
+	return err
+}
+
+// deleteRecords
+// deletes the records of the catalog scan whose field is val and closes
+// the scan, returns the number of records deleted
+func deleteRecords(ts *scan_types.TableScan, fldName string, val string) (int, error) {
+	defer ts.Close()
+	count := 0
+	for {
+		hasNext, err := ts.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return count, nil
+		}
+		current, err := ts.GetString(fldName)
+		if err != nil {
+			return 0, err
+		}
+		if current != val {
+			continue
+		}
+		if err := ts.Delete(); err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"jadb/file"
 	"jadb/log"
 	"jadb/record"
+	"jadb/scan_types"
 	"jadb/tx"
 	"os"
 	"path/filepath"
@@ -161,3 +162,59 @@ func TestCreateMultipleTables(t *testing.T) {
 
 	clearEnv(t, env)
 }
+
+func TestDropTable(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	tblMgr, err := NewTableManager(true, txn)
+	assert.NoError(err)
+	tblSchema := record.NewSchema()
+	tblSchema.AddIntField("id")
+	assert.NoError(tblMgr.createTable("kept", tblSchema, txn))
+	assert.NoError(tblMgr.createTable("dropped", tblSchema, txn))
+	ts, err := scan_types.NewTableScan(txn, "dropped", record.NewLayout(tblSchema))
+	assert.NoError(err)
+	ts.Close()
+	statMgr, err := NewStatManager(tblMgr, txn)
+	assert.NoError(err)
+	_, cached := statMgr.tableStats["dropped"]
+	assert.True(cached)
+	assert.NoError(txn.Commit())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(tblMgr.dropTable("dropped", txn))
+	assert.Error(tblMgr.dropTable("dropped", txn))
+	statMgr.invalidate("dropped")
+	_, cached = statMgr.tableStats["dropped"]
+	assert.False(cached)
+	//the file stays until the transaction commits
+	droppedFilePath := filepath.Join(env.tempDir, "dropped.tbl")
+	_, err = os.Stat(droppedFilePath)
+	assert.NoError(err)
+	assert.NoError(txn.Commit())
+	_, err = os.Stat(droppedFilePath)
+	assert.True(os.IsNotExist(err))
+
+	// no field of the table is left in fldcat
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	_, err = tblMgr.getLayout("dropped", txn)
+	assert.Error(err)
+	layout, err := tblMgr.getLayout("kept", txn)
+	assert.NoError(err)
+	assert.True(layout.Schema().Equals(tblSchema))
+	ts, err = scan_types.NewTableScan(txn, "fldcat", tblMgr.fldCatalogLayout)
+	assert.NoError(err)
+	for hasNext, err := ts.Next(); hasNext; hasNext, err = ts.Next() {
+		assert.NoError(err)
+		tblName, err := ts.GetString("tblname")
+		assert.NoError(err)
+		assert.NotEqual("dropped", tblName)
+	}
+	ts.Close()
+	assert.NoError(txn.Commit())
+	clearEnv(t, env)
+}
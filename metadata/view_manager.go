@@ -79,3 +79,51 @@ func (manager *ViewManager) getViewDef(viewName string, txn *tx.Transaction) (st
 	}
 	return "", fmt.Errorf("view %s not found", viewName)
 }
+
+func (manager *ViewManager) dropView(viewName string, txn *tx.Transaction) error {
+	layout, err := manager.tblMgr.getLayout("viewcat", txn)
+	if err != nil {
+		return err
+	}
+	ts, err := scan_types.NewTableScan(txn, "viewcat", layout)
+	if err != nil {
+		return err
+	}
+	count, err := deleteRecords(ts, "viewname", viewName)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("view %s not found", viewName)
+	}
+	return nil
+}
+
+// viewNames
+// the names of the views in viewcat
+func (manager *ViewManager) viewNames(txn *tx.Transaction) ([]string, error) {
+	layout, err := manager.tblMgr.getLayout("viewcat", txn)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := scan_types.NewTableScan(txn, "viewcat", layout)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+	var names []string
+	for {
+		hasNext, err := ts.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !hasNext {
+			return names, nil
+		}
+		name, err := ts.GetString("viewname")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+}
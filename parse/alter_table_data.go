@@ -0,0 +1,75 @@
+package parse
+
+import "jadb/record"
+
+// AlterAction
+// what an alter table statement changes
+type AlterAction int
+
+const (
+	AddColumn AlterAction = iota
+	DropColumn
+	RenameColumn
+	RenameTable
+)
+
+// AlterTableData
+// fieldName is the column added, dropped or renamed and newName the new
+// name of the column or table. An added column has its type in schema and
+// every existing record gets defaultVal for it
+type AlterTableData struct {
+	tableName  string
+	action     AlterAction
+	fieldName  string
+	newName    string
+	schema     *record.Schema
+	defaultVal any
+}
+
+func NewAddColumnData(tableName string, schema *record.Schema, defaultVal any) *AlterTableData {
+	return &AlterTableData{
+		tableName:  tableName,
+		action:     AddColumn,
+		fieldName:  schema.Fields()[0],
+		schema:     schema,
+		defaultVal: defaultVal,
+	}
+}
+
+func NewDropColumnData(tableName string, fieldName string) *AlterTableData {
+	return &AlterTableData{tableName: tableName, action: DropColumn, fieldName: fieldName}
+}
+
+func NewRenameColumnData(tableName string, fieldName string, newName string) *AlterTableData {
+	return &AlterTableData{tableName: tableName, action: RenameColumn, fieldName: fieldName, newName: newName}
+}
+
+func NewRenameTableData(tableName string, newName string) *AlterTableData {
+	return &AlterTableData{tableName: tableName, action: RenameTable, newName: newName}
+}
+
+func (a *AlterTableData) TableName() string {
+	return a.tableName
+}
+
+func (a *AlterTableData) Action() AlterAction {
+	return a.action
+}
+
+func (a *AlterTableData) FieldName() string {
+	return a.fieldName
+}
+
+func (a *AlterTableData) NewName() string {
+	return a.newName
+}
+
+// Schema
+// the schema of the added column, nil for the other actions
+func (a *AlterTableData) Schema() *record.Schema {
+	return a.schema
+}
+
+func (a *AlterTableData) DefaultVal() any {
+	return a.defaultVal
+}
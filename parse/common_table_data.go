@@ -0,0 +1,36 @@
+package parse
+
+import "strings"
+
+// CommonTableData
+// a table defined by a with clause, columns renames the output columns of
+// its query and is empty when they keep their names
+type CommonTableData struct {
+	name    string
+	columns []string
+	query   *QueryData
+}
+
+func NewCommonTableData(name string, columns []string, query *QueryData) *CommonTableData {
+	return &CommonTableData{name, columns, query}
+}
+
+func (c *CommonTableData) Name() string {
+	return c.name
+}
+
+func (c *CommonTableData) Columns() []string {
+	return c.columns
+}
+
+func (c *CommonTableData) Query() *QueryData {
+	return c.query
+}
+
+func (c *CommonTableData) String() string {
+	result := c.name
+	if len(c.columns) > 0 {
+		result += " (" + strings.Join(c.columns, ", ") + ")"
+	}
+	return result + " as (" + c.query.String() + ")"
+}
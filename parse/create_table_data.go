@@ -3,13 +3,14 @@ package parse
 import "jadb/record"
 
 type CreateTableData struct {
-	tableName string
-	schema    *record.Schema
+	tableName   string
+	schema      *record.Schema
+	constraints []*record.Constraint
 }
 
-func NewCreateTableData(tableName string, schema *record.Schema) *CreateTableData {
+func NewCreateTableData(tableName string, schema *record.Schema, constraints []*record.Constraint) *CreateTableData {
 	return &CreateTableData{
-		tableName, schema,
+		tableName, schema, constraints,
 	}
 }
 
@@ -20,3 +21,10 @@ func (c *CreateTableData) TableName() string {
 func (c *CreateTableData) Schema() *record.Schema {
 	return c.schema
 }
+
+// Constraints
+// the constraints declared with the fields followed by the table
+// constraints, those without a name are named when the table is created
+func (c *CreateTableData) Constraints() []*record.Constraint {
+	return c.constraints
+}
@@ -13,7 +13,7 @@ func TestCreateTableData(t *testing.T) {
 	newTableSchema.AddIntField("id")
 	newTableSchema.AddStringField("name", 10)
 	newTableSchema.AddIntField("age")
-	createTableData := NewCreateTableData(newTableName, newTableSchema)
+	createTableData := NewCreateTableData(newTableName, newTableSchema, nil)
 	assert.Equal(newTableName, createTableData.TableName())
 	assert.Equal(newTableSchema, createTableData.Schema())
 }
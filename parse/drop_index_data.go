@@ -0,0 +1,13 @@
+package parse
+
+type DropIndexData struct {
+	indexName string
+}
+
+func NewDropIndexData(indexName string) *DropIndexData {
+	return &DropIndexData{indexName}
+}
+
+func (d *DropIndexData) IndexName() string {
+	return d.indexName
+}
@@ -0,0 +1,13 @@
+package parse
+
+type DropTableData struct {
+	tableName string
+}
+
+func NewDropTableData(tableName string) *DropTableData {
+	return &DropTableData{tableName}
+}
+
+func (d *DropTableData) TableName() string {
+	return d.tableName
+}
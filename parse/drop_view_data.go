@@ -0,0 +1,13 @@
+package parse
+
+type DropViewData struct {
+	viewName string
+}
+
+func NewDropViewData(viewName string) *DropViewData {
+	return &DropViewData{viewName}
+}
+
+func (d *DropViewData) ViewName() string {
+	return d.viewName
+}
@@ -1,13 +1,23 @@
 package parse
 
+// InsertData
+// insert into a table, the records are either the rows of values or the
+// records of query. Fields is empty when the statement has no field list
 type InsertData struct {
 	tblName   string
 	fieldList []string
-	values    []any
+	values    [][]any
+	query     *QueryData
 }
 
-func NewInsertData(tblName string, fieldList []string, values []any) *InsertData {
-	return &InsertData{tblName, fieldList, values}
+func NewInsertData(tblName string, fieldList []string, values [][]any) *InsertData {
+	return &InsertData{tblName, fieldList, values, nil}
+}
+
+// NewInsertSelectData
+// insert into tblName (fieldList) query
+func NewInsertSelectData(tblName string, fieldList []string, query *QueryData) *InsertData {
+	return &InsertData{tblName, fieldList, nil, query}
 }
 
 func (i *InsertData) TableName() string {
@@ -18,6 +28,14 @@ func (i *InsertData) Fields() []string {
 	return i.fieldList
 }
 
-func (i *InsertData) Values() []any {
+// Values
+// the rows of the values clause, nil when the records come from a query
+func (i *InsertData) Values() [][]any {
 	return i.values
 }
+
+// Query
+// the query the records come from, nil when they are given by values
+func (i *InsertData) Query() *QueryData {
+	return i.query
+}
@@ -0,0 +1,22 @@
+package parse
+
+import "jadb/query"
+
+// JoinData
+// how a table of the from clause is joined to the tables before it
+type JoinData struct {
+	joinType query.JoinType
+	on       *query.Predicate
+}
+
+func NewJoinData(joinType query.JoinType, on *query.Predicate) *JoinData {
+	return &JoinData{joinType, on}
+}
+
+func (j *JoinData) JoinType() query.JoinType {
+	return j.joinType
+}
+
+func (j *JoinData) On() *query.Predicate {
+	return j.on
+}
@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,7 +16,10 @@ const (
 	TTNumber
 	TTString
 	TTOperator
+	TTId
 	TTEof
+	TTDouble
+	TTBlob
 )
 
 type SyntaxError struct {
@@ -30,6 +34,7 @@ type Token struct {
 	tokenType TokenType
 	delimiter rune
 	number    int
+	double    float64
 	str       string
 	operator  string
 }
@@ -43,7 +48,7 @@ type Lexer struct {
 
 func NewLexer(input string) (*Lexer, error) {
 	l := &Lexer{
-		input: strings.ToLower(input),
+		input: input,
 	}
 	l.initKeywords()
 	if err := l.Next(); err != nil {
@@ -64,12 +69,36 @@ func (lexer *Lexer) matchStringConstant() bool {
 	return lexer.currentToken.tokenType == TTString
 }
 
+func (lexer *Lexer) matchDoubleConstant() bool {
+	return lexer.currentToken.tokenType == TTDouble
+}
+
+func (lexer *Lexer) matchBlobConstant() bool {
+	return lexer.currentToken.tokenType == TTBlob
+}
+
+// arithmeticOperator
+// the current token if it can be an arithmetic operator, empty otherwise
+func (lexer *Lexer) arithmeticOperator() string {
+	switch {
+	case lexer.matchDelim('*'):
+		return "*"
+	case lexer.currentToken.tokenType == TTOperator:
+		return lexer.currentToken.operator
+	}
+	return ""
+}
+
 func (lexer *Lexer) matchKeyword(keyword string) bool {
-	return lexer.currentToken.tokenType == TTString && lexer.currentToken.str == keyword && lexer.keywords[lexer.currentToken.str]
+	return lexer.currentToken.tokenType == TTId && lexer.currentToken.str == keyword && lexer.keywords[lexer.currentToken.str]
 }
 
 func (lexer *Lexer) matchId() bool {
-	return lexer.currentToken.tokenType == TTString && !lexer.keywords[lexer.currentToken.str]
+	return lexer.currentToken.tokenType == TTId && !lexer.keywords[lexer.currentToken.str]
+}
+
+func (lexer *Lexer) matchOperator(op string) bool {
+	return lexer.currentToken.tokenType == TTOperator && lexer.currentToken.operator == op
 }
 
 func (lexer *Lexer) eatDelim(d rune) error {
@@ -104,9 +133,35 @@ func (lexer *Lexer) eatStringConstant() (string, error) {
 	return val, nil
 }
 
+func (lexer *Lexer) eatDoubleConstant() (float64, error) {
+	if lexer.currentToken.tokenType != TTDouble {
+		return 0, &SyntaxError{"expected double"}
+	}
+	val := lexer.currentToken.double
+	return val, lexer.Next()
+}
+
+// eatBlobConstant
+// the bytes of a x'...' literal
+func (lexer *Lexer) eatBlobConstant() (string, error) {
+	if lexer.currentToken.tokenType != TTBlob {
+		return "", &SyntaxError{"expected blob"}
+	}
+	val := lexer.currentToken.str
+	return val, lexer.Next()
+}
+
+func (lexer *Lexer) eatOperator() (string, error) {
+	if lexer.currentToken.tokenType != TTOperator {
+		return "", &SyntaxError{fmt.Sprintf("expected an operator at %d got %v", lexer.position, lexer.currentToken.tokenType)}
+	}
+	val := lexer.currentToken.operator
+	return val, lexer.Next()
+}
+
 func (lexer *Lexer) eatKeyword(keyword string) error {
-	if lexer.currentToken.tokenType != TTString {
-		return &SyntaxError{fmt.Sprintf("expected TTString,got %v", lexer.currentToken.tokenType)}
+	if lexer.currentToken.tokenType != TTId {
+		return &SyntaxError{fmt.Sprintf("expected TTId,got %v", lexer.currentToken.tokenType)}
 	}
 	val := lexer.currentToken.str
 	if val != keyword {
@@ -116,8 +171,8 @@ func (lexer *Lexer) eatKeyword(keyword string) error {
 }
 
 func (lexer *Lexer) eatId() (string, error) {
-	if lexer.currentToken.tokenType != TTString {
-		return "", &SyntaxError{"expected TTString"}
+	if !lexer.matchId() {
+		return "", &SyntaxError{fmt.Sprintf("expected identifier at %d", lexer.position)}
 	}
 	val := lexer.currentToken.str
 	return val, lexer.Next()
@@ -127,8 +182,21 @@ func (lexer *Lexer) initKeywords() {
 	keywords := []string{
 		"select", "from", "where", "and",
 		"insert", "into", "values", "delete", "update",
-		"set", "create", "table", "varchar",
+		"set", "create", "drop", "table", "varchar",
 		"int", "view", "as", "index", "on",
+		"distinct", "order", "by", "asc", "desc",
+		"limit", "offset",
+		"join", "inner", "left", "right", "full", "outer",
+		"is", "not", "null", "in", "exists",
+		"union", "intersect", "except", "all",
+		"with", "recursive",
+		"over", "partition", "rows", "between", "unbounded",
+		"preceding", "following", "current", "row",
+		"alter", "add", "column", "rename", "to", "default",
+		"constraint", "primary", "key", "unique",
+		"foreign", "references", "cascade", "restrict", "check",
+		"bigint", "boolean", "double", "date", "timestamp", "blob",
+		"true", "false", "vacuum", "scrub",
 	}
 	keywordsMap := make(map[string]bool)
 	for _, keyword := range keywords {
@@ -151,32 +219,34 @@ func (lexer *Lexer) Next() error {
 		return nil
 	case isStringStart(nextRune):
 		{
-			lexer.position++
-			var sb strings.Builder
-			terminated := false
-			for lexer.position < len(lexer.input) {
-				if lexer.input[lexer.position] == '\'' {
-					terminated = true
-					break
-				}
-				nextRune, width := utf8.DecodeLastRuneInString(lexer.input[lexer.position:])
-				lexer.position += width
-				sb.WriteRune(nextRune)
+			lexer.position += width
+			str, err := lexer.stringBody()
+			if err != nil {
+				return err
 			}
-			if !terminated {
-				return &SyntaxError{"unterminated string"}
+			lexer.currentToken = Token{tokenType: TTString, str: str}
+			return nil
+		}
+	case isBlobStart(nextRune) && lexer.position+width < len(lexer.input) &&
+		isStringStart(rune(lexer.input[lexer.position+width])):
+		{
+			lexer.position += width + 1
+			hexDigits, err := lexer.stringBody()
+			if err != nil {
+				return err
 			}
-			if sb.Len() == 0 {
-				return &SyntaxError{"expected a string,got nothing"}
+			blob, err := hex.DecodeString(hexDigits)
+			if err != nil {
+				return &SyntaxError{fmt.Sprintf("invalid blob x'%s'", hexDigits)}
 			}
-			lexer.currentToken = Token{tokenType: TTString, str: sb.String()}
+			lexer.currentToken = Token{tokenType: TTBlob, str: string(blob)}
 			return nil
 		}
-	case isIntStart(nextRune):
+	case isIntStart(nextRune) && lexer.isNumberAhead():
 		{
 			var number strings.Builder
 			number.WriteRune(nextRune)
-			lexer.position++
+			lexer.position += width
 			for lexer.position < len(lexer.input) {
 				nextRune, width = utf8.DecodeRuneInString(lexer.input[lexer.position:])
 				if !unicode.IsDigit(nextRune) {
@@ -185,6 +255,20 @@ func (lexer *Lexer) Next() error {
 				number.WriteRune(nextRune)
 				lexer.position += width
 			}
+			if lexer.isFractionAhead() {
+				number.WriteRune('.')
+				lexer.position++
+				for lexer.position < len(lexer.input) && unicode.IsDigit(rune(lexer.input[lexer.position])) {
+					number.WriteByte(lexer.input[lexer.position])
+					lexer.position++
+				}
+				double, err := strconv.ParseFloat(number.String(), 64)
+				if err != nil {
+					return &SyntaxError{"expected double"}
+				}
+				lexer.currentToken = Token{tokenType: TTDouble, double: double}
+				return nil
+			}
 			num, err := strconv.Atoi(number.String())
 			if err != nil {
 				return &SyntaxError{"expected number"}
@@ -196,11 +280,16 @@ func (lexer *Lexer) Next() error {
 		{
 			var operator strings.Builder
 			operator.WriteRune(nextRune)
-			lexer.position++
-			nextRune, width = utf8.DecodeRuneInString(lexer.input[lexer.position:])
 			lexer.position += width
-			if !isOperatorStart(nextRune) {
-				return &SyntaxError{fmt.Sprintf("expected a operator,got %c", nextRune)}
+			if lexer.position < len(lexer.input) {
+				secondRune, secondWidth := utf8.DecodeRuneInString(lexer.input[lexer.position:])
+				if isOperator(operator.String() + string(secondRune)) {
+					operator.WriteRune(secondRune)
+					lexer.position += secondWidth
+				}
+			}
+			if !isOperator(operator.String()) {
+				return &SyntaxError{fmt.Sprintf("unknown operator %s", operator.String())}
 			}
 			lexer.currentToken = Token{tokenType: TTOperator, operator: operator.String()}
 			return nil
@@ -208,23 +297,79 @@ func (lexer *Lexer) Next() error {
 	case unicode.IsLetter(nextRune) || nextRune == '_':
 		{
 			var word strings.Builder
-			word.WriteRune(nextRune)
+			word.WriteRune(unicode.ToLower(nextRune))
 			lexer.position += width
 			nextRune, width = utf8.DecodeRuneInString(lexer.input[lexer.position:])
-			for unicode.IsLetter(nextRune) || nextRune == '_' {
-				word.WriteRune(nextRune)
+			for unicode.IsLetter(nextRune) || unicode.IsDigit(nextRune) || nextRune == '_' {
+				word.WriteRune(unicode.ToLower(nextRune))
 				lexer.position += width
 				nextRune, width = utf8.DecodeRuneInString(lexer.input[lexer.position:])
 			}
-			lexer.currentToken = Token{tokenType: TTString, str: word.String()}
+			lexer.currentToken = Token{tokenType: TTId, str: word.String()}
 			return nil
 		}
 	}
 	return fmt.Errorf("unexpected character %c at %d", nextRune, lexer.position)
 }
 
+// stringBody
+// the characters up to the closing quote, which is consumed
+func (lexer *Lexer) stringBody() (string, error) {
+	var sb strings.Builder
+	for lexer.position < len(lexer.input) {
+		nextRune, width := utf8.DecodeRuneInString(lexer.input[lexer.position:])
+		lexer.position += width
+		if isStringStart(nextRune) {
+			return sb.String(), nil
+		}
+		sb.WriteRune(nextRune)
+	}
+	return "", &SyntaxError{"unterminated string"}
+}
+
+// isFractionAhead
+// true when a '.' followed by a digit continues the number, which makes it
+// a double
+func (lexer *Lexer) isFractionAhead() bool {
+	return lexer.position+1 < len(lexer.input) && lexer.input[lexer.position] == '.' &&
+		unicode.IsDigit(rune(lexer.input[lexer.position+1]))
+}
+
+// isNumberAhead a '-' only starts a number when a digit follows it and it
+// does not follow an operand, where it is a subtraction
+func (lexer *Lexer) isNumberAhead() bool {
+	nextRune, width := utf8.DecodeRuneInString(lexer.input[lexer.position:])
+	if nextRune != '-' {
+		return true
+	}
+	if lexer.followsOperand() {
+		return false
+	}
+	following, _ := utf8.DecodeRuneInString(lexer.input[lexer.position+width:])
+	return unicode.IsDigit(following)
+}
+
+// followsOperand
+// true when the current token ends an operand, a field, a constant or a
+// parenthesized expression
+func (lexer *Lexer) followsOperand() bool {
+	switch lexer.currentToken.tokenType {
+	case TTNumber, TTString, TTDouble, TTBlob:
+		return true
+	case TTId:
+		switch lexer.currentToken.str {
+		case "null", "true", "false":
+			return true
+		}
+		return !lexer.keywords[lexer.currentToken.str]
+	case TTDelimiter:
+		return lexer.currentToken.delimiter == ')'
+	}
+	return false
+}
+
 func isDelimiter(r rune) bool {
-	delimiters := []rune{',', '(', ')'}
+	delimiters := []rune{',', '(', ')', '.', '*'}
 	for _, d := range delimiters {
 		if d == r {
 			return true
@@ -237,12 +382,24 @@ func isStringStart(r rune) bool {
 	return r == '\''
 }
 
+func isBlobStart(r rune) bool {
+	return r == 'x' || r == 'X'
+}
+
 func isIntStart(r rune) bool {
 	return r == '-' || unicode.IsDigit(r)
 }
 
 func isOperatorStart(r rune) bool {
-	return r == '=' || r == '<' || r == '>' || r == '!'
+	return r == '=' || r == '<' || r == '>' || r == '!' || r == '+' || r == '-' || r == '/'
+}
+
+func isOperator(op string) bool {
+	switch op {
+	case "=", "!=", "<>", "<", "<=", ">", ">=", "+", "-", "/":
+		return true
+	}
+	return false
 }
 
 func (lexer *Lexer) skipWhitespaces() {
@@ -2,28 +2,31 @@ package parse
 
 import "jadb/query"
 
+// ModifyData
+// update of the records of a table satisfying predicate, fields[i] is set
+// to values[i] evaluated against the record before any field is set
 type ModifyData struct {
 	tableName string
-	field     string
-	value     any
+	fields    []string
+	values    []*query.Expression
 	predicate *query.Predicate
 }
 
-func NewModifyData(tableName string, field string, value any, predicate *query.Predicate) *ModifyData {
+func NewModifyData(tableName string, fields []string, values []*query.Expression, predicate *query.Predicate) *ModifyData {
 	return &ModifyData{
 		tableName,
-		field,
-		value,
+		fields,
+		values,
 		predicate,
 	}
 }
 
-func (m *ModifyData) Fields() string {
-	return m.field
+func (m *ModifyData) Fields() []string {
+	return m.fields
 }
 
-func (m *ModifyData) Values() any {
-	return m.value
+func (m *ModifyData) Values() []*query.Expression {
+	return m.values
 }
 
 func (m *ModifyData) Predicate() *query.Predicate {
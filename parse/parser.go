@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"jadb/query"
 	"jadb/record"
+	"time"
 )
 
 type Parser struct {
@@ -18,21 +19,156 @@ func NewParser(input string) (*Parser, error) {
 	return &Parser{lexer: lexer}, nil
 }
 
+// field
+// a field name optionally qualified by its table, table.field
 func (parser *Parser) field() (string, error) {
-	return parser.lexer.eatId()
+	name, err := parser.lexer.eatId()
+	if err != nil {
+		return "", err
+	}
+	if !parser.lexer.matchDelim('.') {
+		return name, nil
+	}
+	if err := parser.lexer.eatDelim('.'); err != nil {
+		return "", err
+	}
+	fldName, err := parser.lexer.eatId()
+	if err != nil {
+		return "", err
+	}
+	return name + "." + fldName, nil
 }
 
+// constant
+// a string, an int, a double, true or false, date 'yyyy-mm-dd',
+// timestamp 'yyyy-mm-dd hh:mm:ss[.ffffff]', a blob x'hex' or null, which is
+// returned as nil. Dates and timestamps are in UTC
 func (parser *Parser) constant() (any, error) {
-	if parser.lexer.matchStringConstant() {
+	switch {
+	case parser.lexer.matchKeyword("null"):
+		return nil, parser.lexer.eatKeyword("null")
+	case parser.lexer.matchKeyword("true"):
+		return true, parser.lexer.eatKeyword("true")
+	case parser.lexer.matchKeyword("false"):
+		return false, parser.lexer.eatKeyword("false")
+	case parser.lexer.matchKeyword("date"):
+		return parser.timeConstant("date", time.DateOnly)
+	case parser.lexer.matchKeyword("timestamp"):
+		return parser.timeConstant("timestamp", query.TimestampFormat)
+	case parser.lexer.matchStringConstant():
 		return parser.lexer.eatStringConstant()
-	}
-	if parser.lexer.matchIntConstant() {
+	case parser.lexer.matchIntConstant():
 		return parser.lexer.eatIntConstant()
+	case parser.lexer.matchDoubleConstant():
+		return parser.lexer.eatDoubleConstant()
+	case parser.lexer.matchBlobConstant():
+		blob, err := parser.lexer.eatBlobConstant()
+		return record.Blob(blob), err
 	}
 	return nil, fmt.Errorf("expected constant,did not find one")
 }
 
+// timeConstant
+// keyword followed by a string in layout
+func (parser *Parser) timeConstant(keyword string, layout string) (any, error) {
+	if err := parser.lexer.eatKeyword(keyword); err != nil {
+		return nil, err
+	}
+	str, err := parser.lexer.eatStringConstant()
+	if err != nil {
+		return nil, err
+	}
+	val, err := time.Parse(layout, str)
+	if err != nil {
+		return nil, &SyntaxError{fmt.Sprintf("invalid %s '%s'", keyword, str)}
+	}
+	return val, nil
+}
+
+var sumOperators = map[string]query.ArithmeticOperator{
+	"+": query.Add,
+	"-": query.Subtract,
+}
+
+var productOperators = map[string]query.ArithmeticOperator{
+	"*": query.Multiply,
+	"/": query.Divide,
+}
+
+// expression
+// sums and differences of products and quotients of factors
 func (parser *Parser) expression() (*query.Expression, error) {
+	return parser.expressionFrom(nil)
+}
+
+// expressionFrom
+// an expression whose first factor, first, was read already unless it is
+// nil
+func (parser *Parser) expressionFrom(first *query.Expression) (*query.Expression, error) {
+	factor := func() (*query.Expression, error) {
+		if first == nil {
+			return parser.factor()
+		}
+		expr := first
+		first = nil
+		return expr, nil
+	}
+	product := func() (*query.Expression, error) {
+		return parser.arithmetic(factor, productOperators)
+	}
+	return parser.arithmetic(product, sumOperators)
+}
+
+// arithmetic
+// operands separated by the operators given, applied from left to right
+func (parser *Parser) arithmetic(operand func() (*query.Expression, error),
+	operators map[string]query.ArithmeticOperator) (*query.Expression, error) {
+	expr, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := operators[parser.lexer.arithmeticOperator()]
+		if !ok {
+			return expr, nil
+		}
+		//* is a delimiter since it is also used by select *
+		if parser.lexer.matchDelim('*') {
+			err = parser.lexer.eatDelim('*')
+		} else {
+			_, err = parser.lexer.eatOperator()
+		}
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		expr = query.NewArithmeticExpression(expr, op, rhs)
+	}
+}
+
+// factor
+// a field, a constant, a scalar subquery or a parenthesized expression
+func (parser *Parser) factor() (*query.Expression, error) {
+	if parser.lexer.matchDelim('(') {
+		if err := parser.lexer.eatDelim('('); err != nil {
+			return nil, err
+		}
+		if !parser.lexer.matchKeyword("select") && !parser.lexer.matchKeyword("with") {
+			expr, err := parser.expression()
+			if err != nil {
+				return nil, err
+			}
+			return expr, parser.lexer.eatDelim(')')
+		}
+		subquery, err := parser.subquery(true)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewSubqueryExpression(subquery), nil
+	}
 	if parser.lexer.matchId() {
 		field, err := parser.field()
 		if err != nil {
@@ -47,16 +183,141 @@ func (parser *Parser) expression() (*query.Expression, error) {
 	return query.NewConstantExpression(constant), nil
 }
 
+// term
+// a comparison of two expressions, expression is [not] null,
+// expression [not] in (...) or [not] exists (subquery)
 func (parser *Parser) term() (*query.Term, error) {
+	if parser.lexer.matchKeyword("exists") || parser.lexer.matchKeyword("not") {
+		return parser.existsTerm()
+	}
 	lhe, err := parser.expression()
 	if err != nil {
 		return nil, err
 	}
-	if err := parser.lexer.eatDelim('='); err != nil {
+	if parser.lexer.matchKeyword("is") {
+		return parser.nullTest(lhe)
+	}
+	if parser.lexer.matchKeyword("in") || parser.lexer.matchKeyword("not") {
+		return parser.inTerm(lhe)
+	}
+	opStr, err := parser.lexer.eatOperator()
+	if err != nil {
 		return nil, err
 	}
+	op, ok := comparisonOperators[opStr]
+	if !ok {
+		return nil, &SyntaxError{fmt.Sprintf("unknown operator %s", opStr)}
+	}
 	rhe, err := parser.expression()
-	return query.NewTerm(lhe, rhe, query.Equal), nil
+	if err != nil {
+		return nil, err
+	}
+	return query.NewTerm(lhe, rhe, op), nil
+}
+
+var comparisonOperators = map[string]query.Operator{
+	"=":  query.Equal,
+	"!=": query.NotEqual,
+	"<>": query.NotEqual,
+	"<":  query.LessThan,
+	"<=": query.LessThanEqual,
+	">":  query.GreaterThan,
+	">=": query.GreaterThanEqual,
+}
+
+func (parser *Parser) nullTest(lhe *query.Expression) (*query.Term, error) {
+	if err := parser.lexer.eatKeyword("is"); err != nil {
+		return nil, err
+	}
+	op := query.Is
+	if parser.lexer.matchKeyword("not") {
+		if err := parser.lexer.eatKeyword("not"); err != nil {
+			return nil, err
+		}
+		op = query.IsNot
+	}
+	if err := parser.lexer.eatKeyword("null"); err != nil {
+		return nil, err
+	}
+	return query.NewTerm(lhe, query.NewConstantExpression(nil), op), nil
+}
+
+// subquery
+// the select of a subquery and its closing parenthesis, the opening one
+// has already been read
+func (parser *Parser) subquery(singleColumn bool) (*query.Subquery, error) {
+	data, err := parser.Query()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	return query.NewSubquery(data, singleColumn), nil
+}
+
+func (parser *Parser) existsTerm() (*query.Term, error) {
+	negated := false
+	if parser.lexer.matchKeyword("not") {
+		if err := parser.lexer.eatKeyword("not"); err != nil {
+			return nil, err
+		}
+		negated = true
+	}
+	if err := parser.lexer.eatKeyword("exists"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	subquery, err := parser.subquery(false)
+	if err != nil {
+		return nil, err
+	}
+	return query.NewExistsTerm(subquery, negated), nil
+}
+
+// inTerm
+// lhe [not] in, followed by a list of expressions or a subquery
+func (parser *Parser) inTerm(lhe *query.Expression) (*query.Term, error) {
+	op := query.In
+	if parser.lexer.matchKeyword("not") {
+		if err := parser.lexer.eatKeyword("not"); err != nil {
+			return nil, err
+		}
+		op = query.NotIn
+	}
+	if err := parser.lexer.eatKeyword("in"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	if parser.lexer.matchKeyword("select") || parser.lexer.matchKeyword("with") {
+		subquery, err := parser.subquery(true)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewTerm(lhe, query.NewSubqueryExpression(subquery), op), nil
+	}
+	list := make([]*query.Expression, 0)
+	for {
+		item, err := parser.expression()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		if !parser.lexer.matchDelim(',') {
+			break
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	return query.NewTerm(lhe, query.NewListExpression(list), op), nil
 }
 
 func (parser *Parser) predicate() (*query.Predicate, error) {
@@ -69,23 +330,155 @@ func (parser *Parser) predicate() (*query.Predicate, error) {
 		if err := parser.lexer.eatKeyword("and"); err != nil {
 			return nil, err
 		}
+		rest, err := parser.predicate()
+		if err != nil {
+			return nil, err
+		}
+		predicate.CojoinWith(rest)
 	}
 	return predicate, nil
 }
 
-func (parser *Parser) query() (*QueryData, error) {
+// Query
+// a select, or selects combined by union, intersect and except, optionally
+// preceded by a with clause. Order by and limit apply to the combined result
+func (parser *Parser) Query() (*QueryData, error) {
+	var with []*CommonTableData
+	recursive := false
+	if parser.lexer.matchKeyword("with") {
+		var err error
+		if with, recursive, err = parser.withClause(); err != nil {
+			return nil, err
+		}
+	}
+	data, err := parser.selectCore()
+	if err != nil {
+		return nil, err
+	}
+	data.with, data.recursive = with, recursive
+	for {
+		op, ok, err := parser.setOperator()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		all := false
+		if parser.lexer.matchKeyword("all") {
+			if err := parser.lexer.eatKeyword("all"); err != nil {
+				return nil, err
+			}
+			all = true
+		}
+		operand, err := parser.selectCore()
+		if err != nil {
+			return nil, err
+		}
+		data.setOps = append(data.setOps, NewSetOperationData(op, all, operand))
+	}
+	if parser.lexer.matchKeyword("order") {
+		if data.orderBy, err = parser.orderByList(); err != nil {
+			return nil, err
+		}
+	}
+	if parser.lexer.matchKeyword("limit") {
+		if data.limit, data.offset, err = parser.limitClause(); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// withClause
+// with [recursive] name [(columns)] as (query), ...
+func (parser *Parser) withClause() ([]*CommonTableData, bool, error) {
+	if err := parser.lexer.eatKeyword("with"); err != nil {
+		return nil, false, err
+	}
+	recursive := false
+	if parser.lexer.matchKeyword("recursive") {
+		if err := parser.lexer.eatKeyword("recursive"); err != nil {
+			return nil, false, err
+		}
+		recursive = true
+	}
+	tables := make([]*CommonTableData, 0)
+	for {
+		name, err := parser.lexer.eatId()
+		if err != nil {
+			return nil, false, err
+		}
+		var columns []string
+		if parser.lexer.matchDelim('(') {
+			if err := parser.lexer.eatDelim('('); err != nil {
+				return nil, false, err
+			}
+			if columns, err = parser.fieldList(); err != nil {
+				return nil, false, err
+			}
+			if err := parser.lexer.eatDelim(')'); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := parser.lexer.eatKeyword("as"); err != nil {
+			return nil, false, err
+		}
+		if err := parser.lexer.eatDelim('('); err != nil {
+			return nil, false, err
+		}
+		data, err := parser.Query()
+		if err != nil {
+			return nil, false, err
+		}
+		if err := parser.lexer.eatDelim(')'); err != nil {
+			return nil, false, err
+		}
+		tables = append(tables, NewCommonTableData(name, columns, data))
+		if !parser.lexer.matchDelim(',') {
+			return tables, recursive, nil
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+func (parser *Parser) setOperator() (query.SetOperator, bool, error) {
+	for keyword, op := range map[string]query.SetOperator{
+		"union":     query.Union,
+		"intersect": query.Intersect,
+		"except":    query.Except,
+	} {
+		if parser.lexer.matchKeyword(keyword) {
+			return op, true, parser.lexer.eatKeyword(keyword)
+		}
+	}
+	return 0, false, nil
+}
+
+// selectCore
+// a single select without order by and limit
+func (parser *Parser) selectCore() (*QueryData, error) {
 	err := parser.lexer.eatKeyword("select")
 	if err != nil {
 		return nil, err
 	}
-	fields, err := parser.selectList()
+	distinct := false
+	if parser.lexer.matchKeyword("distinct") {
+		if err := parser.lexer.eatKeyword("distinct"); err != nil {
+			return nil, err
+		}
+		distinct = true
+	}
+	fields, fieldAliases, fieldExprs, windows, err := parser.selectList()
 	if err != nil {
 		return nil, err
 	}
 	if err := parser.lexer.eatKeyword("from"); err != nil {
 		return nil, err
 	}
-	tables, err := parser.tableList()
+	tables, tableAliases, joins, err := parser.tableList()
 	if err != nil {
 		return nil, err
 	}
@@ -94,53 +487,456 @@ func (parser *Parser) query() (*QueryData, error) {
 		if err := parser.lexer.eatKeyword("where"); err != nil {
 			return nil, err
 		}
-		if predicate, err = parser.predicate(); err != nil {
-			return nil, err
+		if predicate, err = parser.predicate(); err != nil {
+			return nil, err
+		}
+	}
+	return &QueryData{
+		fieldList:    fields,
+		fieldAliases: fieldAliases,
+		fieldExprs:   fieldExprs,
+		windows:      windows,
+		tableList:    tables,
+		tableAliases: tableAliases,
+		joins:        joins,
+		pred:         predicate,
+		distinct:     distinct,
+		limit:        -1,
+	}, nil
+}
+
+func (parser *Parser) orderByList() ([]*query.OrderField, error) {
+	if err := parser.lexer.eatKeyword("order"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatKeyword("by"); err != nil {
+		return nil, err
+	}
+	list := make([]*query.OrderField, 0)
+	for {
+		field, err := parser.field()
+		if err != nil {
+			return nil, err
+		}
+		descending := false
+		if parser.lexer.matchKeyword("desc") {
+			if err := parser.lexer.eatKeyword("desc"); err != nil {
+				return nil, err
+			}
+			descending = true
+		} else if parser.lexer.matchKeyword("asc") {
+			if err := parser.lexer.eatKeyword("asc"); err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, query.NewOrderField(field, descending))
+		if !parser.lexer.matchDelim(',') {
+			return list, nil
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *Parser) limitClause() (int, int, error) {
+	if err := parser.lexer.eatKeyword("limit"); err != nil {
+		return -1, 0, err
+	}
+	limit, err := parser.lexer.eatIntConstant()
+	if err != nil {
+		return -1, 0, err
+	}
+	offset := 0
+	if parser.lexer.matchKeyword("offset") {
+		if err := parser.lexer.eatKeyword("offset"); err != nil {
+			return -1, 0, err
+		}
+		if offset, err = parser.lexer.eatIntConstant(); err != nil {
+			return -1, 0, err
+		}
+	}
+	if limit < 0 || offset < 0 {
+		return -1, 0, &SyntaxError{"limit and offset can not be negative"}
+	}
+	return limit, offset, nil
+}
+
+// selectList
+// returns the select items, their aliases, their expressions and their
+// window functions. An item without an alias has an empty alias, only
+// subqueries have an expression and only window functions a function
+func (parser *Parser) selectList() ([]string, []string, []*query.Expression, []*query.WindowFunction, error) {
+	fields := make([]string, 0)
+	aliases := make([]string, 0)
+	exprs := make([]*query.Expression, 0)
+	windows := make([]*query.WindowFunction, 0)
+	for {
+		field, alias, expr, window, err := parser.selectItem()
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		fields = append(fields, field)
+		aliases = append(aliases, alias)
+		exprs = append(exprs, expr)
+		windows = append(windows, window)
+		if !parser.lexer.matchDelim(',') {
+			return fields, aliases, exprs, windows, nil
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+}
+
+// selectItem
+// *, table.*, or a field, expression or window function with an optional
+// alias
+func (parser *Parser) selectItem() (string, string, *query.Expression, *query.WindowFunction, error) {
+	if parser.lexer.matchDelim('*') {
+		return "*", "", nil, nil, parser.lexer.eatDelim('*')
+	}
+	var name string
+	var expr *query.Expression
+	var window *query.WindowFunction
+	if parser.lexer.matchDelim('(') || !parser.lexer.matchId() {
+		var err error
+		if expr, err = parser.expression(); err != nil {
+			return "", "", nil, nil, err
+		}
+		name = expr.String()
+	} else {
+		var err error
+		if name, err = parser.lexer.eatId(); err != nil {
+			return "", "", nil, nil, err
+		}
+		if parser.lexer.matchDelim('(') {
+			if window, err = parser.windowFunction(name); err != nil {
+				return "", "", nil, nil, err
+			}
+			name = window.String()
+		} else if parser.lexer.matchDelim('.') {
+			if err := parser.lexer.eatDelim('.'); err != nil {
+				return "", "", nil, nil, err
+			}
+			if parser.lexer.matchDelim('*') {
+				return name + ".*", "", nil, nil, parser.lexer.eatDelim('*')
+			}
+			fldName, err := parser.lexer.eatId()
+			if err != nil {
+				return "", "", nil, nil, err
+			}
+			name += "." + fldName
+		}
+		//a field followed by an operator starts an expression
+		op := parser.lexer.arithmeticOperator()
+		_, sum := sumOperators[op]
+		_, product := productOperators[op]
+		if window == nil && (sum || product) {
+			if expr, err = parser.expressionFrom(query.NewFieldExpression(name)); err != nil {
+				return "", "", nil, nil, err
+			}
+			name = expr.String()
+		}
+	}
+	alias := ""
+	if parser.lexer.matchKeyword("as") {
+		if err := parser.lexer.eatKeyword("as"); err != nil {
+			return "", "", nil, nil, err
+		}
+		var err error
+		if alias, err = parser.lexer.eatId(); err != nil {
+			return "", "", nil, nil, err
+		}
+	}
+	return name, alias, expr, window, nil
+}
+
+var windowFunctions = map[string]query.WindowFunctionType{
+	"row_number": query.RowNumber,
+	"rank":       query.Rank,
+	"dense_rank": query.DenseRank,
+	"lag":        query.Lag,
+	"lead":       query.Lead,
+	"sum":        query.Sum,
+	"avg":        query.Avg,
+}
+
+// windowFunction
+// name(arguments) over ([partition by fields] [order by fields] [frame]).
+// Ranking functions have no arguments, sum and avg a field and lag and
+// lead a field, an optional offset and an optional default
+func (parser *Parser) windowFunction(name string) (*query.WindowFunction, error) {
+	fn, ok := windowFunctions[name]
+	if !ok {
+		return nil, &SyntaxError{fmt.Sprintf("unknown function %s", name)}
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	fldName := ""
+	offset := 1
+	var defaultVal any
+	if !fn.IsRanking() {
+		var err error
+		if fldName, err = parser.field(); err != nil {
+			return nil, err
+		}
+		if (fn == query.Lag || fn == query.Lead) && parser.lexer.matchDelim(',') {
+			if err := parser.lexer.eatDelim(','); err != nil {
+				return nil, err
+			}
+			if offset, err = parser.lexer.eatIntConstant(); err != nil {
+				return nil, err
+			}
+			if offset < 0 {
+				return nil, &SyntaxError{fmt.Sprintf("%s offset can not be negative", name)}
+			}
+			if parser.lexer.matchDelim(',') {
+				if err := parser.lexer.eatDelim(','); err != nil {
+					return nil, err
+				}
+				if defaultVal, err = parser.constant(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatKeyword("over"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	partitionBy := make([]string, 0)
+	if parser.lexer.matchKeyword("partition") {
+		if err := parser.lexer.eatKeyword("partition"); err != nil {
+			return nil, err
+		}
+		if err := parser.lexer.eatKeyword("by"); err != nil {
+			return nil, err
+		}
+		for {
+			field, err := parser.field()
+			if err != nil {
+				return nil, err
+			}
+			partitionBy = append(partitionBy, field)
+			if !parser.lexer.matchDelim(',') {
+				break
+			}
+			if err := parser.lexer.eatDelim(','); err != nil {
+				return nil, err
+			}
+		}
+	}
+	orderBy := make([]*query.OrderField, 0)
+	if parser.lexer.matchKeyword("order") {
+		var err error
+		if orderBy, err = parser.orderByList(); err != nil {
+			return nil, err
+		}
+	}
+	var frame *query.Frame
+	if parser.lexer.matchKeyword("rows") {
+		if fn != query.Sum && fn != query.Avg {
+			return nil, &SyntaxError{fmt.Sprintf("%s can not have a frame", name)}
+		}
+		var err error
+		if frame, err = parser.frame(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	return query.NewWindowFunction(fn, fldName, offset, defaultVal, partitionBy, orderBy, frame), nil
+}
+
+// frame
+// rows between start and end, or rows start which ends at the current row
+func (parser *Parser) frame() (*query.Frame, error) {
+	if err := parser.lexer.eatKeyword("rows"); err != nil {
+		return nil, err
+	}
+	between := parser.lexer.matchKeyword("between")
+	if between {
+		if err := parser.lexer.eatKeyword("between"); err != nil {
+			return nil, err
+		}
+	}
+	start, err := parser.frameBound()
+	if err != nil {
+		return nil, err
+	}
+	end := 0
+	if between {
+		if err := parser.lexer.eatKeyword("and"); err != nil {
+			return nil, err
+		}
+		if end, err = parser.frameBound(); err != nil {
+			return nil, err
+		}
+	}
+	if start == query.UnboundedFollowing || end == query.UnboundedPreceding || start > end {
+		return nil, &SyntaxError{"a frame can not start after it ends"}
+	}
+	return query.NewFrame(start, end), nil
+}
+
+// frameBound
+// unbounded preceding, unbounded following, current row, n preceding or
+// n following, as an offset from the current row
+func (parser *Parser) frameBound() (int, error) {
+	if parser.lexer.matchKeyword("current") {
+		if err := parser.lexer.eatKeyword("current"); err != nil {
+			return 0, err
+		}
+		return 0, parser.lexer.eatKeyword("row")
+	}
+	unbounded := parser.lexer.matchKeyword("unbounded")
+	offset := 0
+	if unbounded {
+		if err := parser.lexer.eatKeyword("unbounded"); err != nil {
+			return 0, err
+		}
+	} else {
+		var err error
+		if offset, err = parser.lexer.eatIntConstant(); err != nil {
+			return 0, err
+		}
+		if offset < 0 {
+			return 0, &SyntaxError{"a frame offset can not be negative"}
+		}
+	}
+	if parser.lexer.matchKeyword("preceding") {
+		if err := parser.lexer.eatKeyword("preceding"); err != nil {
+			return 0, err
+		}
+		if unbounded {
+			return query.UnboundedPreceding, nil
+		}
+		return -offset, nil
+	}
+	if err := parser.lexer.eatKeyword("following"); err != nil {
+		return 0, err
+	}
+	if unbounded {
+		return query.UnboundedFollowing, nil
+	}
+	return offset, nil
+}
+
+// tableList
+// returns the tables, their aliases and how each of them is joined to the
+// tables before it. A table without an alias has an empty alias and a
+// table following a comma has no join
+func (parser *Parser) tableList() ([]string, []string, []*JoinData, error) {
+	tables := make([]string, 0)
+	aliases := make([]string, 0)
+	joins := make([]*JoinData, 0)
+	var join *JoinData
+	for {
+		table, alias, err := parser.tableRef()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if join != nil {
+			if err := parser.lexer.eatKeyword("on"); err != nil {
+				return nil, nil, nil, err
+			}
+			on, err := parser.predicate()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			join = NewJoinData(join.JoinType(), on)
+		}
+		tables = append(tables, table)
+		aliases = append(aliases, alias)
+		joins = append(joins, join)
+		if parser.lexer.matchDelim(',') {
+			if err := parser.lexer.eatDelim(','); err != nil {
+				return nil, nil, nil, err
+			}
+			join = nil
+			continue
+		}
+		joinType, ok, err := parser.joinType()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !ok {
+			return tables, aliases, joins, nil
 		}
+		join = NewJoinData(joinType, nil)
 	}
-	return &QueryData{
-		fieldList: fields,
-		tableList: tables,
-		pred:      predicate,
-	}, nil
 }
 
-func (parser *Parser) selectList() ([]string, error) {
-	field, err := parser.field()
+// tableRef
+// a table with an optional alias
+func (parser *Parser) tableRef() (string, string, error) {
+	table, err := parser.lexer.eatId()
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
-	list := []string{field}
-	for parser.lexer.matchDelim(',') {
-		if err := parser.lexer.eatDelim(','); err != nil {
-			return nil, err
+	alias := ""
+	if parser.lexer.matchKeyword("as") {
+		if err := parser.lexer.eatKeyword("as"); err != nil {
+			return "", "", err
 		}
-		field, err = parser.field()
-		list = append(list, field)
 	}
-	return list, nil
+	if parser.lexer.matchId() {
+		if alias, err = parser.lexer.eatId(); err != nil {
+			return "", "", err
+		}
+	}
+	return table, alias, nil
 }
 
-func (parser *Parser) tableList() ([]string, error) {
-	table, err := parser.field()
-	if err != nil {
-		return nil, err
-	}
-	list := []string{table}
-	for parser.lexer.matchDelim(',') {
-		if err = parser.lexer.eatDelim(','); err != nil {
-			return nil, err
+// joinType
+// [inner | left [outer] | right [outer] | full [outer]] join, returns false
+// when the next token does not start a join
+func (parser *Parser) joinType() (query.JoinType, bool, error) {
+	outerJoins := map[string]query.JoinType{
+		"left":  query.LeftJoin,
+		"right": query.RightJoin,
+		"full":  query.FullJoin,
+	}
+	joinType := query.InnerJoin
+	if parser.lexer.matchKeyword("inner") {
+		if err := parser.lexer.eatKeyword("inner"); err != nil {
+			return 0, false, err
 		}
-		table, err = parser.field()
-		if err != nil {
-			return nil, err
+	} else {
+		for keyword, outerJoin := range outerJoins {
+			if !parser.lexer.matchKeyword(keyword) {
+				continue
+			}
+			if err := parser.lexer.eatKeyword(keyword); err != nil {
+				return 0, false, err
+			}
+			if parser.lexer.matchKeyword("outer") {
+				if err := parser.lexer.eatKeyword("outer"); err != nil {
+					return 0, false, err
+				}
+			}
+			joinType = outerJoin
+			break
+		}
+		if joinType == query.InnerJoin && !parser.lexer.matchKeyword("join") {
+			return 0, false, nil
 		}
-		list = append(list, table)
 	}
-	return list, nil
+	if err := parser.lexer.eatKeyword("join"); err != nil {
+		return 0, false, err
+	}
+	return joinType, true, nil
 }
 
-func (parser *Parser) updateCmd() (any, error) {
+func (parser *Parser) UpdateCmd() (any, error) {
 	switch {
 	case parser.lexer.matchKeyword("insert"):
 		return parser.insert()
@@ -148,11 +944,25 @@ func (parser *Parser) updateCmd() (any, error) {
 		return parser.delete()
 	case parser.lexer.matchKeyword("update"):
 		return parser.update()
+	case parser.lexer.matchKeyword("drop"):
+		return parser.drop()
+	case parser.lexer.matchKeyword("alter"):
+		return parser.alterTable()
+	case parser.lexer.matchKeyword("vacuum"):
+		return parser.vacuum()
+	case parser.lexer.matchKeyword("scrub"):
+		if err := parser.lexer.eatKeyword("scrub"); err != nil {
+			return nil, err
+		}
+		return NewScrubData(), nil
 	default:
 		return parser.create()
 	}
 }
 
+// insert
+// insert into table [(fields)] values (constants), ..., or
+// insert into table [(fields)] query
 func (parser *Parser) insert() (*InsertData, error) {
 	if err := parser.lexer.eatKeyword("insert"); err != nil {
 		return nil, err
@@ -164,36 +974,53 @@ func (parser *Parser) insert() (*InsertData, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := parser.lexer.eatDelim('('); err != nil {
-		return nil, err
-	}
-	fields, err := parser.fieldList()
-	if err != nil {
-		return nil, err
+	fields := make([]string, 0)
+	if parser.lexer.matchDelim('(') {
+		if err := parser.lexer.eatDelim('('); err != nil {
+			return nil, err
+		}
+		if fields, err = parser.fieldList(); err != nil {
+			return nil, err
+		}
+		if err := parser.lexer.eatDelim(')'); err != nil {
+			return nil, err
+		}
 	}
-	if err := parser.lexer.eatDelim(')'); err != nil {
-		return nil, err
+	if parser.lexer.matchKeyword("select") || parser.lexer.matchKeyword("with") {
+		data, err := parser.Query()
+		if err != nil {
+			return nil, err
+		}
+		return NewInsertSelectData(tableName, fields, data), nil
 	}
 	if err := parser.lexer.eatKeyword("values"); err != nil {
 		return nil, err
 	}
-	if err := parser.lexer.eatDelim('('); err != nil {
-		return nil, err
-	}
-	values, err := parser.constList()
-	if err != nil {
-		return nil, err
+	rows := make([][]any, 0)
+	for {
+		if err := parser.lexer.eatDelim('('); err != nil {
+			return nil, err
+		}
+		values, err := parser.constList()
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.lexer.eatDelim(')'); err != nil {
+			return nil, err
+		}
+		rows = append(rows, values)
+		if !parser.lexer.matchDelim(',') {
+			return NewInsertData(tableName, fields, rows), nil
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, err
+		}
 	}
-	return &InsertData{
-		fieldList: fields,
-		tblName:   tableName,
-		values:    values,
-	}, nil
 }
 
 func (parser *Parser) fieldList() ([]string, error) {
 	list := []string{}
-	field, err := parser.field()
+	field, err := parser.lexer.eatId()
 	if err != nil {
 		return nil, err
 	}
@@ -255,6 +1082,8 @@ func (parser *Parser) delete() (*DeleteData, error) {
 	}, nil
 }
 
+// update
+// update table set field = expression, ... [where predicate]
 func (parser *Parser) update() (*ModifyData, error) {
 	if err := parser.lexer.eatKeyword("update"); err != nil {
 		return nil, err
@@ -266,30 +1095,45 @@ func (parser *Parser) update() (*ModifyData, error) {
 	if err := parser.lexer.eatKeyword("set"); err != nil {
 		return nil, err
 	}
-	fldName, err := parser.lexer.eatId()
-	if err != nil {
-		return nil, err
-	}
-	if err := parser.lexer.eatDelim('='); err != nil {
-		return nil, err
-	}
-	expression, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
-	if err := parser.lexer.eatKeyword("where"); err != nil {
-		return nil, err
+	fields := make([]string, 0)
+	values := make([]*query.Expression, 0)
+	for {
+		fldName, err := parser.lexer.eatId()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.lexer.matchOperator("=") {
+			return nil, &SyntaxError{fmt.Sprintf("expected = after %s", fldName)}
+		}
+		if _, err := parser.lexer.eatOperator(); err != nil {
+			return nil, err
+		}
+		value, err := parser.expression()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, fldName)
+		values = append(values, value)
+		if !parser.lexer.matchDelim(',') {
+			if !parser.lexer.matchKeyword("where") && parser.End() != nil {
+				return nil, &SyntaxError{fmt.Sprintf("expected , or where after the value of %s", fldName)}
+			}
+			break
+		}
+		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, err
+		}
 	}
-	pred, err := parser.predicate()
-	if err != nil {
-		return nil, err
+	pred := query.NewPredicate()
+	if parser.lexer.matchKeyword("where") {
+		if err := parser.lexer.eatKeyword("where"); err != nil {
+			return nil, err
+		}
+		if pred, err = parser.predicate(); err != nil {
+			return nil, err
+		}
 	}
-	return &ModifyData{
-		tableName: tableName,
-		predicate: pred,
-		value:     expression,
-		field:     fldName,
-	}, nil
+	return NewModifyData(tableName, fields, values, pred), nil
 }
 
 func (parser *Parser) create() (any, error) {
@@ -307,26 +1151,401 @@ func (parser *Parser) create() (any, error) {
 	return nil, fmt.Errorf("expected table,view or index after create")
 }
 
-func (parser *Parser) fieldDefs() (*record.Schema, error) {
-	schema, err := parser.fieldDef()
+// drop
+// drop table name, drop view name or drop index name
+func (parser *Parser) drop() (any, error) {
+	if err := parser.lexer.eatKeyword("drop"); err != nil {
+		return nil, err
+	}
+	var kind string
+	for _, keyword := range []string{"table", "view", "index"} {
+		if parser.lexer.matchKeyword(keyword) {
+			kind = keyword
+		}
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("expected table,view or index after drop")
+	}
+	if err := parser.lexer.eatKeyword(kind); err != nil {
+		return nil, err
+	}
+	name, err := parser.lexer.eatId()
 	if err != nil {
 		return nil, err
 	}
-	if parser.lexer.matchDelim(',') {
+	switch kind {
+	case "table":
+		return NewDropTableData(name), nil
+	case "view":
+		return NewDropViewData(name), nil
+	}
+	return NewDropIndexData(name), nil
+}
+
+// vacuum
+// vacuum [table]
+func (parser *Parser) vacuum() (*VacuumData, error) {
+	if err := parser.lexer.eatKeyword("vacuum"); err != nil {
+		return nil, err
+	}
+	if !parser.lexer.matchId() {
+		return NewVacuumData(""), nil
+	}
+	tableName, err := parser.lexer.eatId()
+	if err != nil {
+		return nil, err
+	}
+	return NewVacuumData(tableName), nil
+}
+
+// alterTable
+// alter table name add [column] field type [default constant],
+// alter table name drop [column] field,
+// alter table name rename [column] field to newName or
+// alter table name rename to newName
+func (parser *Parser) alterTable() (*AlterTableData, error) {
+	if err := parser.lexer.eatKeyword("alter"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatKeyword("table"); err != nil {
+		return nil, err
+	}
+	tableName, err := parser.lexer.eatId()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case parser.lexer.matchKeyword("add"):
+		if err := parser.eatKeywords("add", "column"); err != nil {
+			return nil, err
+		}
+		schema, err := parser.fieldDef()
+		if err != nil {
+			return nil, err
+		}
+		var defaultVal any
+		if parser.lexer.matchKeyword("default") {
+			if err := parser.lexer.eatKeyword("default"); err != nil {
+				return nil, err
+			}
+			if defaultVal, err = parser.constant(); err != nil {
+				return nil, err
+			}
+		}
+		return NewAddColumnData(tableName, schema, defaultVal), nil
+	case parser.lexer.matchKeyword("drop"):
+		if err := parser.eatKeywords("drop", "column"); err != nil {
+			return nil, err
+		}
+		fieldName, err := parser.lexer.eatId()
+		if err != nil {
+			return nil, err
+		}
+		return NewDropColumnData(tableName, fieldName), nil
+	case parser.lexer.matchKeyword("rename"):
+		if err := parser.eatKeywords("rename", "column"); err != nil {
+			return nil, err
+		}
+		fieldName := ""
+		if !parser.lexer.matchKeyword("to") {
+			if fieldName, err = parser.lexer.eatId(); err != nil {
+				return nil, err
+			}
+		}
+		if err := parser.lexer.eatKeyword("to"); err != nil {
+			return nil, err
+		}
+		newName, err := parser.lexer.eatId()
+		if err != nil {
+			return nil, err
+		}
+		if fieldName == "" {
+			return NewRenameTableData(tableName, newName), nil
+		}
+		return NewRenameColumnData(tableName, fieldName, newName), nil
+	}
+	return nil, fmt.Errorf("expected add,drop or rename after alter table %s", tableName)
+}
+
+// eatKeywords
+// eats keyword followed by the optional keyword when it is there
+func (parser *Parser) eatKeywords(keyword string, optional string) error {
+	if err := parser.lexer.eatKeyword(keyword); err != nil {
+		return err
+	}
+	if parser.lexer.matchKeyword(optional) {
+		return parser.lexer.eatKeyword(optional)
+	}
+	return nil
+}
+
+// tableElements
+// the field definitions and the table constraints of create table, in any
+// order. The constraints of the fields come first in the result
+func (parser *Parser) tableElements() (*record.Schema, []*record.Constraint, error) {
+	schema := record.NewSchema()
+	var constraints, tableConstraints []*record.Constraint
+	for {
+		if parser.matchConstraint() {
+			constraint, err := parser.tableConstraint()
+			if err != nil {
+				return nil, nil, err
+			}
+			tableConstraints = append(tableConstraints, constraint)
+		} else {
+			fldSchema, err := parser.fieldDef()
+			if err != nil {
+				return nil, nil, err
+			}
+			schema.AddAll(fldSchema)
+			fldConstraints, err := parser.columnConstraints(fldSchema.Fields()[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			constraints = append(constraints, fldConstraints...)
+		}
+		if !parser.lexer.matchDelim(',') {
+			return schema, append(constraints, tableConstraints...), nil
+		}
 		if err := parser.lexer.eatDelim(','); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// matchConstraint
+// true when a table constraint starts at the current token
+func (parser *Parser) matchConstraint() bool {
+	return parser.lexer.matchKeyword("constraint") || parser.lexer.matchKeyword("primary") ||
+		parser.lexer.matchKeyword("unique") || parser.lexer.matchKeyword("foreign") ||
+		parser.lexer.matchKeyword("check")
+}
+
+// constraintName
+// the name after the optional constraint keyword, empty without it
+func (parser *Parser) constraintName() (string, error) {
+	if !parser.lexer.matchKeyword("constraint") {
+		return "", nil
+	}
+	if err := parser.lexer.eatKeyword("constraint"); err != nil {
+		return "", err
+	}
+	return parser.lexer.eatId()
+}
+
+// constraintType
+// primary key, unique or foreign key
+func (parser *Parser) constraintType() (record.ConstraintType, error) {
+	if parser.lexer.matchKeyword("unique") {
+		return record.Unique, parser.lexer.eatKeyword("unique")
+	}
+	kind := record.PrimaryKey
+	if parser.lexer.matchKeyword("foreign") {
+		kind = record.ForeignKey
+		if err := parser.lexer.eatKeyword("foreign"); err != nil {
+			return 0, err
+		}
+	} else if err := parser.lexer.eatKeyword("primary"); err != nil {
+		return 0, err
+	}
+	return kind, parser.lexer.eatKeyword("key")
+}
+
+// tableConstraint
+// [constraint name] primary key (fields), [constraint name] unique (fields),
+// [constraint name] foreign key (fields) references ... or
+// [constraint name] check (predicate)
+func (parser *Parser) tableConstraint() (*record.Constraint, error) {
+	name, err := parser.constraintName()
+	if err != nil {
+		return nil, err
+	}
+	if parser.lexer.matchKeyword("check") {
+		return parser.check(name)
+	}
+	kind, err := parser.constraintType()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	fields, err := parser.fieldList()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	if kind == record.ForeignKey {
+		return parser.references(name, fields)
+	}
+	return record.NewConstraint(name, kind, fields), nil
+}
+
+// references
+// references table [(fields)] [on delete cascade|restrict|set null], a
+// foreign key without fields refers to the primary key of the table and
+// restricts deletes unless it declares another action
+func (parser *Parser) references(name string, fields []string) (*record.Constraint, error) {
+	if err := parser.lexer.eatKeyword("references"); err != nil {
+		return nil, err
+	}
+	refTable, err := parser.lexer.eatId()
+	if err != nil {
+		return nil, err
+	}
+	var refFields []string
+	if parser.lexer.matchDelim('(') {
+		if err := parser.lexer.eatDelim('('); err != nil {
+			return nil, err
+		}
+		if refFields, err = parser.fieldList(); err != nil {
+			return nil, err
+		}
+		if err := parser.lexer.eatDelim(')'); err != nil {
 			return nil, err
 		}
-		schema2, err := parser.fieldDefs()
+	}
+	onDelete := record.Restrict
+	if parser.lexer.matchKeyword("on") {
+		if err := parser.lexer.eatKeyword("on"); err != nil {
+			return nil, err
+		}
+		if err := parser.lexer.eatKeyword("delete"); err != nil {
+			return nil, err
+		}
+		switch {
+		case parser.lexer.matchKeyword("cascade"):
+			onDelete = record.Cascade
+			err = parser.lexer.eatKeyword("cascade")
+		case parser.lexer.matchKeyword("set"):
+			onDelete = record.SetNull
+			if err = parser.lexer.eatKeyword("set"); err == nil {
+				err = parser.lexer.eatKeyword("null")
+			}
+		default:
+			err = parser.lexer.eatKeyword("restrict")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record.NewForeignKey(name, fields, refTable, refFields, onDelete), nil
+}
+
+// check
+// check (predicate), the fields of the predicate are only known once it is
+// resolved against its table
+func (parser *Parser) check(name string) (*record.Constraint, error) {
+	if err := parser.lexer.eatKeyword("check"); err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim('('); err != nil {
+		return nil, err
+	}
+	pred, err := parser.predicate()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.lexer.eatDelim(')'); err != nil {
+		return nil, err
+	}
+	return record.NewCheck(name, nil, pred.String()), nil
+}
+
+// CheckCondition
+// the predicate of the definition of a check, which is the whole input
+func (parser *Parser) CheckCondition() (*query.Predicate, error) {
+	pred, err := parser.predicate()
+	if err != nil {
+		return nil, err
+	}
+	return pred, parser.End()
+}
+
+// DefaultValue
+// the constant of the definition of a default, which is the whole input
+func (parser *Parser) DefaultValue() (any, error) {
+	val, err := parser.constant()
+	if err != nil {
+		return nil, err
+	}
+	return val, parser.End()
+}
+
+// End
+// fails unless the whole input has been read, for a statement followed by
+// anything the grammar does not know
+func (parser *Parser) End() error {
+	if parser.lexer.currentToken.tokenType != TTEof {
+		return &SyntaxError{fmt.Sprintf("unexpected input at %d", parser.lexer.position)}
+	}
+	return nil
+}
+
+// columnConstraints
+// the constraints following the type of a field, each is
+// [constraint name] not null, null, primary key, unique, references ... or
+// check (predicate), or default constant
+func (parser *Parser) columnConstraints(fldName string) ([]*record.Constraint, error) {
+	var constraints []*record.Constraint
+	for {
+		name, err := parser.constraintName()
 		if err != nil {
 			return nil, err
 		}
-		schema.AddAll(schema2)
+		switch {
+		case parser.lexer.matchKeyword("not"):
+			if err := parser.lexer.eatKeyword("not"); err != nil {
+				return nil, err
+			}
+			if err := parser.lexer.eatKeyword("null"); err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, record.NewConstraint(name, record.NotNull, []string{fldName}))
+		case parser.lexer.matchKeyword("null") && name == "":
+			if err := parser.lexer.eatKeyword("null"); err != nil {
+				return nil, err
+			}
+		case parser.lexer.matchKeyword("primary") || parser.lexer.matchKeyword("unique"):
+			kind, err := parser.constraintType()
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, record.NewConstraint(name, kind, []string{fldName}))
+		case parser.lexer.matchKeyword("references"):
+			constraint, err := parser.references(name, []string{fldName})
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, constraint)
+		case parser.lexer.matchKeyword("check"):
+			constraint, err := parser.check(name)
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, constraint)
+		case parser.lexer.matchKeyword("default") && name == "":
+			if err := parser.lexer.eatKeyword("default"); err != nil {
+				return nil, err
+			}
+			val, err := parser.constant()
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, record.NewDefault("", fldName, query.NewConstantExpression(val).String()))
+		default:
+			if name != "" {
+				return nil, &SyntaxError{fmt.Sprintf("expected a constraint after constraint %s", name)}
+			}
+			return constraints, nil
+		}
 	}
-	return schema, nil
 }
 
 func (parser *Parser) fieldDef() (*record.Schema, error) {
-	fldName, err := parser.field()
+	fldName, err := parser.lexer.eatId()
 	if err != nil {
 		return nil, err
 	}
@@ -335,13 +1554,30 @@ func (parser *Parser) fieldDef() (*record.Schema, error) {
 
 func (parser *Parser) fieldType(fldName string) (*record.Schema, error) {
 	schema := record.NewSchema()
-	if parser.lexer.matchKeyword("int") {
-		if err := parser.lexer.eatKeyword("int"); err != nil {
-			return nil, err
+	fixed := map[string]func(string){
+		"int":       schema.AddIntField,
+		"bigint":    schema.AddBigIntField,
+		"boolean":   schema.AddBoolField,
+		"double":    schema.AddDoubleField,
+		"date":      schema.AddDateField,
+		"timestamp": schema.AddTimestampField,
+	}
+	for keyword, add := range fixed {
+		if parser.lexer.matchKeyword(keyword) {
+			add(fldName)
+			return schema, parser.lexer.eatKeyword(keyword)
+		}
+	}
+	//varchar(n) and blob(n) take their maximum length
+	sized := map[string]func(string, int){
+		"varchar": schema.AddStringField,
+		"blob":    schema.AddBlobField,
+	}
+	for keyword, add := range sized {
+		if !parser.lexer.matchKeyword(keyword) {
+			continue
 		}
-		schema.AddIntField(fldName)
-	} else if parser.lexer.matchKeyword("varchar") {
-		if err := parser.lexer.eatKeyword("varchar"); err != nil {
+		if err := parser.lexer.eatKeyword(keyword); err != nil {
 			return nil, err
 		}
 		if err := parser.lexer.eatDelim('('); err != nil {
@@ -351,11 +1587,13 @@ func (parser *Parser) fieldType(fldName string) (*record.Schema, error) {
 		if err != nil {
 			return nil, err
 		}
-		schema.AddStringField(fldName, size)
-	} else {
-		return nil, fmt.Errorf("expected int or varchar after %s", fldName)
+		if err := parser.lexer.eatDelim(')'); err != nil {
+			return nil, err
+		}
+		add(fldName, size)
+		return schema, nil
 	}
-	return schema, nil
+	return nil, fmt.Errorf("expected a type after %s", fldName)
 }
 
 func (parser *Parser) createTable() (*CreateTableData, error) {
@@ -369,14 +1607,14 @@ func (parser *Parser) createTable() (*CreateTableData, error) {
 	if err := parser.lexer.eatDelim('('); err != nil {
 		return nil, err
 	}
-	schema, err := parser.fieldDefs()
+	schema, constraints, err := parser.tableElements()
 	if err != nil {
 		return nil, err
 	}
 	if err := parser.lexer.eatDelim(')'); err != nil {
 		return nil, err
 	}
-	return &CreateTableData{schema: schema, tableName: tableName}, nil
+	return NewCreateTableData(tableName, schema, constraints), nil
 
 }
 
@@ -391,7 +1629,7 @@ func (parser *Parser) createView() (*CreateViewData, error) {
 	if err := parser.lexer.eatKeyword("as"); err != nil {
 		return nil, err
 	}
-	queryData, err := parser.query()
+	queryData, err := parser.Query()
 	if err != nil {
 		return nil, err
 	}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	assertPkg "github.com/stretchr/testify/assert"
 	"jadb/file"
+	"jadb/query"
 	"jadb/record"
 	"testing"
+	"time"
 )
 
 func TestCreateTable(t *testing.T) {
@@ -13,7 +15,7 @@ func TestCreateTable(t *testing.T) {
 	sql := "CREATE TABLE ANKIT(id int,name varchar(20))"
 	parser, err := NewParser(sql)
 	assert.NoError(err)
-	data, err := parser.updateCmd()
+	data, err := parser.UpdateCmd()
 	assert.NoError(err)
 	createTableData := data.(*CreateTableData)
 	assert.Equal("ankit", createTableData.tableName)
@@ -28,7 +30,7 @@ func TestCreateView(t *testing.T) {
 	sql := "CREATE VIEW ankit AS SELECT col,col_ FROM test"
 	parser, err := NewParser(sql)
 	assert.NoError(err)
-	data, err := parser.updateCmd()
+	data, err := parser.UpdateCmd()
 	assert.NoError(err)
 	createIndexData := data.(*CreateViewData)
 	assert.Equal("ankit", createIndexData.viewName)
@@ -41,7 +43,7 @@ func TestCreateIndex(t *testing.T) {
 	sql := "CREATE Index hehe on test(col)"
 	parser, err := NewParser(sql)
 	assert.NoError(err)
-	data, err := parser.updateCmd()
+	data, err := parser.UpdateCmd()
 	assert.NoError(err)
 	createIndexData := data.(*CreateIndexData)
 	assert.Equal("hehe", createIndexData.indexName)
@@ -58,8 +60,614 @@ func TestDelete(t *testing.T) {
 	parser, err := NewParser(sql)
 	//pred := query.NewPredicateFromTerm(query.NewTerm(query.NewFieldExpression(testField),query.NewConstantExpression(testValue),query.Equal))
 	assert.NoError(err)
-	data, err := parser.updateCmd()
+	data, err := parser.UpdateCmd()
 	assert.NoError(err)
 	deleteData := data.(*DeleteData)
 	assert.Equal(deleteData.tableName, testTableName)
 }
+
+func TestQueryDistinct(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("SELECT DISTINCT dept, name FROM emp WHERE name = 'Ankit' AND id = 2")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.True(data.IsDistinct())
+	assert.Equal([]string{"dept", "name"}, data.Fields())
+	assert.Equal([]string{"emp"}, data.Tables())
+	assert.Equal("name='Ankit' and id=2", data.Predicate().String())
+	assert.Equal("select distinct dept, name from emp where name='Ankit' and id=2", data.String())
+
+	parser, err = NewParser("select dept from emp")
+	assert.NoError(err)
+	data, err = parser.Query()
+	assert.NoError(err)
+	assert.False(data.IsDistinct())
+}
+
+func TestQueryOrderByLimit(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id, name from emp order by name desc, id limit 10 offset 20")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal(2, len(data.OrderBy()))
+	assert.Equal("name", data.OrderBy()[0].FieldName())
+	assert.True(data.OrderBy()[0].IsDescending())
+	assert.Equal("id", data.OrderBy()[1].FieldName())
+	assert.False(data.OrderBy()[1].IsDescending())
+	assert.Equal(10, data.Limit())
+	assert.Equal(20, data.Offset())
+	assert.Equal("select id, name from emp order by name desc, id limit 10 offset 20", data.String())
+
+	parser, err = NewParser("select id from emp")
+	assert.NoError(err)
+	data, err = parser.Query()
+	assert.NoError(err)
+	assert.Equal(-1, data.Limit())
+	assert.Equal(0, data.Offset())
+
+	parser, err = NewParser("select id from emp limit -1")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQueryQualifiedNamesAndAliases(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select e.*, d.name as dept_name, * from emp e, dept as d where e.dept = d.id")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal([]string{"e.*", "d.name", "*"}, data.Fields())
+	assert.Equal([]string{"", "dept_name", ""}, data.FieldAliases())
+	assert.Equal([]string{"emp", "dept"}, data.Tables())
+	assert.Equal([]string{"e", "d"}, data.TableAliases())
+	assert.Equal("e.dept=d.id", data.Predicate().String())
+	assert.Equal("select e.*, d.name as dept_name, * from emp e, dept d where e.dept=d.id", data.String())
+
+	parser, err = NewParser("select id from emp where")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQueryJoins(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select * from emp e join dept d on e.dept = d.id left outer join site s on d.site = s.id and s.open = 1, bonus")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal([]string{"emp", "dept", "site", "bonus"}, data.Tables())
+	assert.Equal([]string{"e", "d", "s", ""}, data.TableAliases())
+	joins := data.Joins()
+	assert.Nil(joins[0])
+	assert.Equal(query.InnerJoin, joins[1].JoinType())
+	assert.Equal("e.dept=d.id", joins[1].On().String())
+	assert.Equal(query.LeftJoin, joins[2].JoinType())
+	assert.Equal("d.site=s.id and s.open=1", joins[2].On().String())
+	assert.Nil(joins[3])
+	assert.Equal("select * from emp e join dept d on e.dept=d.id left join site s on d.site=s.id and s.open=1, bonus", data.String())
+
+	for sql, joinType := range map[string]query.JoinType{
+		"select * from a inner join b on a.x = b.x":      query.InnerJoin,
+		"select * from a right join b on a.x = b.x":      query.RightJoin,
+		"select * from a full outer join b on a.x = b.x": query.FullJoin,
+	} {
+		parser, err = NewParser(sql)
+		assert.NoError(err)
+		data, err = parser.Query()
+		assert.NoError(err)
+		assert.Equal(joinType, data.Joins()[1].JoinType())
+	}
+
+	parser, err = NewParser("select * from a left join b")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQueryNulls(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id from emp where name is null and dept is not null and age >= 18 and id <> 3")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal("name is null and dept is not null and age>=18 and id!=3", data.Predicate().String())
+	assert.Equal("select id from emp where name is null and dept is not null and age>=18 and id!=3", data.String())
+
+	parser, err = NewParser("insert into emp (id, name) values (1, null)")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	assert.Equal([][]any{{1, nil}}, cmd.(*InsertData).Values())
+
+	parser, err = NewParser("select id from emp where name is 3")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQuerySubqueries(t *testing.T) {
+	assert := assertPkg.New(t)
+	sql := "select name, (select title from dept d where d.id = e.dept) as title from emp e " +
+		"where dept in (select id from dept where title = 'tech') and id not in (1, 2, 3) " +
+		"and not exists (select id from bonus b where b.emp = e.id) and dept = (select id from dept where title = 'ops')"
+	parser, err := NewParser(sql)
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal([]string{"name", "(select title from dept d where d.id=e.dept)"}, data.Fields())
+	assert.Equal([]string{"", "title"}, data.FieldAliases())
+	assert.Nil(data.FieldExpressions()[0])
+	assert.NotNil(data.FieldExpressions()[1])
+	assert.Equal(3, len(data.Predicate().Subqueries()))
+	assert.Equal("select name, (select title from dept d where d.id=e.dept) as title from emp e "+
+		"where dept in (select id from dept where title='tech') and id not in (1, 2, 3) "+
+		"and not exists (select id from bonus b where b.emp=e.id) and dept=(select id from dept where title='ops')",
+		data.String())
+
+	//the string form parses back to the same query
+	parser, err = NewParser(data.String())
+	assert.NoError(err)
+	reparsed, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal(data.String(), reparsed.String())
+
+	for _, sql := range []string{
+		"select id from emp where dept in ()",
+		"select id from emp where dept in (select id from dept",
+		"select id from emp where exists dept",
+	} {
+		parser, err = NewParser(sql)
+		assert.NoError(err)
+		_, err = parser.Query()
+		assert.Error(err, sql)
+	}
+}
+
+func TestQuerySetOperations(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id from emp union all select id from manager intersect select id from staff " +
+		"except select id from intern where id = 3 order by id desc limit 2")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal([]string{"emp"}, data.Tables())
+	setOps := data.SetOperations()
+	assert.Equal(3, len(setOps))
+	assert.Equal(query.Union, setOps[0].Operator())
+	assert.True(setOps[0].IsAll())
+	assert.Equal(query.Intersect, setOps[1].Operator())
+	assert.False(setOps[1].IsAll())
+	assert.Equal(query.Except, setOps[2].Operator())
+	assert.Equal("id=3", setOps[2].Query().Predicate().String())
+	assert.Equal(-1, setOps[2].Query().Limit())
+	assert.Equal(2, data.Limit())
+	assert.Equal("select id from emp union all select id from manager intersect select id from staff "+
+		"except select id from intern where id=3 order by id desc limit 2", data.String())
+
+	parser, err = NewParser("select id from emp union")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQueryCommonTables(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("with recursive chain (id, boss) as (select id, manager from staff where id = 1 " +
+		"union select s.id, s.manager from staff s, chain c where s.manager = c.id), top as (select id from chain) " +
+		"select id from top")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.True(data.IsRecursive())
+	assert.Equal([]string{"top"}, data.Tables())
+	with := data.With()
+	assert.Equal(2, len(with))
+	assert.Equal("chain", with[0].Name())
+	assert.Equal([]string{"id", "boss"}, with[0].Columns())
+	assert.Equal(1, len(with[0].Query().SetOperations()))
+	assert.Equal("top", with[1].Name())
+	assert.Empty(with[1].Columns())
+	assert.Equal("with recursive chain (id, boss) as (select id, manager from staff where id=1 "+
+		"union select s.id, s.manager from staff s, chain c where s.manager=c.id), top as (select id from chain) "+
+		"select id from top", data.String())
+
+	parser, err = NewParser("with t as select id from emp select id from t")
+	assert.NoError(err)
+	_, err = parser.Query()
+	assert.Error(err)
+}
+
+func TestQueryWindowFunctions(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id, rank() over (partition by dept order by salary desc) as r, " +
+		"lag(salary, 2, 0) over (order by id), sum(e.salary) over (partition by dept rows between unbounded preceding and 1 following) " +
+		"from emp e")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	windows := data.WindowFunctions()
+	assert.Equal(4, len(windows))
+	assert.Nil(windows[0])
+	assert.Equal(query.Rank, windows[1].Function())
+	assert.Equal([]string{"dept"}, windows[1].PartitionBy())
+	assert.Equal("partition by dept order by salary desc", windows[1].Window())
+	assert.Equal("r", data.FieldAliases()[1])
+	assert.Equal(query.Lag, windows[2].Function())
+	assert.Equal("salary", windows[2].FieldName())
+	assert.Equal(2, windows[2].Offset())
+	assert.Equal(0, windows[2].Default())
+	assert.Nil(windows[2].Frame())
+	assert.Equal("e.salary", windows[3].FieldName())
+	assert.Equal(query.UnboundedPreceding, windows[3].Frame().Start())
+	assert.Equal(1, windows[3].Frame().End())
+	assert.Equal("select id, rank() over (partition by dept order by salary desc) as r, "+
+		"lag(salary, 2, 0) over (order by id), sum(e.salary) over (partition by dept rows between unbounded preceding and 1 following) "+
+		"from emp e", data.String())
+
+	for _, sql := range []string{
+		"select rank(id) over () from emp",
+		"select sum(id) over (rows between current row and 1 preceding) from emp",
+		"select row_number() from emp",
+	} {
+		parser, err = NewParser(sql)
+		assert.NoError(err)
+		_, err = parser.Query()
+		assert.Error(err, sql)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("insert into emp (id, name) values (1, 'a'), (2, null)")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	data := cmd.(*InsertData)
+	assert.Equal("emp", data.TableName())
+	assert.Equal([]string{"id", "name"}, data.Fields())
+	assert.Equal([][]any{{1, "a"}, {2, nil}}, data.Values())
+	assert.Nil(data.Query())
+
+	parser, err = NewParser("insert into emp select id, name from staff where id > 3")
+	assert.NoError(err)
+	cmd, err = parser.UpdateCmd()
+	assert.NoError(err)
+	data = cmd.(*InsertData)
+	assert.Empty(data.Fields())
+	assert.Nil(data.Values())
+	assert.Equal("select id, name from staff where id>3", data.Query().String())
+
+	parser, err = NewParser("insert into emp (id) values (1), ")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	assert.Error(err)
+}
+
+func TestCreateTableAfterVarchar(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("create table emp (name varchar(10), id int)")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	assert.Equal([]string{"name", "id"}, cmd.(*CreateTableData).Schema().Fields())
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("update emp set salary = salary * (bonus + 2) - 1, name = 'x', age=age-1 where id = -2")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	data := cmd.(*ModifyData)
+	assert.Equal("emp", data.TableName())
+	assert.Equal([]string{"salary", "name", "age"}, data.Fields())
+	values := make([]string, len(data.Values()))
+	for i, value := range data.Values() {
+		values[i] = value.String()
+	}
+	assert.Equal([]string{"salary*(bonus+2)-1", "'x'", "age-1"}, values)
+	assert.Equal("id=-2", data.Predicate().String())
+
+	//without where every record is updated
+	parser, err = NewParser("update emp set age = 1")
+	assert.NoError(err)
+	cmd, err = parser.UpdateCmd()
+	assert.NoError(err)
+	assert.Equal("", cmd.(*ModifyData).Predicate().String())
+
+	parser, err = NewParser("update emp set age 1")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	assert.Error(err)
+
+	//a missing comma does not end the assignments and drop the where
+	parser, err = NewParser("update emp set qty = 9 qty = 10 where id = 2")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(err, &syntaxErr)
+	assert.ErrorContains(err, "expected , or where after the value of qty")
+}
+
+func TestQueryArithmetic(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id from emp where a - (b - c) = a / 2 * 3 + -1 and 2 * (a + 1) > (select b from dept)")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal("a-(b-c)=a/2*3+-1 and 2*(a+1)>(select b from dept)", data.Predicate().String())
+}
+
+func TestSelectExpressions(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("select id, did + 100 as code, e.a * 2 - 1, 0, 'x', (b), lvl from emp e")
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal([]string{"id", "did+100", "e.a*2-1", "0", "'x'", "b", "lvl"}, data.Fields())
+	assert.Equal([]string{"", "code", "", "", "", "", ""}, data.FieldAliases())
+	exprs := data.FieldExpressions()
+	assert.Nil(exprs[0])
+	assert.Nil(exprs[6])
+	for _, expr := range exprs[1:6] {
+		assert.NotNil(expr)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("create table ev (id bigint, ok boolean, rate double, day date, at timestamp, data blob(16))")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	schema := record.NewSchema()
+	schema.AddBigIntField("id")
+	schema.AddBoolField("ok")
+	schema.AddDoubleField("rate")
+	schema.AddDateField("day")
+	schema.AddTimestampField("at")
+	schema.AddBlobField("data", 16)
+	assert.True(schema.Equals(cmd.(*CreateTableData).Schema()))
+
+	parser, err = NewParser("insert into ev (ok, rate, day, at, data) values " +
+		"(TRUE, -1.25, date '2024-02-29', timestamp '2024-02-29 13:04:05.5', X'00fF')")
+	assert.NoError(err)
+	cmd, err = parser.UpdateCmd()
+	assert.NoError(err)
+	assert.Equal([][]any{{true, -1.25, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 13, 4, 5, 500000000, time.UTC), record.Blob("\x00\xff")}},
+		cmd.(*InsertData).Values())
+
+	//the literals are written back in a form that parses again
+	sql := "select id from ev where ok = false and rate * 2.0 > 3 and day < date '2024-03-01' and " +
+		"at <> timestamp '2024-02-29 13:04:05.5' and data = x'00ff' and rate-1.5 < 0.1"
+	parser, err = NewParser(sql)
+	assert.NoError(err)
+	data, err := parser.Query()
+	assert.NoError(err)
+	pred := data.Predicate().String()
+	assert.Equal("ok=false and rate*2.0>3 and day<date '2024-03-01' and "+
+		"at!=timestamp '2024-02-29 13:04:05.5' and data=x'00ff' and rate-1.5<0.1", pred)
+	parser, err = NewParser("select id from ev where " + pred)
+	assert.NoError(err)
+	reparsed, err := parser.Query()
+	assert.NoError(err)
+	assert.Equal(pred, reparsed.Predicate().String())
+
+	parser, err = NewParser("create table ev (data blob)")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	assert.Error(err)
+	for _, sql := range []string{
+		"select id from ev where day = date '2024-02-30'",
+		"select id from ev where data = x'0'",
+		"select id from ev where at = timestamp 5",
+	} {
+		parser, err = NewParser(sql)
+		if err == nil {
+			_, err = parser.Query()
+		}
+		assert.Error(err, sql)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	assert := assertPkg.New(t)
+	cmds := make([]any, 0)
+	for _, sql := range []string{"drop table emp", "drop view rich", "drop index emp_name"} {
+		parser, err := NewParser(sql)
+		assert.NoError(err)
+		cmd, err := parser.UpdateCmd()
+		assert.NoError(err)
+		cmds = append(cmds, cmd)
+	}
+	assert.Equal("emp", cmds[0].(*DropTableData).TableName())
+	assert.Equal("rich", cmds[1].(*DropViewData).ViewName())
+	assert.Equal("emp_name", cmds[2].(*DropIndexData).IndexName())
+
+	parser, err := NewParser("drop emp")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	assert.Error(err)
+}
+
+func TestVacuum(t *testing.T) {
+	assert := assertPkg.New(t)
+	for sql, tableName := range map[string]string{"vacuum emp": "emp", "vacuum": ""} {
+		parser, err := NewParser(sql)
+		assert.NoError(err)
+		cmd, err := parser.UpdateCmd()
+		assert.NoError(err)
+		assert.Equal(tableName, cmd.(*VacuumData).TableName())
+	}
+}
+
+func TestAlterTable(t *testing.T) {
+	assert := assertPkg.New(t)
+	alter := func(sql string) *AlterTableData {
+		parser, err := NewParser(sql)
+		assert.NoError(err)
+		cmd, err := parser.UpdateCmd()
+		assert.NoError(err, sql)
+		return cmd.(*AlterTableData)
+	}
+	data := alter("alter table emp add column nick varchar(5) default 'x'")
+	assert.Equal(AddColumn, data.Action())
+	assert.Equal("emp", data.TableName())
+	assert.Equal("nick", data.FieldName())
+	assert.Equal([]string{"nick"}, data.Schema().Fields())
+	assert.Equal("x", data.DefaultVal())
+	data = alter("alter table emp add age int")
+	assert.Equal("age", data.FieldName())
+	assert.Nil(data.DefaultVal())
+
+	data = alter("alter table emp drop column age")
+	assert.Equal(DropColumn, data.Action())
+	assert.Equal("age", data.FieldName())
+	assert.Equal(DropColumn, alter("alter table emp drop age").Action())
+
+	data = alter("alter table emp rename column age to years")
+	assert.Equal(RenameColumn, data.Action())
+	assert.Equal("age", data.FieldName())
+	assert.Equal("years", data.NewName())
+	assert.Equal(RenameColumn, alter("alter table emp rename age to years").Action())
+
+	data = alter("alter table emp rename to staff")
+	assert.Equal(RenameTable, data.Action())
+	assert.Equal("staff", data.NewName())
+
+	for _, sql := range []string{"alter table emp", "alter table emp rename age", "alter emp add age int"} {
+		parser, err := NewParser(sql)
+		assert.NoError(err)
+		_, err = parser.UpdateCmd()
+		assert.Error(err, sql)
+	}
+}
+
+func TestCreateTableConstraints(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("create table emp (id int primary key, name varchar(10) not null unique, " +
+		"dept int null, unique (dept, name), constraint emp_code constraint emp_dept_nn not null, code int)")
+	assert.NoError(err)
+	_, err = parser.UpdateCmd()
+	assert.Error(err)
+
+	parser, err = NewParser("create table emp (id int primary key, name varchar(10) not null unique, " +
+		"dept int null, constraint emp_dept_name unique (dept, name), code int constraint emp_code_nn not null)")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	data := cmd.(*CreateTableData)
+	assert.Equal([]string{"id", "name", "dept", "code"}, data.Schema().Fields())
+	constraints := make([]string, len(data.Constraints()))
+	for i, constraint := range data.Constraints() {
+		constraints[i] = constraint.String()
+	}
+	assert.Equal([]string{
+		"primary key (id)",
+		"not null (name)",
+		"unique (name)",
+		"constraint emp_code_nn not null (code)",
+		"constraint emp_dept_name unique (dept, name)",
+	}, constraints)
+
+	parser, err = NewParser("create table link (a int, b int, primary key (a, b))")
+	assert.NoError(err)
+	cmd, err = parser.UpdateCmd()
+	assert.NoError(err)
+	data = cmd.(*CreateTableData)
+	assert.Equal([]string{"a", "b"}, data.Schema().Fields())
+	assert.Equal(record.PrimaryKey, data.Constraints()[0].Type())
+	assert.Equal([]string{"a", "b"}, data.Constraints()[0].Fields())
+}
+
+func TestCreateTableForeignKeys(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("create table emp (id int primary key, dept int references dept on delete cascade, " +
+		"mgr int constraint emp_mgr references emp (id) on delete set null, " +
+		"a int, b int, foreign key (a, b) references link (x, y) on delete restrict, c int references link)")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	data := cmd.(*CreateTableData)
+	assert.Equal([]string{"id", "dept", "mgr", "a", "b", "c"}, data.Schema().Fields())
+	constraints := make([]string, len(data.Constraints()))
+	for i, constraint := range data.Constraints() {
+		constraints[i] = constraint.String()
+	}
+	assert.Equal([]string{
+		"primary key (id)",
+		"foreign key (dept) references dept on delete cascade",
+		"constraint emp_mgr foreign key (mgr) references emp (id) on delete set null",
+		"foreign key (c) references link on delete restrict",
+		"foreign key (a, b) references link (x, y) on delete restrict",
+	}, constraints)
+
+	for _, sql := range []string{
+		"create table emp (dept int references)",
+		"create table emp (dept int references dept on delete)",
+		"create table emp (dept int references dept on delete set)",
+		"create table emp (dept int, foreign key (dept))",
+		"create table emp (dept int, foreign (dept) references dept)",
+	} {
+		parser, err = NewParser(sql)
+		assert.NoError(err)
+		_, err = parser.UpdateCmd()
+		assert.Error(err, sql)
+	}
+}
+
+func TestCreateTableChecksAndDefaults(t *testing.T) {
+	assert := assertPkg.New(t)
+	parser, err := NewParser("create table item (id int primary key, qty int default 0 check (qty >= 0), " +
+		"name varchar(10) default 'n/a' not null, low int default -1, " +
+		"constraint item_range check (low <= qty and qty < 100))")
+	assert.NoError(err)
+	cmd, err := parser.UpdateCmd()
+	assert.NoError(err)
+	data := cmd.(*CreateTableData)
+	constraints := make([]string, len(data.Constraints()))
+	for i, constraint := range data.Constraints() {
+		constraints[i] = constraint.String()
+	}
+	assert.Equal([]string{
+		"primary key (id)",
+		"default (qty) 0",
+		"check (qty>=0)",
+		"default (name) 'n/a'",
+		"not null (name)",
+		"default (low) -1",
+		"constraint item_range check (low<=qty and qty<100)",
+	}, constraints)
+
+	//the definitions parse again
+	parser, err = NewParser(data.Constraints()[6].Definition())
+	assert.NoError(err)
+	pred, err := parser.CheckCondition()
+	assert.NoError(err)
+	assert.Equal("low<=qty and qty<100", pred.String())
+	parser, err = NewParser(data.Constraints()[3].Definition())
+	assert.NoError(err)
+	val, err := parser.DefaultValue()
+	assert.NoError(err)
+	assert.Equal("n/a", val)
+	parser, err = NewParser("qty > 0 qty")
+	assert.NoError(err)
+	_, err = parser.CheckCondition()
+	assert.Error(err)
+
+	for _, sql := range []string{
+		"create table item (qty int check qty > 0)",
+		"create table item (qty int check (qty))",
+		"create table item (qty int default)",
+		"create table item (qty int constraint q default 0)",
+	} {
+		parser, err = NewParser(sql)
+		assert.NoError(err)
+		_, err = parser.UpdateCmd()
+		assert.Error(err, sql)
+	}
+}
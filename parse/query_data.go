@@ -3,48 +3,221 @@ package parse
 import (
 	"fmt"
 	"jadb/query"
+	"slices"
 	"strings"
 )
 
 type QueryData struct {
-	fieldList []string
-	tableList []string
-	pred      *query.Predicate
+	with         []*CommonTableData
+	recursive    bool
+	fieldList    []string
+	fieldAliases []string
+	fieldExprs   []*query.Expression
+	windows      []*query.WindowFunction
+	tableList    []string
+	tableAliases []string
+	joins        []*JoinData
+	pred         *query.Predicate
+	distinct     bool
+	setOps       []*SetOperationData
+	orderBy      []*query.OrderField
+	limit        int
+	offset       int
 }
 
-func NewQueryData(fields []string, tables []string, predicate *query.Predicate) *QueryData {
-	return &QueryData{fields, tables, predicate}
+func NewQueryData(fields []string, tables []string, predicate *query.Predicate, distinct bool) *QueryData {
+	return &QueryData{
+		fieldList:    fields,
+		fieldAliases: make([]string, len(fields)),
+		fieldExprs:   make([]*query.Expression, len(fields)),
+		windows:      make([]*query.WindowFunction, len(fields)),
+		tableList:    tables,
+		tableAliases: make([]string, len(tables)),
+		joins:        make([]*JoinData, len(tables)),
+		pred:         predicate,
+		distinct:     distinct,
+		limit:        -1,
+	}
+}
+
+// ReferredTables
+// the tables and views the query reads in its from clauses, those of its
+// common tables, set operations and subqueries too. The names of common
+// tables are among them where they are referred to
+func (q *QueryData) ReferredTables() []string {
+	tables := slices.Clone(q.tableList)
+	var subqueries []*query.Subquery
+	if q.pred != nil {
+		subqueries = append(subqueries, q.pred.Subqueries()...)
+	}
+	for _, join := range q.joins {
+		if join != nil && join.On() != nil {
+			subqueries = append(subqueries, join.On().Subqueries()...)
+		}
+	}
+	for _, expr := range q.fieldExprs {
+		if expr != nil {
+			subqueries = append(subqueries, expr.Subqueries()...)
+		}
+	}
+	for _, subquery := range subqueries {
+		if data, ok := subquery.Definition().(*QueryData); ok {
+			tables = append(tables, data.ReferredTables()...)
+		}
+	}
+	for _, table := range q.with {
+		tables = append(tables, table.Query().ReferredTables()...)
+	}
+	for _, setOp := range q.setOps {
+		tables = append(tables, setOp.Query().ReferredTables()...)
+	}
+	return tables
 }
 
-func (q *QueryData) fields() []string {
+// Fields
+// select items, either *, table.*, table.field or field
+func (q *QueryData) Fields() []string {
 	return q.fieldList
 }
 
-func (q *QueryData) tables() []string {
+// FieldAliases
+// alias of each select item, empty when the item has none
+func (q *QueryData) FieldAliases() []string {
+	return q.fieldAliases
+}
+
+// FieldExpressions
+// the scalar subquery of each select item, nil for fields
+func (q *QueryData) FieldExpressions() []*query.Expression {
+	return q.fieldExprs
+}
+
+// WindowFunctions
+// the window function of each select item, nil for the other items
+func (q *QueryData) WindowFunctions() []*query.WindowFunction {
+	return q.windows
+}
+
+func (q *QueryData) Tables() []string {
 	return q.tableList
 }
 
-func (q *QueryData) predicate() *query.Predicate {
+// TableAliases
+// alias of each table, empty when the table has none
+func (q *QueryData) TableAliases() []string {
+	return q.tableAliases
+}
+
+// Joins
+// how each table is joined to the tables before it, nil for the first
+// table and for tables separated by a comma
+func (q *QueryData) Joins() []*JoinData {
+	return q.joins
+}
+
+func (q *QueryData) Predicate() *query.Predicate {
 	return q.pred
 }
 
-func (q *QueryData) toString() string {
-	result := "select "
-	fields := ""
-	for _, fld := range q.fieldList {
-		fields += fmt.Sprintf("%s,", fld)
+// IsDistinct
+// true when duplicate rows have to be removed from the output
+func (q *QueryData) IsDistinct() bool {
+	return q.distinct
+}
+
+// With
+// the tables defined by the with clause of the query
+func (q *QueryData) With() []*CommonTableData {
+	return q.with
+}
+
+// IsRecursive
+// true for with recursive, whose tables can refer to themselves
+func (q *QueryData) IsRecursive() bool {
+	return q.recursive
+}
+
+// SetOperations
+// the queries combined with this one, in order. When there are any, order
+// by and limit apply to the combined result and refer to its output columns
+func (q *QueryData) SetOperations() []*SetOperationData {
+	return q.setOps
+}
+
+func (q *QueryData) OrderBy() []*query.OrderField {
+	return q.orderBy
+}
+
+// Limit
+// maximum number of records to return, -1 when there is no limit
+func (q *QueryData) Limit() int {
+	return q.limit
+}
+
+func (q *QueryData) Offset() int {
+	return q.offset
+}
+
+func (q *QueryData) String() string {
+	result := ""
+	if len(q.with) > 0 {
+		result += "with "
+		if q.recursive {
+			result += "recursive "
+		}
+		tables := make([]string, len(q.with))
+		for i, table := range q.with {
+			tables[i] = table.String()
+		}
+		result += strings.Join(tables, ", ") + " "
 	}
-	fields = strings.TrimRight(fields, ",")
-	tables := ""
-	for _, tbl := range q.tableList {
-		tables += fmt.Sprintf("%s,", tbl)
+	result += "select "
+	if q.distinct {
+		result += "distinct "
 	}
-	tables = strings.TrimRight(tables, ",")
-
-	predicate := ""
-	if q.pred.String() != "" {
-		predicate += "where " + q.pred.String()
+	fields := make([]string, len(q.fieldList))
+	for i, field := range q.fieldList {
+		fields[i] = field
+		if q.fieldAliases[i] != "" {
+			fields[i] += " as " + q.fieldAliases[i]
+		}
+	}
+	result += strings.Join(fields, ", ")
+	result += " from "
+	for i, table := range q.tableList {
+		if i > 0 {
+			if join := q.joins[i]; join != nil {
+				result += " " + join.JoinType().String() + " "
+			} else {
+				result += ", "
+			}
+		}
+		result += table
+		if q.tableAliases[i] != "" {
+			result += " " + q.tableAliases[i]
+		}
+		if join := q.joins[i]; i > 0 && join != nil && join.On() != nil {
+			result += " on " + join.On().String()
+		}
+	}
+	if q.pred != nil && q.pred.String() != "" {
+		result += " where " + q.pred.String()
+	}
+	for _, setOp := range q.setOps {
+		result += " " + setOp.String()
+	}
+	if len(q.orderBy) > 0 {
+		orderBy := make([]string, len(q.orderBy))
+		for i, field := range q.orderBy {
+			orderBy[i] = field.String()
+		}
+		result += " order by " + strings.Join(orderBy, ", ")
+	}
+	if q.limit >= 0 {
+		result += fmt.Sprintf(" limit %d", q.limit)
+		if q.offset > 0 {
+			result += fmt.Sprintf(" offset %d", q.offset)
+		}
 	}
-	result = result + fields + tables + predicate
 	return result
 }
@@ -0,0 +1,9 @@
+package parse
+
+// ScrubData
+// scrub, checking every block of the database for corruption
+type ScrubData struct{}
+
+func NewScrubData() *ScrubData {
+	return &ScrubData{}
+}
@@ -0,0 +1,36 @@
+package parse
+
+import "jadb/query"
+
+// SetOperationData
+// a query combined with the queries before it by union, intersect or
+// except. Duplicates are kept when all is set
+type SetOperationData struct {
+	op    query.SetOperator
+	all   bool
+	query *QueryData
+}
+
+func NewSetOperationData(op query.SetOperator, all bool, query *QueryData) *SetOperationData {
+	return &SetOperationData{op, all, query}
+}
+
+func (s *SetOperationData) Operator() query.SetOperator {
+	return s.op
+}
+
+func (s *SetOperationData) IsAll() bool {
+	return s.all
+}
+
+func (s *SetOperationData) Query() *QueryData {
+	return s.query
+}
+
+func (s *SetOperationData) String() string {
+	result := s.op.String()
+	if s.all {
+		result += " all"
+	}
+	return result + " " + s.query.String()
+}
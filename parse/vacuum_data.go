@@ -0,0 +1,15 @@
+package parse
+
+// VacuumData
+// vacuum [table], an empty table name stands for every table
+type VacuumData struct {
+	tableName string
+}
+
+func NewVacuumData(tableName string) *VacuumData {
+	return &VacuumData{tableName}
+}
+
+func (d *VacuumData) TableName() string {
+	return d.tableName
+}
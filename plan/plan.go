@@ -6,7 +6,7 @@ import (
 )
 
 type Plan interface {
-	Open() *scan.Scan
+	Open() (scan.Scan, error)
 	BlocksAccessed() int
 	RecordsOutput() int
 	DistinctValues(string) int
@@ -0,0 +1,58 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*ConcatPlan)(nil)
+
+// ConcatPlan
+// the records of p1 followed by those of p2, as in union all. p2 has to
+// have the field names of p1, a string field is as long as the longest of
+// the two
+type ConcatPlan struct {
+	p1     plan.Plan
+	p2     plan.Plan
+	schema *record.Schema
+}
+
+func NewConcatPlan(p1 plan.Plan, p2 plan.Plan) *ConcatPlan {
+	schema := record.NewSchema()
+	for _, fldName := range p1.Schema().Fields() {
+		length := max(p1.Schema().Length(fldName), p2.Schema().Length(fldName))
+		schema.AddField(fldName, p1.Schema().Type(fldName), length)
+	}
+	return &ConcatPlan{p1, p2, schema}
+}
+
+func (c *ConcatPlan) Open() (scan.Scan, error) {
+	s1, err := c.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := c.p2.Open()
+	if err != nil {
+		s1.Close()
+		return nil, err
+	}
+	return scan_types.NewConcatScan(s1, s2), nil
+}
+
+func (c *ConcatPlan) BlocksAccessed() int {
+	return c.p1.BlocksAccessed() + c.p2.BlocksAccessed()
+}
+
+func (c *ConcatPlan) RecordsOutput() int {
+	return c.p1.RecordsOutput() + c.p2.RecordsOutput()
+}
+
+func (c *ConcatPlan) DistinctValues(fldName string) int {
+	return c.p1.DistinctValues(fldName) + c.p2.DistinctValues(fldName)
+}
+
+func (c *ConcatPlan) Schema() *record.Schema {
+	return c.schema
+}
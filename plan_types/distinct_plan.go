@@ -0,0 +1,53 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*DistinctPlan)(nil)
+
+type DistinctPlan struct {
+	p plan.Plan
+}
+
+func NewDistinctPlan(p plan.Plan) *DistinctPlan {
+	return &DistinctPlan{p}
+}
+
+func (d *DistinctPlan) Open() (scan.Scan, error) {
+	s, err := d.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewDistinctScan(s, d.p.Schema().Fields()), nil
+}
+
+func (d *DistinctPlan) BlocksAccessed() int {
+	return d.p.BlocksAccessed()
+}
+
+// RecordsOutput
+// there can not be more distinct rows than combinations of the
+// distinct values of each field, nor more than the input has
+func (d *DistinctPlan) RecordsOutput() int {
+	input := d.p.RecordsOutput()
+	combinations := 1
+	for _, fldName := range d.p.Schema().Fields() {
+		combinations *= max(d.p.DistinctValues(fldName), 1)
+		if combinations >= input {
+			return input
+		}
+	}
+	return combinations
+}
+
+func (d *DistinctPlan) DistinctValues(fldName string) int {
+	return min(d.p.DistinctValues(fldName), d.RecordsOutput())
+}
+
+func (d *DistinctPlan) Schema() *record.Schema {
+	return d.p.Schema()
+}
@@ -0,0 +1,56 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*ExtendPlan)(nil)
+
+// ExtendPlan
+// adds a computed field to the records of p
+type ExtendPlan struct {
+	p       plan.Plan
+	fldName string
+	expr    *query.Expression
+	schema  *record.Schema
+}
+
+// NewExtendPlan
+// fldName is computed by expr and has the type and length given
+func NewExtendPlan(p plan.Plan, fldName string, fldType int, length int, expr *query.Expression) *ExtendPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p.Schema())
+	schema.AddField(fldName, fldType, length)
+	return &ExtendPlan{p, fldName, expr, schema}
+}
+
+func (e *ExtendPlan) Open() (scan.Scan, error) {
+	s, err := e.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewExtendScan(s, e.fldName, e.expr), nil
+}
+
+func (e *ExtendPlan) BlocksAccessed() int {
+	return e.p.BlocksAccessed()
+}
+
+func (e *ExtendPlan) RecordsOutput() int {
+	return e.p.RecordsOutput()
+}
+
+func (e *ExtendPlan) DistinctValues(fldName string) int {
+	if fldName == e.fldName {
+		return e.p.RecordsOutput()
+	}
+	return e.p.DistinctValues(fldName)
+}
+
+func (e *ExtendPlan) Schema() *record.Schema {
+	return e.schema
+}
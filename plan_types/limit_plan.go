@@ -0,0 +1,50 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*LimitPlan)(nil)
+
+type LimitPlan struct {
+	p      plan.Plan
+	limit  int
+	offset int
+}
+
+// NewLimitPlan
+// a negative limit only skips offset records
+func NewLimitPlan(p plan.Plan, limit int, offset int) *LimitPlan {
+	return &LimitPlan{p, limit, offset}
+}
+
+func (l *LimitPlan) Open() (scan.Scan, error) {
+	s, err := l.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewLimitScan(s, l.limit, l.offset), nil
+}
+
+func (l *LimitPlan) BlocksAccessed() int {
+	return l.p.BlocksAccessed()
+}
+
+func (l *LimitPlan) RecordsOutput() int {
+	remaining := max(l.p.RecordsOutput()-l.offset, 0)
+	if l.limit < 0 {
+		return remaining
+	}
+	return min(remaining, l.limit)
+}
+
+func (l *LimitPlan) DistinctValues(fldName string) int {
+	return min(l.p.DistinctValues(fldName), l.RecordsOutput())
+}
+
+func (l *LimitPlan) Schema() *record.Schema {
+	return l.p.Schema()
+}
@@ -0,0 +1,73 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*OuterJoinPlan)(nil)
+
+// OuterJoinPlan
+// left outer join of p1 and p2 on pred, a full outer join when full is set.
+// A right outer join is a left outer join with the inputs swapped
+type OuterJoinPlan struct {
+	p1     plan.Plan
+	p2     plan.Plan
+	pred   *query.Predicate
+	full   bool
+	schema *record.Schema
+}
+
+func NewOuterJoinPlan(p1 plan.Plan, p2 plan.Plan, pred *query.Predicate, full bool) *OuterJoinPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	return &OuterJoinPlan{p1, p2, pred, full, schema}
+}
+
+func (o *OuterJoinPlan) Open() (scan.Scan, error) {
+	s1, err := o.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := o.p2.Open()
+	if err != nil {
+		s1.Close()
+		return nil, err
+	}
+	return scan_types.NewOuterJoinScan(s1, s2, o.pred, o.full), nil
+}
+
+func (o *OuterJoinPlan) BlocksAccessed() int {
+	blocks := o.p1.BlocksAccessed() + (o.p1.RecordsOutput() * o.p2.BlocksAccessed())
+	if o.full {
+		blocks += o.p2.BlocksAccessed()
+	}
+	return blocks
+}
+
+// RecordsOutput
+// every record of p1 is returned at least once, and for a full join every
+// record of p2 as well
+func (o *OuterJoinPlan) RecordsOutput() int {
+	product := NewProductPlan(o.p1, o.p2)
+	records := max(product.RecordsOutput()/o.pred.ReductionFactor(product), o.p1.RecordsOutput())
+	if o.full {
+		records += o.p2.RecordsOutput()
+	}
+	return records
+}
+
+func (o *OuterJoinPlan) DistinctValues(fldName string) int {
+	if o.p1.Schema().HasField(fldName) {
+		return o.p1.DistinctValues(fldName)
+	}
+	return o.p2.DistinctValues(fldName)
+}
+
+func (o *OuterJoinPlan) Schema() *record.Schema {
+	return o.schema
+}
@@ -0,0 +1,55 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*ProductPlan)(nil)
+
+type ProductPlan struct {
+	p1     plan.Plan
+	p2     plan.Plan
+	schema *record.Schema
+}
+
+func NewProductPlan(p1 plan.Plan, p2 plan.Plan) *ProductPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p1.Schema())
+	schema.AddAll(p2.Schema())
+	return &ProductPlan{p1, p2, schema}
+}
+
+func (p *ProductPlan) Open() (scan.Scan, error) {
+	s1, err := p.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := p.p2.Open()
+	if err != nil {
+		s1.Close()
+		return nil, err
+	}
+	return scan_types.NewProductScan(s1, s2), nil
+}
+
+func (p *ProductPlan) BlocksAccessed() int {
+	return p.p1.BlocksAccessed() + (p.p1.RecordsOutput() * p.p2.BlocksAccessed())
+}
+
+func (p *ProductPlan) RecordsOutput() int {
+	return p.p1.RecordsOutput() * p.p2.RecordsOutput()
+}
+
+func (p *ProductPlan) DistinctValues(fldName string) int {
+	if p.p1.Schema().HasField(fldName) {
+		return p.p1.DistinctValues(fldName)
+	}
+	return p.p2.DistinctValues(fldName)
+}
+
+func (p *ProductPlan) Schema() *record.Schema {
+	return p.schema
+}
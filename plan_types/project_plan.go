@@ -0,0 +1,51 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*ProjectPlan)(nil)
+
+type ProjectPlan struct {
+	p      plan.Plan
+	schema *record.Schema
+}
+
+func NewProjectPlan(p plan.Plan, fields []string) *ProjectPlan {
+	schema := record.NewSchema()
+	for _, fldName := range fields {
+		schema.Add(fldName, p.Schema())
+	}
+	return &ProjectPlan{p, schema}
+}
+
+func (p *ProjectPlan) Open() (scan.Scan, error) {
+	s, err := p.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	fldList := make(map[string]bool)
+	for _, fldName := range p.schema.Fields() {
+		fldList[fldName] = true
+	}
+	return scan_types.NewProjectScan(s, fldList), nil
+}
+
+func (p *ProjectPlan) BlocksAccessed() int {
+	return p.p.BlocksAccessed()
+}
+
+func (p *ProjectPlan) RecordsOutput() int {
+	return p.p.RecordsOutput()
+}
+
+func (p *ProjectPlan) DistinctValues(fldName string) int {
+	return p.p.DistinctValues(fldName)
+}
+
+func (p *ProjectPlan) Schema() *record.Schema {
+	return p.schema
+}
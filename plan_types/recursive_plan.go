@@ -0,0 +1,130 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+	"jadb/tx"
+)
+
+var _ plan.Plan = (*RecursivePlan)(nil)
+
+// RecursivePlan
+// a recursive common table. The records of anchor are materialised into a
+// temp table and become the work table, recursive, which reads the work
+// table, is then evaluated again and again with the records it found the
+// previous time until it finds none. With distinct a record that has
+// already been found is dropped, which also ends cycles
+type RecursivePlan struct {
+	txn       *tx.Transaction
+	anchor    plan.Plan
+	recursive plan.Plan
+	work      *WorkTable
+	distinct  bool
+	schema    *record.Schema
+}
+
+// NewRecursivePlan
+// recursive has to have the field names of anchor, string fields are as
+// long as the longest of the two
+func NewRecursivePlan(txn *tx.Transaction, anchor plan.Plan, recursive plan.Plan, work *WorkTable, distinct bool) *RecursivePlan {
+	schema := record.NewSchema()
+	for _, fldName := range anchor.Schema().Fields() {
+		length := max(anchor.Schema().Length(fldName), recursive.Schema().Length(fldName))
+		schema.AddField(fldName, anchor.Schema().Type(fldName), length)
+	}
+	return &RecursivePlan{txn, anchor, recursive, work, distinct, schema}
+}
+
+func (r *RecursivePlan) Open() (scan.Scan, error) {
+	result := scan_types.NewTempTable(r.txn, r.schema)
+	dest, err := result.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+	seen := make(map[string]bool)
+	next := r.anchor
+	for {
+		work, err := r.materialise(next, dest, seen)
+		if err != nil {
+			return nil, err
+		}
+		if work.records == 0 {
+			break
+		}
+		*r.work = *work
+		next = r.recursive
+	}
+	return result.Open()
+}
+
+// materialise
+// copies the new records of p into dest and into a new work table
+func (r *RecursivePlan) materialise(p plan.Plan, dest scan.UpdateScan, seen map[string]bool) (*WorkTable, error) {
+	src, err := p.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	work := &WorkTable{table: scan_types.NewTempTable(r.txn, r.schema)}
+	workScan, err := work.table.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer workScan.Close()
+	fields := r.schema.Fields()
+	for {
+		hasNext, err := src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !hasNext {
+			return work, nil
+		}
+		values := make([]any, len(fields))
+		for i, fldName := range fields {
+			if values[i], err = src.GetVal(fldName); err != nil {
+				return nil, err
+			}
+		}
+		if r.distinct {
+			key := scan_types.RecordKey(values)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		for _, s := range []scan.UpdateScan{dest, workScan} {
+			if err := s.Insert(); err != nil {
+				return nil, err
+			}
+			for i, fldName := range fields {
+				if err := s.SetVal(fldName, values[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		work.records++
+	}
+}
+
+func (r *RecursivePlan) BlocksAccessed() int {
+	return r.anchor.BlocksAccessed() + r.recursive.BlocksAccessed()
+}
+
+// RecordsOutput
+// the number of iterations is not known, assume the recursive part adds
+// as many records as the anchor has
+func (r *RecursivePlan) RecordsOutput() int {
+	return r.anchor.RecordsOutput() + r.recursive.RecordsOutput()
+}
+
+func (r *RecursivePlan) DistinctValues(fldName string) int {
+	return r.anchor.DistinctValues(fldName) + r.recursive.DistinctValues(fldName)
+}
+
+func (r *RecursivePlan) Schema() *record.Schema {
+	return r.schema
+}
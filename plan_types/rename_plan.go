@@ -0,0 +1,53 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*RenamePlan)(nil)
+
+// RenamePlan
+// projects the fields in sources and names them after outputs, the same
+// source field can be output under several names
+type RenamePlan struct {
+	p       plan.Plan
+	schema  *record.Schema
+	sources map[string]string
+}
+
+func NewRenamePlan(p plan.Plan, outputs []string, sources []string) *RenamePlan {
+	schema := record.NewSchema()
+	sourceOf := make(map[string]string)
+	for i, output := range outputs {
+		schema.AddField(output, p.Schema().Type(sources[i]), p.Schema().Length(sources[i]))
+		sourceOf[output] = sources[i]
+	}
+	return &RenamePlan{p, schema, sourceOf}
+}
+
+func (r *RenamePlan) Open() (scan.Scan, error) {
+	s, err := r.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewRenameScan(s, r.sources), nil
+}
+
+func (r *RenamePlan) BlocksAccessed() int {
+	return r.p.BlocksAccessed()
+}
+
+func (r *RenamePlan) RecordsOutput() int {
+	return r.p.RecordsOutput()
+}
+
+func (r *RenamePlan) DistinctValues(fldName string) int {
+	return r.p.DistinctValues(r.sources[fldName])
+}
+
+func (r *RenamePlan) Schema() *record.Schema {
+	return r.schema
+}
@@ -0,0 +1,50 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*SelectPlan)(nil)
+
+type SelectPlan struct {
+	p    plan.Plan
+	pred *query.Predicate
+}
+
+func NewSelectPlan(p plan.Plan, pred *query.Predicate) *SelectPlan {
+	return &SelectPlan{p, pred}
+}
+
+func (s *SelectPlan) Open() (scan.Scan, error) {
+	sc, err := s.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewSelectScan(sc, s.pred), nil
+}
+
+func (s *SelectPlan) BlocksAccessed() int {
+	return s.p.BlocksAccessed()
+}
+
+func (s *SelectPlan) RecordsOutput() int {
+	return s.p.RecordsOutput() / s.pred.ReductionFactor(s.p)
+}
+
+func (s *SelectPlan) DistinctValues(fldName string) int {
+	if s.pred.EquatesWithConstant(fldName) != nil {
+		return 1
+	}
+	if fldName2 := s.pred.EquatesWithField(fldName); fldName2 != "" {
+		return min(s.p.DistinctValues(fldName), s.p.DistinctValues(fldName2))
+	}
+	return min(s.p.DistinctValues(fldName), s.RecordsOutput())
+}
+
+func (s *SelectPlan) Schema() *record.Schema {
+	return s.p.Schema()
+}
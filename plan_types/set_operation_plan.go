@@ -0,0 +1,59 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*SetOperationPlan)(nil)
+
+// SetOperationPlan
+// intersect or except of p1 and p2, p2 has to have the field names of p1
+type SetOperationPlan struct {
+	p1  plan.Plan
+	p2  plan.Plan
+	op  query.SetOperator
+	all bool
+}
+
+func NewSetOperationPlan(p1 plan.Plan, p2 plan.Plan, op query.SetOperator, all bool) *SetOperationPlan {
+	return &SetOperationPlan{p1, p2, op, all}
+}
+
+func (s *SetOperationPlan) Open() (scan.Scan, error) {
+	s1, err := s.p1.Open()
+	if err != nil {
+		return nil, err
+	}
+	s2, err := s.p2.Open()
+	if err != nil {
+		s1.Close()
+		return nil, err
+	}
+	return scan_types.NewSetOperationScan(s1, s2, s.op, s.all, s.p1.Schema().Fields()), nil
+}
+
+func (s *SetOperationPlan) BlocksAccessed() int {
+	return s.p1.BlocksAccessed() + s.p2.BlocksAccessed()
+}
+
+// RecordsOutput
+// an intersection is no larger than either input, a difference no larger
+// than p1
+func (s *SetOperationPlan) RecordsOutput() int {
+	if s.op == query.Intersect {
+		return min(s.p1.RecordsOutput(), s.p2.RecordsOutput())
+	}
+	return s.p1.RecordsOutput()
+}
+
+func (s *SetOperationPlan) DistinctValues(fldName string) int {
+	return min(s.p1.DistinctValues(fldName), s.RecordsOutput())
+}
+
+func (s *SetOperationPlan) Schema() *record.Schema {
+	return s.p1.Schema()
+}
@@ -0,0 +1,187 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+	"jadb/tx"
+)
+
+var _ plan.Plan = (*SortPlan)(nil)
+
+// SortPlan
+// external merge sort, the input is split into sorted runs stored in temp
+// tables which are merged pairwise until at most two runs remain
+type SortPlan struct {
+	txn    *tx.Transaction
+	p      plan.Plan
+	schema *record.Schema
+	comp   *scan_types.RecordComparator
+}
+
+func NewSortPlan(txn *tx.Transaction, p plan.Plan, fields []*query.OrderField) *SortPlan {
+	return &SortPlan{txn, p, p.Schema(), scan_types.NewRecordComparator(fields)}
+}
+
+func (s *SortPlan) Open() (scan.Scan, error) {
+	src, err := s.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.splitIntoRuns(src)
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+	for len(runs) > 2 {
+		if runs, err = s.doAMergeIteration(runs); err != nil {
+			return nil, err
+		}
+	}
+	return scan_types.NewSortScan(runs, s.comp)
+}
+
+// BlocksAccessed
+// does not include the cost of sorting, only of reading the sorted output
+func (s *SortPlan) BlocksAccessed() int {
+	layout := record.NewLayout(s.schema)
+	rpb := max(s.txn.BlockSize()/layout.SlotSize(), 1)
+	return (s.p.RecordsOutput() + rpb - 1) / rpb
+}
+
+func (s *SortPlan) RecordsOutput() int {
+	return s.p.RecordsOutput()
+}
+
+func (s *SortPlan) DistinctValues(fldName string) int {
+	return s.p.DistinctValues(fldName)
+}
+
+func (s *SortPlan) Schema() *record.Schema {
+	return s.schema
+}
+
+func (s *SortPlan) splitIntoRuns(src scan.Scan) ([]*scan_types.TempTable, error) {
+	current := scan_types.NewTempTable(s.txn, s.schema)
+	runs := []*scan_types.TempTable{current}
+	currentScan, err := current.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { currentScan.Close() }()
+	if err := src.BeforeFirst(); err != nil {
+		return nil, err
+	}
+	hasNext, err := src.Next()
+	for hasNext {
+		if err != nil {
+			return nil, err
+		}
+		if err := s.copy(src, currentScan); err != nil {
+			return nil, err
+		}
+		if hasNext, err = src.Next(); err != nil || !hasNext {
+			break
+		}
+		//a new run starts whenever the order breaks
+		result, err := s.comp.Compare(src, currentScan)
+		if err != nil {
+			return nil, err
+		}
+		if result < 0 {
+			current = scan_types.NewTempTable(s.txn, s.schema)
+			runs = append(runs, current)
+			nextScan, err := current.Open()
+			if err != nil {
+				return nil, err
+			}
+			currentScan.Close()
+			currentScan = nextScan
+		}
+	}
+	return runs, err
+}
+
+func (s *SortPlan) doAMergeIteration(runs []*scan_types.TempTable) ([]*scan_types.TempTable, error) {
+	result := make([]*scan_types.TempTable, 0, (len(runs)+1)/2)
+	for len(runs) > 1 {
+		merged, err := s.mergeTwoRuns(runs[0], runs[1])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, merged)
+		runs = runs[2:]
+	}
+	return append(result, runs...), nil
+}
+
+func (s *SortPlan) mergeTwoRuns(run1 *scan_types.TempTable, run2 *scan_types.TempTable) (*scan_types.TempTable, error) {
+	src1, err := run1.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src1.Close()
+	src2, err := run2.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src2.Close()
+	result := scan_types.NewTempTable(s.txn, s.schema)
+	dest, err := result.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+
+	hasMore1, err := src1.Next()
+	if err != nil {
+		return nil, err
+	}
+	hasMore2, err := src2.Next()
+	if err != nil {
+		return nil, err
+	}
+	for hasMore1 || hasMore2 {
+		takeFirst := !hasMore2
+		if hasMore1 && hasMore2 {
+			comparison, err := s.comp.Compare(src1, src2)
+			if err != nil {
+				return nil, err
+			}
+			takeFirst = comparison <= 0
+		}
+		if takeFirst {
+			if err := s.copy(src1, dest); err != nil {
+				return nil, err
+			}
+			hasMore1, err = src1.Next()
+		} else {
+			if err := s.copy(src2, dest); err != nil {
+				return nil, err
+			}
+			hasMore2, err = src2.Next()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *SortPlan) copy(src scan.Scan, dest scan.UpdateScan) error {
+	if err := dest.Insert(); err != nil {
+		return err
+	}
+	for _, fldName := range s.schema.Fields() {
+		val, err := src.GetVal(fldName)
+		if err != nil {
+			return err
+		}
+		if err := dest.SetVal(fldName, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package plan_types
+
+import (
+	"jadb/metadata"
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+	"jadb/tx"
+)
+
+var _ plan.Plan = (*TablePlan)(nil)
+
+type TablePlan struct {
+	txn     *tx.Transaction
+	tblName string
+	layout  *record.Layout
+	si      *metadata.StatInfo
+}
+
+func NewTablePlan(txn *tx.Transaction, tblName string, mdm *metadata.MetadataManager) (*TablePlan, error) {
+	layout, err := mdm.GetLayout(tblName, txn)
+	if err != nil {
+		return nil, err
+	}
+	si, err := mdm.GetStatInfo(tblName, layout, txn)
+	if err != nil {
+		return nil, err
+	}
+	return &TablePlan{txn, tblName, layout, si}, nil
+}
+
+func (p *TablePlan) Open() (scan.Scan, error) {
+	return scan_types.NewTableScan(p.txn, p.tblName, p.layout)
+}
+
+func (p *TablePlan) BlocksAccessed() int {
+	return p.si.BlocksAccessed()
+}
+
+func (p *TablePlan) RecordsOutput() int {
+	return p.si.RecordsOutput()
+}
+
+func (p *TablePlan) DistinctValues(fldName string) int {
+	return p.si.DistinctValues()
+}
+
+func (p *TablePlan) Schema() *record.Schema {
+	return p.layout.Schema()
+}
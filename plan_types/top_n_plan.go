@@ -0,0 +1,48 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*TopNPlan)(nil)
+
+// TopNPlan
+// sort for queries that only need the first n records, keeps a bounded
+// heap in memory instead of doing a full external sort
+type TopNPlan struct {
+	p    plan.Plan
+	comp *scan_types.RecordComparator
+	n    int
+}
+
+func NewTopNPlan(p plan.Plan, fields []*query.OrderField, n int) *TopNPlan {
+	return &TopNPlan{p, scan_types.NewRecordComparator(fields), n}
+}
+
+func (t *TopNPlan) Open() (scan.Scan, error) {
+	s, err := t.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewTopNScan(s, t.comp, t.n, t.p.Schema().Fields()), nil
+}
+
+func (t *TopNPlan) BlocksAccessed() int {
+	return t.p.BlocksAccessed()
+}
+
+func (t *TopNPlan) RecordsOutput() int {
+	return min(t.p.RecordsOutput(), t.n)
+}
+
+func (t *TopNPlan) DistinctValues(fldName string) int {
+	return min(t.p.DistinctValues(fldName), t.RecordsOutput())
+}
+
+func (t *TopNPlan) Schema() *record.Schema {
+	return t.p.Schema()
+}
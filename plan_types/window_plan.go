@@ -0,0 +1,64 @@
+package plan_types
+
+import (
+	"jadb/plan"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*WindowPlan)(nil)
+
+// WindowPlan
+// adds the window functions with the same window to the records of p,
+// which has to be sorted on their partition by and order by fields
+type WindowPlan struct {
+	p         plan.Plan
+	fldNames  []string
+	functions []*query.WindowFunction
+	schema    *record.Schema
+}
+
+// NewWindowPlan
+// the value of functions[i] is the field fldNames[i]. Lag and lead have
+// the type of their argument, the other functions are ints
+func NewWindowPlan(p plan.Plan, fldNames []string, functions []*query.WindowFunction) *WindowPlan {
+	schema := record.NewSchema()
+	schema.AddAll(p.Schema())
+	for i, fn := range functions {
+		if fn.Function() == query.Lag || fn.Function() == query.Lead {
+			schema.AddField(fldNames[i], p.Schema().Type(fn.FieldName()), p.Schema().Length(fn.FieldName()))
+		} else {
+			schema.AddIntField(fldNames[i])
+		}
+	}
+	return &WindowPlan{p, fldNames, functions, schema}
+}
+
+func (w *WindowPlan) Open() (scan.Scan, error) {
+	s, err := w.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return scan_types.NewWindowScan(s, w.p.Schema().Fields(), w.fldNames, w.functions), nil
+}
+
+func (w *WindowPlan) BlocksAccessed() int {
+	return w.p.BlocksAccessed()
+}
+
+func (w *WindowPlan) RecordsOutput() int {
+	return w.p.RecordsOutput()
+}
+
+func (w *WindowPlan) DistinctValues(fldName string) int {
+	if w.p.Schema().HasField(fldName) {
+		return w.p.DistinctValues(fldName)
+	}
+	return w.p.RecordsOutput()
+}
+
+func (w *WindowPlan) Schema() *record.Schema {
+	return w.schema
+}
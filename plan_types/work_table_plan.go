@@ -0,0 +1,60 @@
+package plan_types
+
+import (
+	"fmt"
+	"jadb/plan"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+)
+
+var _ plan.Plan = (*WorkTablePlan)(nil)
+
+// WorkTable
+// the records found by the last iteration of a recursive common table
+type WorkTable struct {
+	table   *scan_types.TempTable
+	records int
+}
+
+func NewWorkTable() *WorkTable {
+	return &WorkTable{}
+}
+
+// WorkTablePlan
+// reads the work table of a recursive common table, it is how the recursive
+// part of its query refers to the common table
+type WorkTablePlan struct {
+	work     *WorkTable
+	schema   *record.Schema
+	estimate int
+}
+
+// NewWorkTablePlan
+// estimate is the expected number of records per iteration
+func NewWorkTablePlan(work *WorkTable, schema *record.Schema, estimate int) *WorkTablePlan {
+	return &WorkTablePlan{work, schema, estimate}
+}
+
+func (w *WorkTablePlan) Open() (scan.Scan, error) {
+	if w.work.table == nil {
+		return nil, fmt.Errorf("work table read before it has been filled")
+	}
+	return w.work.table.Open()
+}
+
+func (w *WorkTablePlan) BlocksAccessed() int {
+	return 1
+}
+
+func (w *WorkTablePlan) RecordsOutput() int {
+	return w.estimate
+}
+
+func (w *WorkTablePlan) DistinctValues(fldName string) int {
+	return w.estimate
+}
+
+func (w *WorkTablePlan) Schema() *record.Schema {
+	return w.schema
+}
@@ -0,0 +1,198 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/metadata"
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/tx"
+)
+
+var _ QueryPlanner = (*BasicQueryPlanner)(nil)
+
+type BasicQueryPlanner struct {
+	mdm *metadata.MetadataManager
+}
+
+func NewBasicQueryPlanner(mdm *metadata.MetadataManager) *BasicQueryPlanner {
+	return &BasicQueryPlanner{mdm}
+}
+
+func (qp *BasicQueryPlanner) CreatePlan(data *parse.QueryData, txn *tx.Transaction) (plan.Plan, error) {
+	return qp.createPlan(data, txn, newScope())
+}
+
+// createPlan
+// plan of data with its field names resolved in scope, which is the
+// scope of a subquery when data is nested in another query
+func (qp *BasicQueryPlanner) createPlan(data *parse.QueryData, txn *tx.Transaction, scope *scope) (plan.Plan, error) {
+	for _, table := range data.With() {
+		scope.with = &commonTable{data: table, recursive: data.IsRecursive(), prev: scope.with}
+	}
+	if len(data.SetOperations()) > 0 {
+		return qp.setOperationPlan(data, txn, scope)
+	}
+	return qp.selectPlan(data, txn, scope, true)
+}
+
+// selectPlan
+// plan of a single select, its order by and limit are left out unless
+// ordered is set
+func (qp *BasicQueryPlanner) selectPlan(data *parse.QueryData, txn *tx.Transaction, scope *scope, ordered bool) (plan.Plan, error) {
+	//a plan for every table or view, with its fields qualified by the
+	//name the query refers to it by. Tables joined with join ... on are
+	//combined into one plan, the comma separated groups form a product
+	groupScope := scope.newSiblingScope()
+	plans := make([]plan.Plan, 0, len(data.Tables()))
+	for i, tblName := range data.Tables() {
+		p, err := qp.tableOrViewPlan(tblName, txn, scope)
+		if err != nil {
+			return nil, err
+		}
+		name := tblName
+		if alias := data.TableAliases()[i]; alias != "" {
+			name = alias
+		}
+		if err := scope.add(name, p.Schema()); err != nil {
+			return nil, err
+		}
+		p = qualifiedPlan(name, p)
+		join := data.Joins()[i]
+		if join == nil {
+			groupScope = scope.newSiblingScope()
+			if err := groupScope.add(name, scope.item(name).schema); err != nil {
+				return nil, err
+			}
+			plans = append(plans, p)
+			continue
+		}
+		//the on predicate can only refer to the tables of its own group
+		if err := groupScope.add(name, scope.item(name).schema); err != nil {
+			return nil, err
+		}
+		on, err := qp.resolvePredicate(join.On(), groupScope, txn)
+		if err != nil {
+			return nil, err
+		}
+		plans[len(plans)-1] = joinPlan(plans[len(plans)-1], p, join.JoinType(), on)
+	}
+
+	//product of all groups
+	p := plans[0]
+	for _, next := range plans[1:] {
+		p = plan_types.NewProductPlan(p, next)
+	}
+
+	//predicate
+	if data.Predicate() != nil {
+		pred, err := qp.resolvePredicate(data.Predicate(), scope, txn)
+		if err != nil {
+			return nil, err
+		}
+		p = plan_types.NewSelectPlan(p, pred)
+	}
+
+	//scalar subqueries of the select list are computed fields
+	computed := make([]string, len(data.Fields()))
+	for i, expr := range data.FieldExpressions() {
+		if expr == nil {
+			continue
+		}
+		computed[i] = fmt.Sprintf("column%d", i+1)
+		var err error
+		if p, err = qp.extendPlan(p, computed[i], expr, scope, txn); err != nil {
+			return nil, err
+		}
+	}
+
+	//window functions see the records left by the predicate
+	p, err := qp.windowPlan(p, data.WindowFunctions(), computed, scope, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, sources, err := scope.selectList(data.Fields(), data.FieldAliases(), computed)
+	if err != nil {
+		return nil, err
+	}
+
+	//ordering, done before the projection so any field can be ordered on
+	if ordered && len(data.OrderBy()) > 0 {
+		orderBy := make([]*query.OrderField, len(data.OrderBy()))
+		for i, field := range data.OrderBy() {
+			source, err := orderBySource(field.FieldName(), outputs, sources, scope)
+			if err != nil {
+				return nil, err
+			}
+			orderBy[i] = query.NewOrderField(source, field.IsDescending())
+		}
+		if data.Limit() >= 0 && !data.IsDistinct() {
+			p = plan_types.NewTopNPlan(p, orderBy, data.Offset()+data.Limit())
+		} else {
+			p = plan_types.NewSortPlan(txn, p, orderBy)
+		}
+	}
+
+	//projection
+	p = plan_types.NewRenamePlan(p, outputs, sources)
+
+	//duplicate elimination keeps the first occurrence so the order is kept
+	if data.IsDistinct() {
+		p = plan_types.NewDistinctPlan(p)
+	}
+
+	if ordered && (data.Limit() >= 0 || data.Offset() > 0) {
+		p = plan_types.NewLimitPlan(p, data.Limit(), data.Offset())
+	}
+	return p, nil
+}
+
+// joinPlan
+// joins the plan of the tables so far with the plan of the next table
+func joinPlan(group plan.Plan, p plan.Plan, joinType query.JoinType, on *query.Predicate) plan.Plan {
+	switch joinType {
+	case query.LeftJoin:
+		return plan_types.NewOuterJoinPlan(group, p, on, false)
+	case query.RightJoin:
+		return plan_types.NewOuterJoinPlan(p, group, on, false)
+	case query.FullJoin:
+		return plan_types.NewOuterJoinPlan(group, p, on, true)
+	}
+	return plan_types.NewSelectPlan(plan_types.NewProductPlan(group, p), on)
+}
+
+// orderBySource
+// order by can refer to a column of the select list by its output name
+// or to any field of the tables in the query
+func orderBySource(fldName string, outputs []string, sources []string, scope *scope) (string, error) {
+	for i, output := range outputs {
+		if output == fldName {
+			return sources[i], nil
+		}
+	}
+	return scope.resolve(fldName)
+}
+
+// tableOrViewPlan
+// plan of a common table, view or table, in that order
+func (qp *BasicQueryPlanner) tableOrViewPlan(tblName string, txn *tx.Transaction, scope *scope) (plan.Plan, error) {
+	if table := scope.with.lookup(tblName); table != nil {
+		return qp.commonTablePlan(table, txn)
+	}
+	viewDef, err := qp.mdm.GetViewDef(tblName, txn)
+	if err != nil {
+		//not a view
+		return plan_types.NewTablePlan(txn, tblName, qp.mdm)
+	}
+	parser, err := parse.NewParser(viewDef)
+	if err != nil {
+		return nil, err
+	}
+	viewData, err := parser.Query()
+	if err != nil {
+		return nil, err
+	}
+	return qp.CreatePlan(viewData, txn)
+}
@@ -0,0 +1,1205 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/constants"
+	"jadb/file"
+	"jadb/metadata"
+	"jadb/parse"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan"
+	"jadb/scan_types"
+	"jadb/tx"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var _ UpdatePlanner = (*BasicUpdatePlanner)(nil)
+
+// BasicUpdatePlanner
+// modifies the records of a table through a table scan and keeps every
+// index of the table up to date
+type BasicUpdatePlanner struct {
+	mdm *metadata.MetadataManager
+	qp  *BasicQueryPlanner
+}
+
+func NewBasicUpdatePlanner(mdm *metadata.MetadataManager) *BasicUpdatePlanner {
+	return &BasicUpdatePlanner{mdm, NewBasicQueryPlanner(mdm)}
+}
+
+// ExecuteInsert
+// inserts the rows of values, or the records of the query which are read
+// before anything is inserted so it can read the table itself. Fields not
+// in the field list are left null, without a field list the values are
+// for all fields of the table in order
+func (up *BasicUpdatePlanner) ExecuteInsert(data *parse.InsertData, txn *tx.Transaction) (int, error) {
+	layout, err := up.mdm.GetLayout(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	schema := layout.Schema()
+	fields := data.Fields()
+	if len(fields) == 0 {
+		fields = schema.Fields()
+	}
+	if err := checkFields(data.TableName(), schema, fields); err != nil {
+		return 0, err
+	}
+	indexes, err := up.mdm.GetIndexInfo(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	checker, err := newConstraintChecker(up.mdm, data.TableName(), layout, indexes, txn)
+	if err != nil {
+		return 0, err
+	}
+
+	var src scan.Scan
+	var srcFields []string
+	if data.Query() != nil {
+		if src, srcFields, err = up.selectRecords(data.Query(), schema, fields, txn); err != nil {
+			return 0, err
+		}
+		defer src.Close()
+	}
+	ts, err := scan_types.NewTableScan(txn, data.TableName(), layout)
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+
+	if src == nil {
+		for _, row := range data.Values() {
+			if err := insertRecord(ts, schema, fields, row, indexes, checker); err != nil {
+				return 0, err
+			}
+		}
+		return len(data.Values()), nil
+	}
+	count := 0
+	for {
+		hasNext, err := src.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return count, nil
+		}
+		row := make([]any, len(srcFields))
+		for i, fldName := range srcFields {
+			if row[i], err = src.GetVal(fldName); err != nil {
+				return 0, err
+			}
+		}
+		if err := insertRecord(ts, schema, fields, row, indexes, checker); err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+// selectRecords
+// the records of the query copied into a temp table, with the names of
+// its columns. The columns are matched to fields by position
+func (up *BasicUpdatePlanner) selectRecords(data *parse.QueryData, schema *record.Schema, fields []string,
+	txn *tx.Transaction) (scan.Scan, []string, error) {
+	p, err := up.qp.CreatePlan(data, txn)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := p.Schema().Fields()
+	if len(columns) != len(fields) {
+		return nil, nil, fmt.Errorf("insert has %d fields but its query returns %d columns", len(fields), len(columns))
+	}
+	for i, column := range columns {
+		if !assignable(p.Schema().Type(column), schema.Type(fields[i])) {
+			return nil, nil, fmt.Errorf("column %s of the query does not have the type of field %s", column, fields[i])
+		}
+	}
+	src, err := p.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+	temp := scan_types.NewTempTable(txn, p.Schema())
+	dest, err := temp.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		hasNext, err := src.Next()
+		if err != nil {
+			dest.Close()
+			return nil, nil, err
+		}
+		if !hasNext {
+			break
+		}
+		if err := dest.Insert(); err != nil {
+			dest.Close()
+			return nil, nil, err
+		}
+		for _, column := range columns {
+			val, err := src.GetVal(column)
+			if err == nil {
+				err = dest.SetVal(column, val)
+			}
+			if err != nil {
+				dest.Close()
+				return nil, nil, err
+			}
+		}
+	}
+	if err := dest.BeforeFirst(); err != nil {
+		dest.Close()
+		return nil, nil, err
+	}
+	return dest, columns, nil
+}
+
+// insertRecord
+// inserts a record with row as the values of fields, the values and the
+// constraints of the table are checked before the record is inserted. The
+// fields left out get their default, or null without one. Without a
+// checker no constraint is checked and there are no defaults
+func insertRecord(ts *scan_types.TableScan, schema *record.Schema, fields []string, row []any,
+	indexes map[string]metadata.IndexInfo, checker *constraintChecker) error {
+	if len(row) != len(fields) {
+		return fmt.Errorf("expected %d values for fields %v, got %d", len(fields), fields, len(row))
+	}
+	vals := make(map[string]any)
+	for i, fldName := range fields {
+		val, err := convertValue(schema, fldName, row[i])
+		if err != nil {
+			return err
+		}
+		vals[fldName] = val
+	}
+	if checker != nil {
+		for fldName, val := range checker.defaults {
+			if _, listed := vals[fldName]; listed {
+				continue
+			}
+			var err error
+			if vals[fldName], err = convertValue(schema, fldName, val); err != nil {
+				return err
+			}
+		}
+		if err := checker.check(vals, nil, nil); err != nil {
+			return err
+		}
+	}
+	if err := ts.Insert(); err != nil {
+		return err
+	}
+	for fldName, val := range vals {
+		if err := ts.SetVal(fldName, val); err != nil {
+			return err
+		}
+	}
+	rid := ts.GetRid()
+	for fldName, info := range indexes {
+		val, err := ts.GetVal(fldName)
+		if err != nil {
+			return err
+		}
+		idx := info.Open()
+		err = idx.Insert(val, rid)
+		idx.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteDelete
+// deletes the records satisfying the predicate and their index entries,
+// and applies the foreign keys referring to each deleted record
+func (up *BasicUpdatePlanner) ExecuteDelete(data *parse.DeleteData, txn *tx.Transaction) (int, error) {
+	layout, err := up.mdm.GetLayout(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	s, indexes, err := up.modifiedRecords(data.TableName(), data.Predicate(), txn)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	count := 0
+	for {
+		hasNext, err := s.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return count, nil
+		}
+		if err := up.deleteRecord(data.TableName(), s, layout.Schema(), indexes, txn); err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+// deleteRecord
+// deletes the current record of s and its index entries, then applies the
+// action of every foreign key referring to the table to the records
+// referring to the deleted one
+func (up *BasicUpdatePlanner) deleteRecord(tblName string, s scan.UpdateScan, schema *record.Schema,
+	indexes map[string]metadata.IndexInfo, txn *tx.Transaction) error {
+	vals := make(map[string]any)
+	rid := s.GetRid()
+	for _, fldName := range schema.Fields() {
+		val, err := s.GetVal(fldName)
+		if err != nil {
+			return err
+		}
+		vals[fldName] = val
+	}
+	// a restrict foreign key fails before anything is deleted
+	if err := up.applyReferences(tblName, vals, nil, rid, false, txn); err != nil {
+		return err
+	}
+	for fldName, info := range indexes {
+		idx := info.Open()
+		err := idx.Delete(vals[fldName], rid)
+		idx.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	return up.applyReferences(tblName, vals, nil, nil, true, txn)
+}
+
+// applyReferences
+// vals are the values of a record of the table before it was deleted or
+// changed, newVals its values after a change and nil after a delete. The
+// records still referring to vals through a foreign key make a restrict
+// foreign key fail, are deleted by a cascade one and get null for its
+// fields by a set null one. A change of the fields referred to is always
+// restricted, and only restrict foreign keys are checked unless apply is
+// true. A record referring to its own fields is not referring to vals once
+// its change at rid is done
+func (up *BasicUpdatePlanner) applyReferences(tblName string, vals map[string]any, newVals map[string]any,
+	rid *record.RID, apply bool, txn *tx.Transaction) error {
+	references, err := up.mdm.GetReferences(tblName, txn)
+	if err != nil {
+		return err
+	}
+	for _, reference := range references {
+		constraint := reference.Constraint()
+		values := valuesOf(vals, constraint.RefFields())
+		if slices.Contains(values, nil) {
+			continue
+		}
+		if newVals != nil && slices.Equal(values, valuesOf(newVals, constraint.RefFields())) {
+			continue
+		}
+		action := constraint.OnDelete()
+		if newVals != nil {
+			action = record.Restrict
+		}
+		if !apply && action != record.Restrict {
+			continue
+		}
+		layout, err := up.mdm.GetLayout(reference.Table(), txn)
+		if err != nil {
+			return err
+		}
+		indexes, err := up.mdm.GetIndexInfo(reference.Table(), txn)
+		if err != nil {
+			return err
+		}
+		self := rid
+		if reference.Table() != tblName || (newVals != nil && slices.Equal(values, valuesOf(newVals, constraint.Fields()))) {
+			self = nil
+		}
+		violation := &ConstraintViolation{reference.Table(), constraint, values, true}
+		// every action removes the record from the ones referring to vals,
+		// so the first one is looked up again until there is none left
+		for {
+			var child *record.RID
+			err := findRecords(txn, reference.Table(), layout, indexes, constraint.Fields(), values,
+				func(ts *scan_types.TableScan) (bool, error) {
+					if self != nil && ts.GetRid().Equals(self) {
+						return false, nil
+					}
+					child = ts.GetRid()
+					return true, nil
+				})
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				break
+			}
+			if action == record.Restrict {
+				return violation
+			}
+			if err := up.applyAction(reference.Table(), constraint, layout, indexes, child, txn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyAction
+// deletes the record at rid for a cascade foreign key, or sets its fields
+// to null for a set null one
+func (up *BasicUpdatePlanner) applyAction(tblName string, constraint *record.Constraint, layout *record.Layout,
+	indexes map[string]metadata.IndexInfo, rid *record.RID, txn *tx.Transaction) error {
+	ts, err := scan_types.NewTableScan(txn, tblName, layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	if err := ts.MoveToRid(rid); err != nil {
+		return err
+	}
+	if constraint.OnDelete() == record.Cascade {
+		return up.deleteRecord(tblName, ts, layout.Schema(), indexes, txn)
+	}
+	vals := make(map[string]any)
+	newVals := make(map[string]any)
+	for _, fldName := range layout.Schema().Fields() {
+		val, err := ts.GetVal(fldName)
+		if err != nil {
+			return err
+		}
+		vals[fldName] = val
+		newVals[fldName] = val
+	}
+	for _, fldName := range constraint.Fields() {
+		newVals[fldName] = nil
+	}
+	if err := up.applyReferences(tblName, vals, newVals, rid, true, txn); err != nil {
+		return err
+	}
+	for _, fldName := range constraint.Fields() {
+		if err := setIndexedVal(ts, fldName, nil, indexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteModify
+// sets the fields to the values of their expressions for every record
+// satisfying the predicate. All expressions are evaluated against the
+// record as it was before the update, and are checked before any field of
+// it is set, so a record is either updated completely or not at all
+func (up *BasicUpdatePlanner) ExecuteModify(data *parse.ModifyData, txn *tx.Transaction) (int, error) {
+	layout, err := up.mdm.GetLayout(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	schema := layout.Schema()
+	fields := data.Fields()
+	if err := checkFields(data.TableName(), schema, fields); err != nil {
+		return 0, err
+	}
+	scope := newTableScope(data.TableName(), schema)
+	exprs := make([]*query.Expression, len(fields))
+	for i, expr := range data.Values() {
+		if exprs[i], err = expr.ResolveFields(scope.expression); err != nil {
+			return 0, err
+		}
+		for _, subquery := range exprs[i].Subqueries() {
+			if err := up.qp.bindSubquery(subquery, scope, txn); err != nil {
+				return 0, err
+			}
+		}
+	}
+	s, indexes, err := up.modifiedRecords(data.TableName(), data.Predicate(), txn)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	checker, err := newConstraintChecker(up.mdm, data.TableName(), layout, indexes, txn)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	values := make([]any, len(fields))
+	for {
+		hasNext, err := s.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return count, nil
+		}
+		for i, expr := range exprs {
+			if values[i], err = expr.Evaluate(s); err != nil {
+				return 0, err
+			}
+			if values[i], err = convertValue(schema, fields[i], values[i]); err != nil {
+				return 0, err
+			}
+		}
+		if err := up.checkModified(data.TableName(), s, schema, fields, values, checker, txn); err != nil {
+			return 0, err
+		}
+		for i, fldName := range fields {
+			if err := setIndexedVal(s, fldName, values[i], indexes); err != nil {
+				return 0, err
+			}
+		}
+		count++
+	}
+}
+
+// checkModified
+// checks the constraints on the fields of the current record against the
+// record it becomes with values as the new values of fields, and that no
+// record still refers to the values it had
+func (up *BasicUpdatePlanner) checkModified(tblName string, s scan.UpdateScan, schema *record.Schema,
+	fields []string, values []any, checker *constraintChecker, txn *tx.Transaction) error {
+	vals := make(map[string]any)
+	newVals := make(map[string]any)
+	for _, fldName := range schema.Fields() {
+		val, err := s.GetVal(fldName)
+		if err != nil {
+			return err
+		}
+		vals[fldName] = val
+		newVals[fldName] = val
+	}
+	for i, fldName := range fields {
+		newVals[fldName] = values[i]
+	}
+	if err := checker.check(newVals, s.GetRid(), fields); err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(fields, func(fldName string) bool { return vals[fldName] != newVals[fldName] }) {
+		return nil
+	}
+	return up.applyReferences(tblName, vals, newVals, s.GetRid(), true, txn)
+}
+
+// setIndexedVal
+// sets the field of the current record, moving its entry in the index of
+// the field if there is one and the value changes
+func setIndexedVal(s scan.UpdateScan, fldName string, val any, indexes map[string]metadata.IndexInfo) error {
+	info, indexed := indexes[fldName]
+	if !indexed {
+		return s.SetVal(fldName, val)
+	}
+	old, err := s.GetVal(fldName)
+	if err != nil {
+		return err
+	}
+	if old == val {
+		return nil
+	}
+	idx := info.Open()
+	defer idx.Close()
+	rid := s.GetRid()
+	if err := idx.Delete(old, rid); err != nil {
+		return err
+	}
+	if err := s.SetVal(fldName, val); err != nil {
+		return err
+	}
+	return idx.Insert(val, rid)
+}
+
+// modifiedRecords
+// a scan of the records of the table satisfying pred and the indexes of
+// the table
+func (up *BasicUpdatePlanner) modifiedRecords(tblName string, pred *query.Predicate,
+	txn *tx.Transaction) (scan.UpdateScan, map[string]metadata.IndexInfo, error) {
+	layout, err := up.mdm.GetLayout(tblName, txn)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, err := up.qp.resolvePredicate(pred, newTableScope(tblName, layout.Schema()), txn)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexes, err := up.mdm.GetIndexInfo(tblName, txn)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts, err := scan_types.NewTableScan(txn, tblName, layout)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scan_types.NewSelectScan(ts, resolved), indexes, nil
+}
+
+// ExecuteCreateTable
+// creates the table with its constraints, a primary key or unique
+// constraint gets an index named after it on its first field unless an
+// earlier constraint already created one there
+func (up *BasicUpdatePlanner) ExecuteCreateTable(data *parse.CreateTableData, txn *tx.Transaction) (int, error) {
+	tblName := data.TableName()
+	if _, err := up.mdm.GetLayout(tblName, txn); err == nil {
+		return 0, fmt.Errorf("table %s already exists", tblName)
+	}
+	if err := checkFields(tblName, data.Schema(), data.Schema().Fields()); err != nil {
+		return 0, err
+	}
+	constraints := make([]*record.Constraint, len(data.Constraints()))
+	names := make(map[string]bool)
+	indexed := make(map[string]bool)
+	defaults := make(map[string]bool)
+	hasPrimaryKey := false
+	for i, constraint := range data.Constraints() {
+		switch constraint.Type() {
+		case record.Check:
+			resolved, err := resolveCheck(tblName, data.Schema(), constraint)
+			if err != nil {
+				return 0, err
+			}
+			constraint = resolved
+		case record.Default:
+			fldName := constraint.Fields()[0]
+			if defaults[fldName] {
+				return 0, fmt.Errorf("field %s has more than one default", fldName)
+			}
+			defaults[fldName] = true
+			val, err := parseDefault(constraint.Definition())
+			if err != nil {
+				return 0, err
+			}
+			if _, err := convertValue(data.Schema(), fldName, val); err != nil {
+				return 0, err
+			}
+		}
+		if err := checkFields(tblName, data.Schema(), constraint.Fields()); err != nil {
+			return 0, err
+		}
+		if constraint.Type() == record.PrimaryKey {
+			if hasPrimaryKey {
+				return 0, fmt.Errorf("table %s can only have one primary key", tblName)
+			}
+			hasPrimaryKey = true
+		}
+		name := constraint.Name()
+		if name == "" {
+			name = constraintName(tblName, constraint)
+		}
+		if names[name] {
+			return 0, fmt.Errorf("constraint %s appears more than once", name)
+		}
+		names[name] = true
+		constraints[i] = constraint.Named(name)
+	}
+	for i, constraint := range constraints {
+		if constraint.Type() != record.ForeignKey {
+			continue
+		}
+		resolved, err := up.resolveForeignKey(tblName, data.Schema(), constraints, constraint, txn)
+		if err != nil {
+			return 0, err
+		}
+		constraints[i] = resolved
+	}
+	if err := up.mdm.CreateTable(tblName, data.Schema(), txn); err != nil {
+		return 0, err
+	}
+	for _, constraint := range constraints {
+		if err := up.mdm.CreateConstraint(tblName, constraint, txn); err != nil {
+			return 0, err
+		}
+		fldName := constraint.Fields()[0]
+		if !constraint.IsUnique() || indexed[fldName] {
+			continue
+		}
+		if err := up.checkIndexName(constraint.Name(), txn); err != nil {
+			return 0, err
+		}
+		if err := up.mdm.CreateIndex(constraint.Name(), tblName, fldName, txn); err != nil {
+			return 0, err
+		}
+		indexed[fldName] = true
+	}
+	return 0, nil
+}
+
+// resolveForeignKey
+// checks the foreign key of a table being created with schema and
+// constraints, and returns it with the fields it refers to, which are the
+// primary key of the table referred to when none are given. The fields
+// referred to have to be the fields of a primary key or unique constraint
+// of that table, which is the table itself for a foreign key referring to
+// its own records
+func (up *BasicUpdatePlanner) resolveForeignKey(tblName string, schema *record.Schema,
+	constraints []*record.Constraint, fk *record.Constraint, txn *tx.Transaction) (*record.Constraint, error) {
+	refTable := fk.RefTable()
+	refSchema, refConstraints := schema, constraints
+	if refTable != tblName {
+		layout, err := up.mdm.GetLayout(refTable, txn)
+		if err != nil {
+			return nil, fmt.Errorf("table %s referred to by constraint %s does not exist", refTable, fk.Name())
+		}
+		refSchema = layout.Schema()
+		if refConstraints, err = up.mdm.GetConstraints(refTable, txn); err != nil {
+			return nil, err
+		}
+	}
+	refFields := fk.RefFields()
+	if len(refFields) == 0 {
+		for _, constraint := range refConstraints {
+			if constraint.Type() == record.PrimaryKey {
+				refFields = constraint.Fields()
+			}
+		}
+		if len(refFields) == 0 {
+			return nil, fmt.Errorf("table %s referred to by constraint %s has no primary key", refTable, fk.Name())
+		}
+	}
+	if err := checkFields(refTable, refSchema, refFields); err != nil {
+		return nil, err
+	}
+	if len(refFields) != len(fk.Fields()) {
+		return nil, fmt.Errorf("constraint %s has %d fields but refers to %d", fk.Name(), len(fk.Fields()), len(refFields))
+	}
+	for i, fldName := range fk.Fields() {
+		if schema.Type(fldName) != refSchema.Type(refFields[i]) {
+			return nil, fmt.Errorf("field %s of constraint %s has another type than %s of table %s",
+				fldName, fk.Name(), refFields[i], refTable)
+		}
+	}
+	if !slices.ContainsFunc(refConstraints, func(constraint *record.Constraint) bool {
+		return constraint.IsUnique() && slices.Equal(constraint.Fields(), refFields)
+	}) {
+		return nil, fmt.Errorf("fields (%s) of table %s referred to by constraint %s are not a primary key or unique",
+			strings.Join(refFields, ", "), refTable, fk.Name())
+	}
+	if fk.OnDelete() == record.SetNull && slices.ContainsFunc(constraints, func(constraint *record.Constraint) bool {
+		return constraint.IsNotNull() && slices.ContainsFunc(constraint.Fields(), func(fldName string) bool {
+			return slices.Contains(fk.Fields(), fldName)
+		})
+	}) {
+		return nil, fmt.Errorf("constraint %s sets fields that cannot be null to null", fk.Name())
+	}
+	return record.NewForeignKey(fk.Name(), fk.Fields(), refTable, refFields, fk.OnDelete()), nil
+}
+
+// checkIndexName
+// index names are shared by all tables, as the buckets are named after them
+func (up *BasicUpdatePlanner) checkIndexName(idxName string, txn *tx.Transaction) error {
+	exists, err := up.mdm.HasIndex(idxName, txn)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("index %s already exists", idxName)
+	}
+	return nil
+}
+
+// ExecuteCreateView
+// the query of the view is planned first so an invalid view is rejected
+func (up *BasicUpdatePlanner) ExecuteCreateView(data *parse.CreateViewData, txn *tx.Transaction) (int, error) {
+	if _, err := up.qp.CreatePlan(data.QueryData(), txn); err != nil {
+		return 0, err
+	}
+	return 0, up.mdm.CreateView(data.ViewName(), data.QueryData().String(), txn)
+}
+
+// ExecuteCreateIndex
+// the records already in the table are added to the new index. A field
+// can only have one index
+func (up *BasicUpdatePlanner) ExecuteCreateIndex(data *parse.CreateIndexData, txn *tx.Transaction) (int, error) {
+	layout, err := up.mdm.GetLayout(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkFields(data.TableName(), layout.Schema(), []string{data.FieldName()}); err != nil {
+		return 0, err
+	}
+	if err := up.checkIndexName(data.IndexName(), txn); err != nil {
+		return 0, err
+	}
+	existing, err := up.mdm.GetIndexInfo(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	if info, indexed := existing[data.FieldName()]; indexed {
+		return 0, fmt.Errorf("field %s of table %s already has index %s", data.FieldName(), data.TableName(), info.IndexName())
+	}
+	if err := up.mdm.CreateIndex(data.IndexName(), data.TableName(), data.FieldName(), txn); err != nil {
+		return 0, err
+	}
+	indexes, err := up.mdm.GetIndexInfo(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	idx := indexes[data.FieldName()].Open()
+	defer idx.Close()
+	ts, err := scan_types.NewTableScan(txn, data.TableName(), layout)
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	for {
+		hasNext, err := ts.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return 0, nil
+		}
+		val, err := ts.GetVal(data.FieldName())
+		if err != nil {
+			return 0, err
+		}
+		if err := idx.Insert(val, ts.GetRid()); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// ExecuteAlterTable
+// adding or dropping a column rewrites every record of the table for the
+// new layout and returns the number of records rewritten, renaming only
+// changes the catalog. The default of an added column stays its default.
+// The constraints on a dropped column are dropped with it, a column
+// referred to by a foreign key of another table cannot be dropped
+func (up *BasicUpdatePlanner) ExecuteAlterTable(data *parse.AlterTableData, txn *tx.Transaction) (int, error) {
+	tblName := data.TableName()
+	if catalogTables[tblName] {
+		return 0, fmt.Errorf("cannot alter catalog table %s", tblName)
+	}
+	layout, err := up.mdm.GetLayout(tblName, txn)
+	if err != nil {
+		return 0, err
+	}
+	schema := layout.Schema()
+	if data.Action() != parse.AddColumn && data.Action() != parse.RenameTable {
+		if err := checkFields(tblName, schema, []string{data.FieldName()}); err != nil {
+			return 0, err
+		}
+	}
+	switch data.Action() {
+	case parse.AddColumn:
+		if schema.HasField(data.FieldName()) {
+			return 0, fmt.Errorf("table %s already has a field %s", tblName, data.FieldName())
+		}
+		defaultVal, err := convertValue(data.Schema(), data.FieldName(), data.DefaultVal())
+		if err != nil {
+			return 0, err
+		}
+		newSchema := record.NewSchema()
+		newSchema.AddAll(schema)
+		newSchema.AddAll(data.Schema())
+		count, err := up.rewriteTable(tblName, layout, newSchema, map[string]any{data.FieldName(): defaultVal}, txn)
+		if err != nil || defaultVal == nil {
+			return count, err
+		}
+		// the default also applies to the records inserted later
+		def := record.NewDefault("", data.FieldName(), query.NewConstantExpression(defaultVal).String())
+		return count, up.mdm.CreateConstraint(tblName, def.Named(constraintName(tblName, def)), txn)
+	case parse.DropColumn:
+		if len(schema.Fields()) == 1 {
+			return 0, fmt.Errorf("cannot drop %s, the only field of table %s", data.FieldName(), tblName)
+		}
+		indexes, err := up.mdm.GetIndexInfo(tblName, txn)
+		if err != nil {
+			return 0, err
+		}
+		if info, indexed := indexes[data.FieldName()]; indexed {
+			if err := up.mdm.DropIndex(info.IndexName(), txn); err != nil {
+				return 0, err
+			}
+		}
+		references, err := up.mdm.GetReferences(tblName, txn)
+		if err != nil {
+			return 0, err
+		}
+		for _, reference := range references {
+			if reference.Table() != tblName && slices.Contains(reference.Constraint().RefFields(), data.FieldName()) {
+				return 0, fmt.Errorf("cannot drop %s, it is referred to by constraint %s of table %s",
+					data.FieldName(), reference.Constraint().Name(), reference.Table())
+			}
+		}
+		constraints, err := up.mdm.GetConstraints(tblName, txn)
+		if err != nil {
+			return 0, err
+		}
+		for _, constraint := range constraints {
+			if !slices.Contains(constraint.Fields(), data.FieldName()) &&
+				!slices.Contains(constraint.RefFields(), data.FieldName()) {
+				continue
+			}
+			if err := up.mdm.DropConstraint(tblName, constraint.Name(), txn); err != nil {
+				return 0, err
+			}
+		}
+		newSchema := record.NewSchema()
+		for _, fldName := range schema.Fields() {
+			if fldName != data.FieldName() {
+				newSchema.Add(fldName, schema)
+			}
+		}
+		return up.rewriteTable(tblName, layout, newSchema, nil, txn)
+	case parse.RenameColumn:
+		if schema.HasField(data.NewName()) {
+			return 0, fmt.Errorf("table %s already has a field %s", tblName, data.NewName())
+		}
+		if err := up.mdm.RenameField(tblName, data.FieldName(), data.NewName(), txn); err != nil {
+			return 0, err
+		}
+		return 0, up.renameChecks(tblName, data.FieldName(), data.NewName(), txn)
+	case parse.RenameTable:
+		if _, err := up.mdm.GetLayout(data.NewName(), txn); err == nil {
+			return 0, fmt.Errorf("table %s already exists", data.NewName())
+		}
+		return 0, up.mdm.RenameTable(tblName, data.NewName(), txn)
+	}
+	return 0, fmt.Errorf("unsupported alter table action %d", data.Action())
+}
+
+// renameChecks
+// the catalog has the renamed field in the fields of the checks, their
+// conditions are rewritten with it
+func (up *BasicUpdatePlanner) renameChecks(tblName string, oldName string, newName string, txn *tx.Transaction) error {
+	constraints, err := up.mdm.GetConstraints(tblName, txn)
+	if err != nil {
+		return err
+	}
+	for _, constraint := range constraints {
+		if constraint.Type() != record.Check || !slices.Contains(constraint.Fields(), newName) {
+			continue
+		}
+		renamed, err := renameCheck(constraint, oldName, newName)
+		if err != nil {
+			return err
+		}
+		if err := up.mdm.DropConstraint(tblName, constraint.Name(), txn); err != nil {
+			return err
+		}
+		if err := up.mdm.CreateConstraint(tblName, renamed, txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteTable
+// copies the records of the table aside, clears its blocks, truncates its
+// file and inserts the records again with the layout of newSchema, so they
+// take as few blocks as they need. Fields that are new get their value
+// from defaults. Every change is logged, so the table has either the old
+// or the new layout after a crash
+func (up *BasicUpdatePlanner) rewriteTable(tblName string, layout *record.Layout, newSchema *record.Schema,
+	defaults map[string]any, txn *tx.Transaction) (int, error) {
+	indexes, err := up.mdm.GetIndexInfo(tblName, txn)
+	if err != nil {
+		return 0, err
+	}
+	temp := scan_types.NewTempTable(txn, layout.Schema())
+	saved, err := temp.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer saved.Close()
+	ts, err := scan_types.NewTableScan(txn, tblName, layout)
+	if err != nil {
+		return 0, err
+	}
+	err = saveRecords(ts, saved, layout.Schema(), indexes)
+	ts.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	filename := scan_types.TableFileName(tblName)
+	size, err := txn.Size(filename)
+	if err != nil {
+		return 0, err
+	}
+	for blockNumber := 0; blockNumber < size; blockNumber++ {
+		if err := record.ClearBlock(txn, file.NewBlock(filename, blockNumber)); err != nil {
+			return 0, err
+		}
+	}
+	if err := txn.Truncate(filename, 0); err != nil {
+		return 0, err
+	}
+	if err := record.NewFreeSpaceMap(txn, scan_types.FreeSpaceFileName(tblName)).Clear(); err != nil {
+		return 0, err
+	}
+	if err := up.mdm.AlterTable(tblName, newSchema, txn); err != nil {
+		return 0, err
+	}
+	if layout, err = up.mdm.GetLayout(tblName, txn); err != nil {
+		return 0, err
+	}
+	if indexes, err = up.mdm.GetIndexInfo(tblName, txn); err != nil {
+		return 0, err
+	}
+	if ts, err = scan_types.NewTableScan(txn, tblName, layout); err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	if err := saved.BeforeFirst(); err != nil {
+		return 0, err
+	}
+	fields := newSchema.Fields()
+	count := 0
+	for {
+		hasNext, err := saved.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !hasNext {
+			return count, nil
+		}
+		row := make([]any, len(fields))
+		for i, fldName := range fields {
+			if !saved.HasField(fldName) {
+				row[i] = defaults[fldName]
+				continue
+			}
+			if row[i], err = saved.GetVal(fldName); err != nil {
+				return 0, err
+			}
+		}
+		if err := insertRecord(ts, newSchema, fields, row, indexes, nil); err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+// saveRecords
+// copies the records of ts into saved and deletes their index entries,
+// which point at slots that are about to be cleared
+func saveRecords(ts *scan_types.TableScan, saved *scan_types.TableScan, schema *record.Schema,
+	indexes map[string]metadata.IndexInfo) error {
+	for {
+		hasNext, err := ts.Next()
+		if err != nil || !hasNext {
+			return err
+		}
+		if err := saved.Insert(); err != nil {
+			return err
+		}
+		for _, fldName := range schema.Fields() {
+			val, err := ts.GetVal(fldName)
+			if err != nil {
+				return err
+			}
+			if err := saved.SetVal(fldName, val); err != nil {
+				return err
+			}
+			info, indexed := indexes[fldName]
+			if !indexed {
+				continue
+			}
+			idx := info.Open()
+			err = idx.Delete(val, ts.GetRid())
+			idx.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// catalogTables
+// the tables of the catalog, which cannot be dropped
+var catalogTables = map[string]bool{"tblcat": true, "fldcat": true, "viewcat": true, "idxcat": true, "concat": true}
+
+// ExecuteDropTable
+// drops the table and its indexes, their files are removed when the
+// transaction commits. Views reading the table are left as they are, a
+// table referred to by a foreign key of another table cannot be dropped
+func (up *BasicUpdatePlanner) ExecuteDropTable(data *parse.DropTableData, txn *tx.Transaction) (int, error) {
+	if catalogTables[data.TableName()] {
+		return 0, fmt.Errorf("cannot drop catalog table %s", data.TableName())
+	}
+	references, err := up.mdm.GetReferences(data.TableName(), txn)
+	if err != nil {
+		return 0, err
+	}
+	for _, reference := range references {
+		if reference.Table() != data.TableName() {
+			return 0, fmt.Errorf("cannot drop table %s, it is referred to by constraint %s of table %s",
+				data.TableName(), reference.Constraint().Name(), reference.Table())
+		}
+	}
+	if err := up.checkViews(data.TableName(), txn); err != nil {
+		return 0, err
+	}
+	return 0, up.mdm.DropTable(data.TableName(), txn)
+}
+
+// checkViews
+// fails when a view reads the table, it could no longer be queried
+func (up *BasicUpdatePlanner) checkViews(tblName string, txn *tx.Transaction) error {
+	views, err := up.mdm.GetViewNames(txn)
+	if err != nil {
+		return err
+	}
+	for _, view := range views {
+		viewDef, err := up.mdm.GetViewDef(view, txn)
+		if err != nil {
+			return err
+		}
+		parser, err := parse.NewParser(viewDef)
+		if err != nil {
+			return err
+		}
+		viewData, err := parser.Query()
+		if err != nil {
+			return err
+		}
+		if slices.Contains(viewData.ReferredTables(), tblName) {
+			return fmt.Errorf("cannot drop table %s, view %s refers to it", tblName, view)
+		}
+	}
+	return nil
+}
+
+func (up *BasicUpdatePlanner) ExecuteDropView(data *parse.DropViewData, txn *tx.Transaction) (int, error) {
+	return 0, up.mdm.DropView(data.ViewName(), txn)
+}
+
+func (up *BasicUpdatePlanner) ExecuteDropIndex(data *parse.DropIndexData, txn *tx.Transaction) (int, error) {
+	return 0, up.mdm.DropIndex(data.IndexName(), txn)
+}
+
+// ExecuteVacuum
+// rewrites the records of the table, or of every table but the catalog
+// tables, into as few blocks as they need and truncates its file. The
+// records get new rids, so their index entries are written again. Returns
+// the number of records rewritten
+func (up *BasicUpdatePlanner) ExecuteVacuum(data *parse.VacuumData, txn *tx.Transaction) (int, error) {
+	tables := []string{data.TableName()}
+	if data.TableName() == "" {
+		names, err := up.mdm.GetTableNames(txn)
+		if err != nil {
+			return 0, err
+		}
+		tables = slices.DeleteFunc(names, func(name string) bool { return catalogTables[name] })
+	} else if catalogTables[data.TableName()] {
+		return 0, fmt.Errorf("cannot vacuum catalog table %s", data.TableName())
+	}
+	count := 0
+	for _, tblName := range tables {
+		layout, err := up.mdm.GetLayout(tblName, txn)
+		if err != nil {
+			return 0, err
+		}
+		rewritten, err := up.rewriteTable(tblName, layout, layout.Schema(), nil, txn)
+		if err != nil {
+			return 0, err
+		}
+		count += rewritten
+	}
+	return count, nil
+}
+
+// ExecuteScrub
+// checks every block of every file of the database, returns the number of
+// blocks checked and an error naming the corrupted ones
+func (up *BasicUpdatePlanner) ExecuteScrub(data *parse.ScrubData, txn *tx.Transaction) (int, error) {
+	return txn.Scrub()
+}
+
+// checkFields
+// every field has to be a field of the table, and appear only once
+func checkFields(tblName string, schema *record.Schema, fields []string) error {
+	seen := make(map[string]bool)
+	for _, fldName := range fields {
+		if !schema.HasField(fldName) {
+			return fmt.Errorf("table %s has no field %s", tblName, fldName)
+		}
+		if seen[fldName] {
+			return fmt.Errorf("field %s appears more than once", fldName)
+		}
+		seen[fldName] = true
+	}
+	return nil
+}
+
+// convertValue
+// val as the go type of the field, an int of a double field becomes a
+// float64. An int has to fit in 32 bits, a date has no time of day and a
+// string or a blob has to fit in the field
+func convertValue(schema *record.Schema, fldName string, val any) (any, error) {
+	fldType := schema.Type(fldName)
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		switch fldType {
+		case record.INTEGER:
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("%d is out of range for int field %s", v, fldName)
+			}
+			return v, nil
+		case record.BIGINT:
+			return v, nil
+		case record.DOUBLE:
+			return float64(v), nil
+		}
+	case float64:
+		if fldType == record.DOUBLE {
+			return v, nil
+		}
+	case bool:
+		if fldType == record.BOOLEAN {
+			return v, nil
+		}
+	case time.Time:
+		switch fldType {
+		case record.DATE:
+			if !v.Equal(v.Truncate(24 * time.Hour)) {
+				return nil, fmt.Errorf("date field %s cannot have a time of day, got %s", fldName, v)
+			}
+			return v, nil
+		case record.TIMESTAMP:
+			return v, nil
+		}
+	case string:
+		if fldType != record.VARCHAR {
+			break
+		}
+		if file.MaxLength(utf8.RuneCountInString(v)) > schema.Length(fldName) {
+			return nil, fmt.Errorf("'%s' is too long for field %s", v, fldName)
+		}
+		return v, nil
+	case record.Blob:
+		if fldType != record.BLOB {
+			break
+		}
+		if constants.IntSize+len(v) > schema.Length(fldName) {
+			return nil, fmt.Errorf("blob of %d bytes is too long for field %s", len(v), fldName)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v for field %s", val, fldName)
+	}
+	return nil, fmt.Errorf("field %s is %s, got %s", fldName, record.TypeName(fldType), formatValue(val))
+}
+
+// assignable
+// true when the values of a column of type from can be stored in a field of
+// type to
+func assignable(from int, to int) bool {
+	switch {
+	case from == to:
+		return true
+	case from == record.INTEGER || from == record.BIGINT:
+		return to == record.INTEGER || to == record.BIGINT || to == record.DOUBLE
+	case from == record.DATE:
+		return to == record.TIMESTAMP
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/tx"
+	"slices"
+)
+
+// commonTable
+// a table defined by a with clause. Every common table links to the one
+// defined before it, so a query sees the tables of its own with clause and
+// of the with clauses of the queries it is nested in, the latest first
+type commonTable struct {
+	data      *parse.CommonTableData
+	recursive bool
+	prev      *commonTable
+	//set while the table is planned, work while its recursive part is
+	planning bool
+	work     plan.Plan
+}
+
+// lookup
+// the common table called name visible from c, nil when there is none
+func (c *commonTable) lookup(name string) *commonTable {
+	for table := c; table != nil; table = table.prev {
+		if table.data.Name() == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// visible
+// the common tables the query of c can refer to, those defined before it
+// and with recursive also c itself
+func (c *commonTable) visible() *commonTable {
+	if c.recursive {
+		return c
+	}
+	return c.prev
+}
+
+// commonTablePlan
+// a common table is planned again wherever it is referred to. Inside the
+// recursive part of its own query it is the work table of the recursion
+func (qp *BasicQueryPlanner) commonTablePlan(table *commonTable, txn *tx.Transaction) (plan.Plan, error) {
+	if table.work != nil {
+		return table.work, nil
+	}
+	if table.planning {
+		return nil, fmt.Errorf("common table %s can only refer to itself in the from clause of a union operand",
+			table.data.Name())
+	}
+	table.planning = true
+	defer func() { table.planning = false }()
+
+	body := table.data.Query()
+	if table.recursive && slices.ContainsFunc(operands(body), table.refersToItself) {
+		return qp.recursivePlan(table, txn)
+	}
+	scope := newScope()
+	scope.with = table.prev
+	p, err := qp.createPlan(body, txn, scope)
+	if err != nil {
+		return nil, err
+	}
+	return table.columnsPlan(p)
+}
+
+// recursivePlan
+// the operands of the union not referring to the table are the anchor, the
+// others are evaluated repeatedly on the records found the previous time
+// until no new records are found
+func (qp *BasicQueryPlanner) recursivePlan(table *commonTable, txn *tx.Transaction) (plan.Plan, error) {
+	body := table.data.Query()
+	name := table.data.Name()
+	if len(body.OrderBy()) > 0 || body.Limit() >= 0 || body.Offset() > 0 {
+		return nil, fmt.Errorf("recursive common table %s can not have order by or limit", name)
+	}
+	distinct := false
+	for _, setOp := range body.SetOperations() {
+		if setOp.Operator() != query.Union {
+			return nil, fmt.Errorf("recursive common table %s can only combine its selects with union, not %s",
+				name, setOp.Operator())
+		}
+		distinct = distinct || !setOp.IsAll()
+	}
+	anchors := make([]*parse.QueryData, 0)
+	recursives := make([]*parse.QueryData, 0)
+	for _, operand := range operands(body) {
+		if table.refersToItself(operand) {
+			recursives = append(recursives, operand)
+		} else {
+			anchors = append(anchors, operand)
+		}
+	}
+	if len(anchors) == 0 {
+		return nil, fmt.Errorf("recursive common table %s needs a select that does not refer to itself", name)
+	}
+
+	//the with clause of the query itself applies to all its selects
+	with := table.visible()
+	for _, inner := range body.With() {
+		with = &commonTable{data: inner, recursive: body.IsRecursive(), prev: with}
+	}
+	anchor, err := qp.unionAllPlan(table, anchors, with, nil, txn)
+	if err != nil {
+		return nil, err
+	}
+	work := plan_types.NewWorkTable()
+	table.work = plan_types.NewWorkTablePlan(work, anchor.Schema(), anchor.RecordsOutput())
+	defer func() { table.work = nil }()
+	recursive, err := qp.unionAllPlan(table, recursives, with, anchor, txn)
+	if err != nil {
+		return nil, err
+	}
+	return plan_types.NewRecursivePlan(txn, anchor, recursive, work, distinct), nil
+}
+
+// unionAllPlan
+// the records of all selects, with the columns of the table. The selects
+// see the common tables with, their columns have to be compatible with
+// those of first unless it is nil
+func (qp *BasicQueryPlanner) unionAllPlan(table *commonTable, selects []*parse.QueryData,
+	with *commonTable, first plan.Plan, txn *tx.Transaction) (plan.Plan, error) {
+	var result plan.Plan
+	for _, data := range selects {
+		scope := newScope()
+		scope.with = with
+		p, err := qp.selectPlan(data, txn, scope, false)
+		if err != nil {
+			return nil, err
+		}
+		if p, err = table.columnsPlan(p); err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = p
+		}
+		if !first.Schema().IsCompatible(p.Schema()) {
+			return nil, fmt.Errorf("union operands of common table %s have incompatible columns, %v and %v",
+				table.data.Name(), first.Schema().Fields(), p.Schema().Fields())
+		}
+		p = plan_types.NewRenamePlan(p, first.Schema().Fields(), p.Schema().Fields())
+		if result == nil {
+			result = p
+		} else {
+			result = plan_types.NewConcatPlan(result, p)
+		}
+	}
+	return result, nil
+}
+
+// columnsPlan
+// names the fields of p after the column list of the table, if it has one
+func (c *commonTable) columnsPlan(p plan.Plan) (plan.Plan, error) {
+	columns := c.data.Columns()
+	fields := p.Schema().Fields()
+	if len(columns) == 0 {
+		return p, nil
+	}
+	if len(columns) != len(fields) {
+		return nil, fmt.Errorf("common table %s has %d columns but its query returns %d",
+			c.data.Name(), len(columns), len(fields))
+	}
+	return plan_types.NewRenamePlan(p, columns, fields), nil
+}
+
+// refersToItself
+// true when the table is in the from clause of data
+func (c *commonTable) refersToItself(data *parse.QueryData) bool {
+	return slices.Contains(data.Tables(), c.data.Name())
+}
+
+// operands
+// the selects combined by the set operations of data
+func operands(data *parse.QueryData) []*parse.QueryData {
+	result := []*parse.QueryData{data}
+	for _, setOp := range data.SetOperations() {
+		result = append(result, setOp.Query())
+	}
+	return result
+}
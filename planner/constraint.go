@@ -0,0 +1,360 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/metadata"
+	"jadb/parse"
+	"jadb/query"
+	"jadb/record"
+	"jadb/scan_types"
+	"jadb/tx"
+	"slices"
+	"strings"
+)
+
+// ConstraintViolation
+// the error of a statement that would leave a record breaking a constraint
+// of its table. Values are the values the record would have for the fields
+// of the constraint. When Referenced is true a record of the table the
+// foreign key refers to is deleted or changed, and Values are its values
+// for the fields referred to
+type ConstraintViolation struct {
+	Table      string
+	Constraint *record.Constraint
+	Values     []any
+	Referenced bool
+}
+
+func (c *ConstraintViolation) Error() string {
+	fields := c.Constraint.Fields()
+	values := make([]string, len(c.Values))
+	for i, val := range c.Values {
+		values[i] = formatValue(val)
+	}
+	if c.Referenced {
+		return fmt.Sprintf("record of table %s with (%s) for (%s) is still referenced by %s constraint %s of table %s",
+			c.Constraint.RefTable(), strings.Join(values, ", "), strings.Join(c.Constraint.RefFields(), ", "),
+			c.Constraint.Type(), c.Constraint.Name(), c.Table)
+	}
+	if c.Constraint.Type() == record.Check {
+		return fmt.Sprintf("value (%s) for (%s) violates %s constraint %s (%s) of table %s",
+			strings.Join(values, ", "), strings.Join(fields, ", "), c.Constraint.Type(), c.Constraint.Name(),
+			c.Constraint.Definition(), c.Table)
+	}
+	if c.Constraint.Type() == record.ForeignKey {
+		return fmt.Sprintf("value (%s) for (%s) violates %s constraint %s of table %s, table %s has no such record",
+			strings.Join(values, ", "), strings.Join(fields, ", "), c.Constraint.Type(), c.Constraint.Name(),
+			c.Table, c.Constraint.RefTable())
+	}
+	for i, val := range c.Values {
+		if val == nil && c.Constraint.IsNotNull() {
+			return fmt.Sprintf("null value for field %s violates %s constraint %s of table %s",
+				fields[i], c.Constraint.Type(), c.Constraint.Name(), c.Table)
+		}
+	}
+	return fmt.Sprintf("duplicate value (%s) for (%s) violates %s constraint %s of table %s",
+		strings.Join(values, ", "), strings.Join(fields, ", "), c.Constraint.Type(), c.Constraint.Name(), c.Table)
+}
+
+func formatValue(val any) string {
+	return query.NewConstantExpression(val).String()
+}
+
+// constraintChecker
+// checks the records written to a table against its constraints. A unique
+// constraint looks for duplicates and a foreign key for the record it
+// refers to through the index on the first field when there is one. The
+// conditions of the checks read the values of a record from params, and
+// defaults has the values of the fields with a default
+type constraintChecker struct {
+	mdm         *metadata.MetadataManager
+	tblName     string
+	layout      *record.Layout
+	constraints []*record.Constraint
+	indexes     map[string]metadata.IndexInfo
+	txn         *tx.Transaction
+	params      *query.Params
+	checks      map[string]*query.Predicate
+	defaults    map[string]any
+}
+
+func newConstraintChecker(mdm *metadata.MetadataManager, tblName string, layout *record.Layout,
+	indexes map[string]metadata.IndexInfo, txn *tx.Transaction) (*constraintChecker, error) {
+	constraints, err := mdm.GetConstraints(tblName, txn)
+	if err != nil {
+		return nil, err
+	}
+	params := query.NewParams()
+	checks := make(map[string]*query.Predicate)
+	defaults := make(map[string]any)
+	for _, constraint := range constraints {
+		switch constraint.Type() {
+		case record.Check:
+			pred, err := parseCheck(constraint.Definition())
+			if err != nil {
+				return nil, err
+			}
+			if checks[constraint.Name()], err = pred.ResolveFields(func(fldName string) (*query.Expression, error) {
+				return query.NewParamExpression(fldName, params), nil
+			}); err != nil {
+				return nil, err
+			}
+		case record.Default:
+			if defaults[constraint.Fields()[0]], err = parseDefault(constraint.Definition()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &constraintChecker{mdm, tblName, layout, constraints, indexes, txn, params, checks, defaults}, nil
+}
+
+// check
+// vals are the values of every field of the record, rid is where the
+// record is stored or nil when it is being inserted. Only the constraints
+// on a changed field are checked, all of them when changed is nil
+func (c *constraintChecker) check(vals map[string]any, rid *record.RID, changed []string) error {
+	for _, constraint := range c.constraints {
+		if changed != nil && !slices.ContainsFunc(constraint.Fields(), func(fldName string) bool {
+			return slices.Contains(changed, fldName)
+		}) {
+			continue
+		}
+		values := make([]any, len(constraint.Fields()))
+		hasNull := false
+		for i, fldName := range constraint.Fields() {
+			values[i] = vals[fldName]
+			hasNull = hasNull || values[i] == nil
+		}
+		violation := &ConstraintViolation{c.tblName, constraint, values, false}
+		if constraint.Type() == record.Check {
+			// a condition that is unknown for nulls still holds
+			for _, fldName := range constraint.Fields() {
+				c.params.Set(fldName, vals[fldName])
+			}
+			truth, err := c.checks[constraint.Name()].Evaluate(nil)
+			if err != nil {
+				return err
+			}
+			if truth == query.False {
+				return violation
+			}
+			continue
+		}
+		if hasNull && constraint.IsNotNull() {
+			return violation
+		}
+		if hasNull {
+			continue
+		}
+		var found bool
+		var err error
+		switch {
+		case constraint.IsUnique():
+			found, err = findRecord(c.txn, c.tblName, c.layout, c.indexes, constraint.Fields(), values, rid)
+			found = !found
+		case constraint.Type() == record.ForeignKey:
+			found, err = c.hasReferenced(constraint, values, vals)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !found {
+			return violation
+		}
+	}
+	return nil
+}
+
+// hasReferenced
+// true when the table the foreign key refers to has a record with values,
+// which is the record itself when it refers to its own fields
+func (c *constraintChecker) hasReferenced(constraint *record.Constraint, values []any, vals map[string]any) (bool, error) {
+	if constraint.RefTable() == c.tblName && slices.Equal(values, valuesOf(vals, constraint.RefFields())) {
+		return true, nil
+	}
+	layout, err := c.mdm.GetLayout(constraint.RefTable(), c.txn)
+	if err != nil {
+		return false, err
+	}
+	indexes, err := c.mdm.GetIndexInfo(constraint.RefTable(), c.txn)
+	if err != nil {
+		return false, err
+	}
+	return findRecord(c.txn, constraint.RefTable(), layout, indexes, constraint.RefFields(), values, nil)
+}
+
+// findRecord
+// true when a record of the table other than the one at rid has values for
+// fields, found through the index on the first field when there is one.
+// Without a rid any record counts
+func findRecord(txn *tx.Transaction, tblName string, layout *record.Layout, indexes map[string]metadata.IndexInfo,
+	fields []string, values []any, rid *record.RID) (bool, error) {
+	found := false
+	err := findRecords(txn, tblName, layout, indexes, fields, values, func(ts *scan_types.TableScan) (bool, error) {
+		found = rid == nil || !ts.GetRid().Equals(rid)
+		return found, nil
+	})
+	return found, err
+}
+
+// findRecords
+// calls visit with ts at every record of the table that has values for
+// fields until visit returns true. The records are found through the
+// index on the first field when there is one
+func findRecords(txn *tx.Transaction, tblName string, layout *record.Layout, indexes map[string]metadata.IndexInfo,
+	fields []string, values []any, visit func(ts *scan_types.TableScan) (bool, error)) error {
+	ts, err := scan_types.NewTableScan(txn, tblName, layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	matches := func() (bool, error) {
+		for i, fldName := range fields {
+			val, err := ts.GetVal(fldName)
+			if err != nil || val != values[i] {
+				return false, err
+			}
+		}
+		return visit(ts)
+	}
+
+	info, indexed := indexes[fields[0]]
+	if !indexed {
+		for {
+			hasNext, err := ts.Next()
+			if err != nil || !hasNext {
+				return err
+			}
+			if done, err := matches(); done || err != nil {
+				return err
+			}
+		}
+	}
+	idx := info.Open()
+	defer idx.Close()
+	if err := idx.BeforeFirst(values[0]); err != nil {
+		return err
+	}
+	for {
+		hasNext, err := idx.Next()
+		if err != nil || !hasNext {
+			return err
+		}
+		dataRid, err := idx.GetDataRid()
+		if err != nil {
+			return err
+		}
+		if err := ts.MoveToRid(dataRid); err != nil {
+			return err
+		}
+		if done, err := matches(); done || err != nil {
+			return err
+		}
+	}
+}
+
+// valuesOf
+// the values of fields in vals
+func valuesOf(vals map[string]any, fields []string) []any {
+	values := make([]any, len(fields))
+	for i, fldName := range fields {
+		values[i] = vals[fldName]
+	}
+	return values
+}
+
+// parseCheck
+// the condition of the definition of a check
+func parseCheck(definition string) (*query.Predicate, error) {
+	parser, err := parse.NewParser(definition)
+	if err != nil {
+		return nil, err
+	}
+	return parser.CheckCondition()
+}
+
+// parseDefault
+// the value of the definition of a default
+func parseDefault(definition string) (any, error) {
+	parser, err := parse.NewParser(definition)
+	if err != nil {
+		return nil, err
+	}
+	return parser.DefaultValue()
+}
+
+// resolveCheck
+// the check with the fields its condition refers to, which all have to be
+// fields of the table. A condition cannot have a subquery, as it only sees
+// the record being checked
+func resolveCheck(tblName string, schema *record.Schema, check *record.Constraint) (*record.Constraint, error) {
+	pred, err := parseCheck(check.Definition())
+	if err != nil {
+		return nil, err
+	}
+	if len(pred.Subqueries()) > 0 {
+		return nil, fmt.Errorf("check (%s) cannot have a subquery", check.Definition())
+	}
+	var fields []string
+	if _, err := pred.ResolveFields(func(fldName string) (*query.Expression, error) {
+		if !schema.HasField(fldName) {
+			return nil, fmt.Errorf("table %s has no field %s", tblName, fldName)
+		}
+		if !slices.Contains(fields, fldName) {
+			fields = append(fields, fldName)
+		}
+		return query.NewFieldExpression(fldName), nil
+	}); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("check (%s) refers to no field of table %s", check.Definition(), tblName)
+	}
+	return record.NewCheck(check.Name(), fields, check.Definition()), nil
+}
+
+// renameCheck
+// the check with the field renamed in its fields and its condition
+func renameCheck(check *record.Constraint, oldName string, newName string) (*record.Constraint, error) {
+	pred, err := parseCheck(check.Definition())
+	if err != nil {
+		return nil, err
+	}
+	renamed, err := pred.ResolveFields(func(fldName string) (*query.Expression, error) {
+		if fldName == oldName {
+			fldName = newName
+		}
+		return query.NewFieldExpression(fldName), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(check.Fields()))
+	for i, fldName := range check.Fields() {
+		fields[i] = fldName
+		if fldName == oldName {
+			fields[i] = newName
+		}
+	}
+	return record.NewCheck(check.Name(), fields, renamed.String()), nil
+}
+
+// constraintName
+// the name a constraint declared without one gets
+func constraintName(tblName string, constraint *record.Constraint) string {
+	switch constraint.Type() {
+	case record.PrimaryKey:
+		return tblName + "_pkey"
+	case record.NotNull:
+		return tblName + "_" + constraint.Fields()[0] + "_not_null"
+	case record.ForeignKey:
+		return tblName + "_" + strings.Join(constraint.Fields(), "_") + "_fkey"
+	case record.Check:
+		return tblName + "_" + strings.Join(constraint.Fields(), "_") + "_check"
+	case record.Default:
+		return tblName + "_" + constraint.Fields()[0] + "_default"
+	}
+	return tblName + "_" + strings.Join(constraint.Fields(), "_") + "_key"
+}
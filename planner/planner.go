@@ -0,0 +1,93 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/tx"
+)
+
+type Planner struct {
+	qp QueryPlanner
+	up UpdatePlanner
+}
+
+func NewPlanner(qp QueryPlanner, up UpdatePlanner) *Planner {
+	return &Planner{qp, up}
+}
+
+func (planner *Planner) CreateQueryPlan(sql string, txn *tx.Transaction) (plan.Plan, error) {
+	parser, err := parse.NewParser(sql)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parser.Query()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.End(); err != nil {
+		return nil, err
+	}
+	return planner.qp.CreatePlan(data, txn)
+}
+
+// ExecuteUpdate
+// executes an insert, delete, update, create, alter, drop, vacuum or scrub statement
+// and returns the number of records it affected, or blocks it checked
+func (planner *Planner) ExecuteUpdate(sql string, txn *tx.Transaction) (int, error) {
+	parser, err := parse.NewParser(sql)
+	if err != nil {
+		return 0, err
+	}
+	cmd, err := parser.UpdateCmd()
+	if err != nil {
+		return 0, err
+	}
+	if err := parser.End(); err != nil {
+		return 0, err
+	}
+	switch data := cmd.(type) {
+	case *parse.InsertData:
+		return atomically(txn, func() (int, error) { return planner.up.ExecuteInsert(data, txn) })
+	case *parse.DeleteData:
+		return atomically(txn, func() (int, error) { return planner.up.ExecuteDelete(data, txn) })
+	case *parse.ModifyData:
+		return atomically(txn, func() (int, error) { return planner.up.ExecuteModify(data, txn) })
+	case *parse.CreateTableData:
+		return planner.up.ExecuteCreateTable(data, txn)
+	case *parse.CreateViewData:
+		return planner.up.ExecuteCreateView(data, txn)
+	case *parse.CreateIndexData:
+		return planner.up.ExecuteCreateIndex(data, txn)
+	case *parse.AlterTableData:
+		return planner.up.ExecuteAlterTable(data, txn)
+	case *parse.DropTableData:
+		return planner.up.ExecuteDropTable(data, txn)
+	case *parse.DropViewData:
+		return planner.up.ExecuteDropView(data, txn)
+	case *parse.DropIndexData:
+		return planner.up.ExecuteDropIndex(data, txn)
+	case *parse.VacuumData:
+		return planner.up.ExecuteVacuum(data, txn)
+	case *parse.ScrubData:
+		return planner.up.ExecuteScrub(data, txn)
+	}
+	return 0, fmt.Errorf("unsupported statement %s", sql)
+}
+
+// atomically
+// executes a statement changing records, undoing the changes it made when
+// it fails part way, a constraint violated by its last record for instance,
+// so the transaction can go on without them
+func atomically(txn *tx.Transaction, execute func() (int, error)) (int, error) {
+	savepoint := txn.Savepoint()
+	count, err := execute()
+	if err == nil {
+		return count, nil
+	}
+	if undoErr := txn.RollbackTo(savepoint); undoErr != nil {
+		return 0, errors.Join(err, undoErr)
+	}
+	return 0, err
+}
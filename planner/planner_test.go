@@ -0,0 +1,1673 @@
+package planner
+
+import (
+	"fmt"
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/buffer"
+	"jadb/concurrency"
+	"jadb/file"
+	"jadb/log"
+	"jadb/metadata"
+	"jadb/parse"
+	"jadb/record"
+	"jadb/scan_types"
+	"jadb/tx"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestEnv struct {
+	fm      *file.Manager
+	lm      *log.Manager
+	bm      *buffer.Manager
+	lt      *concurrency.LockTable
+	mdm     *metadata.MetadataManager
+	tempDir string
+}
+
+func initEnv(assert *assertPkg.Assertions) TestEnv {
+	blockSize := 4096
+	logFile := "test.log"
+	tempDir := filepath.Join(os.TempDir(), "planner_test")
+	assert.NoError(os.RemoveAll(tempDir))
+	fm, err := file.NewFileManager(tempDir, blockSize)
+	assert.NoError(err)
+	lm, err := log.NewLogManager(fm, logFile)
+	assert.NoError(err)
+	bm, err := buffer.NewBufferManager(fm, lm, 100)
+	assert.NoError(err)
+	lt := concurrency.NewLockTable()
+
+	txn, err := tx.NewTransaction(fm, lm, bm, lt)
+	assert.NoError(err)
+	tblMgr, err := metadata.NewTableManager(true, txn)
+	assert.NoError(err)
+	statMgr, err := metadata.NewStatManager(tblMgr, txn)
+	assert.NoError(err)
+	idxMgr, err := metadata.NewIndexManager(true, tblMgr, statMgr, txn)
+	assert.NoError(err)
+	viewMgr, err := metadata.NewViewManager(true, tblMgr, txn)
+	assert.NoError(err)
+	conMgr, err := metadata.NewConstraintManager(true, tblMgr, txn)
+	assert.NoError(err)
+	assert.NoError(txn.Commit())
+	return TestEnv{
+		fm, lm, bm, lt,
+		metadata.NewMetadataManager(tblMgr, statMgr, idxMgr, viewMgr, conMgr),
+		tempDir,
+	}
+}
+
+func clearEnv(t *testing.T, env TestEnv) {
+	if err := os.RemoveAll(env.tempDir); err != nil {
+		t.Error(err)
+	}
+}
+
+// createEmployees
+// emp(id int, name varchar(10), dept int) where dept cycles through deptCount values
+func createEmployees(assert *assertPkg.Assertions, env TestEnv, txn *tx.Transaction, count int, deptCount int) {
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("name", 10)
+	schema.AddIntField("dept")
+	assert.NoError(env.mdm.CreateTable("emp", schema, txn))
+	layout, err := env.mdm.GetLayout("emp", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "emp", layout)
+	assert.NoError(err)
+	for i := 0; i < count; i++ {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+		assert.NoError(ts.SetString("name", fmt.Sprintf("emp%d", i)))
+		assert.NoError(ts.SetInt("dept", i%deptCount))
+	}
+	ts.Close()
+}
+
+func TestSelectDistinct(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	createEmployees(assert, env, txn, 100, 7)
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+
+	p, err := planner.CreateQueryPlan("select distinct dept from emp", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"dept"}, p.Schema().Fields())
+	assert.LessOrEqual(p.RecordsOutput(), 100)
+	s, err := p.Open()
+	assert.NoError(err)
+	depts := make(map[int]bool)
+	for hasNext, err := s.Next(); hasNext; hasNext, err = s.Next() {
+		assert.NoError(err)
+		dept, err := s.GetInt("dept")
+		assert.NoError(err)
+		assert.False(depts[dept], "dept %d returned twice", dept)
+		depts[dept] = true
+	}
+	s.Close()
+	assert.Equal(7, len(depts))
+
+	//without distinct every row is returned
+	p, err = planner.CreateQueryPlan("select dept from emp where dept = 3", txn)
+	assert.NoError(err)
+	s, err = p.Open()
+	assert.NoError(err)
+	count := 0
+	for hasNext, err := s.Next(); hasNext; hasNext, err = s.Next() {
+		assert.NoError(err)
+		count++
+	}
+	s.Close()
+	assert.Equal(14, count)
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestSelectDistinctFromView(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	createEmployees(assert, env, txn, 30, 3)
+	assert.NoError(env.mdm.CreateView("depts", "select distinct dept from emp", txn))
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	p, err := planner.CreateQueryPlan("select dept from depts", txn)
+	assert.NoError(err)
+	s, err := p.Open()
+	assert.NoError(err)
+	count := 0
+	for hasNext, err := s.Next(); hasNext; hasNext, err = s.Next() {
+		assert.NoError(err)
+		count++
+	}
+	s.Close()
+	assert.Equal(3, count)
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestOrderByLimit(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddIntField("score")
+	assert.NoError(env.mdm.CreateTable("scores", schema, txn))
+	layout, err := env.mdm.GetLayout("scores", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "scores", layout)
+	assert.NoError(err)
+	//scores out of order so the sort has several runs to merge
+	testRecordCount := 300
+	for i := 0; i < testRecordCount; i++ {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+		assert.NoError(ts.SetInt("score", (i*71)%testRecordCount))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	readScores := func(sql string) []int {
+		p, err := planner.CreateQueryPlan(sql, txn)
+		assert.NoError(err)
+		s, err := p.Open()
+		assert.NoError(err)
+		scores := make([]int, 0)
+		for hasNext, err := s.Next(); hasNext; hasNext, err = s.Next() {
+			assert.NoError(err)
+			score, err := s.GetInt("score")
+			assert.NoError(err)
+			scores = append(scores, score)
+		}
+		s.Close()
+		return scores
+	}
+
+	//full external sort
+	scores := readScores("select score from scores order by score")
+	assert.Equal(testRecordCount, len(scores))
+	for i, score := range scores {
+		assert.Equal(i, score)
+	}
+
+	//bounded top n
+	p, err := planner.CreateQueryPlan("select score from scores order by score desc limit 5 offset 10", txn)
+	assert.NoError(err)
+	assert.Equal(5, p.RecordsOutput())
+	assert.Equal([]int{289, 288, 287, 286, 285}, readScores("select score from scores order by score desc limit 5 offset 10"))
+
+	//limit without ordering
+	assert.Equal(7, len(readScores("select score from scores limit 7")))
+	assert.Equal(0, len(readScores("select score from scores limit 0")))
+
+	_, err = planner.CreateQueryPlan("select score from scores order by unknown", txn)
+	assert.Error(err)
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func readRows(assert *assertPkg.Assertions, planner *Planner, sql string, txn *tx.Transaction) []map[string]any {
+	p, err := planner.CreateQueryPlan(sql, txn)
+	if !assert.NoError(err) {
+		return nil
+	}
+	s, err := p.Open()
+	assert.NoError(err)
+	rows := make([]map[string]any, 0)
+	for hasNext, err := s.Next(); hasNext; hasNext, err = s.Next() {
+		assert.NoError(err)
+		row := make(map[string]any)
+		for _, fldName := range p.Schema().Fields() {
+			val, err := s.GetVal(fldName)
+			assert.NoError(err)
+			row[fldName] = val
+		}
+		rows = append(rows, row)
+	}
+	s.Close()
+	return rows
+}
+
+func TestQualifiedNamesAndAliases(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	createEmployees(assert, env, txn, 6, 2)
+
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("name", 10)
+	assert.NoError(env.mdm.CreateTable("dept", schema, txn))
+	layout, err := env.mdm.GetLayout("dept", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "dept", layout)
+	assert.NoError(err)
+	for i, name := range []string{"sales", "tech"} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+		assert.NoError(ts.SetString("name", name))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+
+	//both tables have id and name, qualified names pick the right one
+	rows := readRows(assert, planner,
+		"select e.name, d.name as dept_name from emp e, dept d where e.dept = d.id and d.name = 'tech'", txn)
+	assert.Equal(3, len(rows))
+	for _, row := range rows {
+		assert.Equal("tech", row["dept_name"])
+		assert.Contains([]string{"emp1", "emp3", "emp5"}, row["name"])
+	}
+
+	//* outputs every column, shared names are qualified
+	p, err := planner.CreateQueryPlan("select * from emp, dept", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"emp.id", "emp.name", "dept", "dept.id", "dept.name"}, p.Schema().Fields())
+	assert.Equal(12, len(readRows(assert, planner, "select * from emp, dept", txn)))
+
+	p, err = planner.CreateQueryPlan("select d.* from emp, dept d where emp.dept = d.id", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"id", "name"}, p.Schema().Fields())
+
+	//self join through aliases
+	rows = readRows(assert, planner,
+		"select a.id as first, b.id as second from emp a, emp b where a.dept = b.dept and a.id = 0 order by second", txn)
+	assert.Equal([]map[string]any{
+		{"first": 0, "second": 0},
+		{"first": 0, "second": 2},
+		{"first": 0, "second": 4},
+	}, rows)
+
+	//ambiguity and unknown names are reported at plan time
+	_, err = planner.CreateQueryPlan("select name from emp, dept", txn)
+	assert.ErrorContains(err, "ambiguous")
+	_, err = planner.CreateQueryPlan("select id from emp, dept where emp.id = 1", txn)
+	assert.ErrorContains(err, "ambiguous")
+	_, err = planner.CreateQueryPlan("select x.id from emp e", txn)
+	assert.ErrorContains(err, "unknown table")
+	_, err = planner.CreateQueryPlan("select e.salary from emp e", txn)
+	assert.ErrorContains(err, "unknown field")
+	_, err = planner.CreateQueryPlan("select id from emp, emp", txn)
+	assert.Error(err)
+
+	//a name taken by an earlier column is replaced by the position
+	p, err = planner.CreateQueryPlan("select id as x, dept as x from emp", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"x", "column2"}, p.Schema().Fields())
+	rows = readRows(assert, planner, "select id, name, id from emp where id = 1", txn)
+	assert.Equal([]map[string]any{{"id": 1, "name": "emp1", "column3": 1}}, rows)
+	p, err = planner.CreateQueryPlan("select d.id, e.id, d.id from emp e, dept d", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"d.id", "e.id", "column3"}, p.Schema().Fields())
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestJoins(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	//employees in departments 0, 1 and 2
+	createEmployees(assert, env, txn, 6, 3)
+
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("title", 10)
+	assert.NoError(env.mdm.CreateTable("dept", schema, txn))
+	layout, err := env.mdm.GetLayout("dept", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "dept", layout)
+	assert.NoError(err)
+	for id, title := range map[int]string{0: "sales", 1: "tech", 3: "ops"} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", id))
+		assert.NoError(ts.SetString("title", title))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	titles := func(rows []map[string]any) map[any]int {
+		counts := make(map[any]int)
+		for _, row := range rows {
+			counts[row["title"]]++
+		}
+		return counts
+	}
+
+	rows := readRows(assert, planner, "select name, title from emp e join dept d on e.dept = d.id", txn)
+	assert.Equal(map[any]int{"sales": 2, "tech": 2}, titles(rows))
+
+	rows = readRows(assert, planner, "select name, title from emp e left join dept d on e.dept = d.id", txn)
+	assert.Equal(map[any]int{"sales": 2, "tech": 2, nil: 2}, titles(rows))
+	for _, row := range rows {
+		if row["title"] == nil {
+			assert.Contains([]any{"emp2", "emp5"}, row["name"])
+		}
+	}
+
+	rows = readRows(assert, planner, "select name, title from emp e right outer join dept d on e.dept = d.id", txn)
+	assert.Equal(map[any]int{"sales": 2, "tech": 2, "ops": 1}, titles(rows))
+	for _, row := range rows {
+		if row["title"] == "ops" {
+			assert.Nil(row["name"])
+		}
+	}
+
+	rows = readRows(assert, planner, "select name, title from emp e full join dept d on e.dept = d.id", txn)
+	assert.Equal(map[any]int{"sales": 2, "tech": 2, "ops": 1, nil: 2}, titles(rows))
+
+	//the where clause applies after the join
+	rows = readRows(assert, planner,
+		"select name from emp e left join dept d on e.dept = d.id where title = 'tech'", txn)
+	assert.Equal(2, len(rows))
+
+	//joins and comma separated tables mix, on only sees its own group
+	rows = readRows(assert, planner,
+		"select e.id, x.id from emp e join dept d on e.dept = d.id, emp x where x.id = e.id", txn)
+	assert.Equal(4, len(rows))
+	_, err = planner.CreateQueryPlan("select e.id from emp x, emp e join dept d on x.dept = d.id", txn)
+	assert.ErrorContains(err, "unknown table")
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestNulls(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddIntField("manager")
+	assert.NoError(env.mdm.CreateTable("staff", schema, txn))
+	layout, err := env.mdm.GetLayout("staff", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "staff", layout)
+	assert.NoError(err)
+	//staff 0 has no manager, the others report to 0 or 1
+	for i := 0; i < 5; i++ {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+		if i > 0 {
+			assert.NoError(ts.SetVal("manager", (i-1)%2))
+		}
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	assert.Equal([]map[string]any{{"id": 0, "manager": nil}},
+		readRows(assert, planner, "select id, manager from staff where manager is null", txn))
+	assert.Equal(4, len(readRows(assert, planner, "select id from staff where manager is not null", txn)))
+
+	//comparisons with null are unknown, never true
+	assert.Equal(0, len(readRows(assert, planner, "select id from staff where manager = null", txn)))
+	assert.Equal(2, len(readRows(assert, planner, "select id from staff where manager != 0", txn)))
+	assert.Equal(2, len(readRows(assert, planner, "select id from staff where manager < 1", txn)))
+
+	//nulls sort first and survive materialisation into a temp table
+	rows := readRows(assert, planner, "select id, manager from staff order by manager, id", txn)
+	assert.Equal([]map[string]any{
+		{"id": 0, "manager": nil},
+		{"id": 1, "manager": 0},
+		{"id": 3, "manager": 0},
+		{"id": 2, "manager": 1},
+		{"id": 4, "manager": 1},
+	}, rows)
+
+	//outer join padding can be tested with is null
+	rows = readRows(assert, planner,
+		"select s.id from staff s left join staff m on s.manager = m.id where m.id is null", txn)
+	assert.Equal([]map[string]any{{"id": 0}}, rows)
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestSubqueries(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	//employees 0-5 in departments 0, 1 and 2
+	createEmployees(assert, env, txn, 6, 3)
+
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("title", 10)
+	assert.NoError(env.mdm.CreateTable("dept", schema, txn))
+	layout, err := env.mdm.GetLayout("dept", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "dept", layout)
+	assert.NoError(err)
+	for id, title := range map[int]string{0: "sales", 1: "tech", 3: "ops"} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", id))
+		assert.NoError(ts.SetString("title", title))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	ids := func(sql string) []any {
+		rows := readRows(assert, planner, sql, txn)
+		result := make([]any, len(rows))
+		for i, row := range rows {
+			result[i] = row["id"]
+		}
+		return result
+	}
+
+	//in lists and uncorrelated subqueries
+	assert.Equal([]any{1, 3, 5}, ids("select id from emp where id in (1, 3, 5, 7) order by id"))
+	assert.Equal([]any{0, 1, 3, 4}, ids("select id from emp where dept in (select id from dept) order by id"))
+	assert.Equal([]any{2, 5}, ids("select id from emp where dept not in (select id from dept) order by id"))
+	assert.Equal([]any{1, 4}, ids("select id from emp where dept = (select id from dept where title = 'tech') order by id"))
+
+	//correlated exists, the outer field is read for every employee
+	assert.Equal([]any{0, 1, 3, 4},
+		ids("select id from emp e where exists (select id from dept d where d.id = e.dept) order by id"))
+	assert.Equal([]any{2, 5},
+		ids("select id from emp e where not exists (select id from dept where id = dept) order by id"))
+
+	//correlated scalar subquery in the select list, null when nothing matches
+	rows := readRows(assert, planner,
+		"select id, (select title from dept d where d.id = e.dept) as title from emp e where id < 3 order by id", txn)
+	assert.Equal([]map[string]any{
+		{"id": 0, "title": "sales"},
+		{"id": 1, "title": "tech"},
+		{"id": 2, "title": nil},
+	}, rows)
+
+	//errors in subqueries are reported at plan time
+	_, err = planner.CreateQueryPlan("select id from emp where dept in (select id, title from dept)", txn)
+	assert.ErrorContains(err, "single column")
+	_, err = planner.CreateQueryPlan("select id from emp where dept in (select x.id from dept)", txn)
+	assert.ErrorContains(err, "unknown table")
+
+	//more than one record from a scalar subquery is an error at run time
+	p, err := planner.CreateQueryPlan("select id from emp where dept = (select id from dept)", txn)
+	assert.NoError(err)
+	s, err := p.Open()
+	assert.NoError(err)
+	_, err = s.Next()
+	assert.ErrorContains(err, "more than one record")
+	s.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestSetOperations(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	//employees 0-5 in departments 0, 1 and 2
+	createEmployees(assert, env, txn, 6, 3)
+
+	schema := record.NewSchema()
+	schema.AddIntField("code")
+	schema.AddStringField("label", 20)
+	assert.NoError(env.mdm.CreateTable("dept", schema, txn))
+	layout, err := env.mdm.GetLayout("dept", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "dept", layout)
+	assert.NoError(err)
+	for _, code := range []int{1, 1, 3, 4} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("code", code))
+		assert.NoError(ts.SetString("label", fmt.Sprintf("dept%d", code)))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	depts := func(sql string) []any {
+		rows := readRows(assert, planner, sql, txn)
+		result := make([]any, len(rows))
+		for i, row := range rows {
+			result[i] = row["dept"]
+		}
+		return result
+	}
+
+	assert.Equal([]any{0, 1, 2, 3, 4}, depts("select dept from emp union select code from dept order by dept"))
+	assert.Equal([]any{0, 0, 1, 1, 1, 1, 2, 2, 3, 4},
+		depts("select dept from emp union all select code from dept order by dept"))
+	assert.Equal([]any{1}, depts("select dept from emp intersect select code from dept"))
+	assert.Equal([]any{1, 1}, depts("select dept from emp intersect all select code from dept"))
+	assert.Equal([]any{0, 2}, depts("select dept from emp except select code from dept order by dept"))
+	assert.Equal([]any{0, 0, 2, 2}, depts("select dept from emp except all select code from dept order by dept"))
+
+	//intersect binds tighter than union
+	assert.Equal([]any{0, 1, 2},
+		depts("select dept from emp union select code from dept intersect select dept from emp order by dept"))
+	assert.Equal([]any{4, 3}, depts("select dept from emp union select code from dept order by dept desc limit 2"))
+
+	//string columns of different lengths
+	rows := readRows(assert, planner, "select name from emp where id = 0 union select label from dept where code = 3", txn)
+	assert.Equal([]map[string]any{{"name": "emp0"}, {"name": "dept3"}}, rows)
+
+	//set operations in a subquery
+	rows = readRows(assert, planner,
+		"select id from emp where dept in (select code from dept union select dept from emp where id = 2) order by id", txn)
+	assert.Equal([]map[string]any{{"id": 1}, {"id": 2}, {"id": 4}, {"id": 5}}, rows)
+
+	_, err = planner.CreateQueryPlan("select dept from emp union select label from dept", txn)
+	assert.ErrorContains(err, "incompatible")
+	_, err = planner.CreateQueryPlan("select id, dept from emp union select code from dept", txn)
+	assert.ErrorContains(err, "incompatible")
+	_, err = planner.CreateQueryPlan("select dept from emp union select code from dept order by code", txn)
+	assert.ErrorContains(err, "unknown field")
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestCommonTables(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	//employees 0-5 in departments 0, 1 and 2
+	createEmployees(assert, env, txn, 6, 3)
+
+	//staff 1 manages 2 and 3, 2 manages 4, 4 manages 5 and 3 manages 6
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddIntField("manager")
+	assert.NoError(env.mdm.CreateTable("staff", schema, txn))
+	layout, err := env.mdm.GetLayout("staff", txn)
+	assert.NoError(err)
+	ts, err := scan_types.NewTableScan(txn, "staff", layout)
+	assert.NoError(err)
+	for id, manager := range map[int]any{1: nil, 2: 1, 3: 1, 4: 2, 5: 4, 6: 3} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", id))
+		assert.NoError(ts.SetVal("manager", manager))
+	}
+	ts.Close()
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	ids := func(sql string) []any {
+		rows := readRows(assert, planner, sql, txn)
+		result := make([]any, len(rows))
+		for i, row := range rows {
+			result[i] = row["id"]
+		}
+		return result
+	}
+
+	assert.Equal([]any{1, 4},
+		ids("with d1 as (select id from emp where dept = 1) select id from d1 order by id"))
+	//column names, and a common table referring to an earlier one
+	assert.Equal([]any{4},
+		ids("with d1 (id, d) as (select id, dept from emp where dept = 1), big as (select id from d1 where id > 2) select id from big"))
+	//common tables are visible in subqueries and hide tables of the same name
+	assert.Equal([]any{3},
+		ids("with emp as (select id, dept from emp where dept = 0) select id from staff where id in (select id from emp) order by id"))
+	assert.Equal([]any{1, 4},
+		ids("select id from emp where id in (with d1 as (select id from emp where dept = 1) select id from d1) order by id"))
+
+	//the staff managed by 2, directly or not
+	assert.Equal([]any{2, 4, 5},
+		ids("with recursive chain (id) as (select id from staff where id = 2 "+
+			"union all select s.id from staff s, chain c where s.manager = c.id) select id from chain order by id"))
+	//the managers of 5
+	assert.Equal([]any{5, 4, 2, 1},
+		ids("with recursive up as (select id, manager from staff where id = 5 "+
+			"union all select s.id, s.manager from staff s join up on s.id = up.manager) select id from up"))
+	//the level of every staff member under 1, carried through the recursion
+	levels := make(map[any]any)
+	for _, row := range readRows(assert, planner, "with recursive org (id, lvl) as (select id, 0 from staff where id = 1 "+
+		"union all select s.id, c.lvl + 1 from staff s, org c where s.manager = c.id) select id, lvl from org", txn) {
+		levels[row["id"]] = row["lvl"]
+	}
+	assert.Equal(map[any]any{1: 0, 2: 1, 3: 1, 4: 2, 5: 3, 6: 2}, levels)
+	//going both ways would loop forever, union stops at the records already found
+	assert.Equal([]any{1, 2, 3, 4, 5, 6},
+		ids("with recursive linked (id) as (select id from staff where id = 4 "+
+			"union select s.manager from staff s, linked l where s.id = l.id and s.manager is not null "+
+			"union select s.id from staff s, linked l where s.manager = l.id) select id from linked order by id"))
+
+	_, err = planner.CreateQueryPlan("with d (a, b) as (select id from emp) select a from d", txn)
+	assert.ErrorContains(err, "columns")
+	_, err = planner.CreateQueryPlan("with recursive r as (select id from r) select id from r", txn)
+	assert.ErrorContains(err, "does not refer to itself")
+	_, err = planner.CreateQueryPlan("with recursive r as (select id from emp "+
+		"except select id from r) select id from r", txn)
+	assert.ErrorContains(err, "only combine")
+	//without recursive the table refers to the emp table
+	_, err = planner.CreateQueryPlan("with r as (select id from r) select id from r", txn)
+	assert.ErrorContains(err, "r")
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestWindowFunctions(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	//employees 0-5 in departments 0, 1 and 2
+	createEmployees(assert, env, txn, 6, 3)
+
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	column := func(sql string, fldName string) []any {
+		rows := readRows(assert, planner, sql, txn)
+		result := make([]any, len(rows))
+		for i, row := range rows {
+			result[i] = row[fldName]
+		}
+		return result
+	}
+
+	assert.Equal([]any{2, 2, 2, 1, 1, 1},
+		column("select id, row_number() over (partition by dept order by id desc) as rn from emp order by id", "rn"))
+	assert.Equal([]any{1, 3, 5, 1, 3, 5},
+		column("select id, rank() over (order by dept) as r from emp order by id", "r"))
+	assert.Equal([]any{1, 2, 3, 1, 2, 3},
+		column("select id, dense_rank() over (order by dept) as r from emp order by id", "r"))
+	assert.Equal([]any{0, 1, 3, 5, 7, 9},
+		column("select id, sum(id) over (order by id rows between 1 preceding and current row) as s from emp order by id", "s"))
+	assert.Equal([]any{1, 2, 3, 1, 2, 3},
+		column("select id, avg(id) over (partition by dept) as a from emp order by id", "a"))
+
+	//the functions see the records left by the predicate
+	assert.Equal([]any{nil, nil, nil, "emp1", "emp2"},
+		column("select id, lag(name) over (partition by dept order by id) as prev from emp where id > 0 order by id", "prev"))
+	assert.Equal([]any{"emp3", "emp4", "emp5", "none", "none", "none"},
+		column("select id, lead(name, 1, 'none') over (partition by dept order by id) as next from emp order by id", "next"))
+
+	//functions with different windows, qualified names and a generated column name
+	rows := readRows(assert, planner, "select e.id, row_number() over (order by e.id desc), "+
+		"sum(e.id) over (partition by e.dept order by e.id) as total from emp e where e.dept = 1", txn)
+	assert.ElementsMatch([]map[string]any{
+		{"id": 1, "column2": 2, "total": 1},
+		{"id": 4, "column2": 1, "total": 5},
+	}, rows)
+
+	_, err = planner.CreateQueryPlan("select sum(name) over () from emp", txn)
+	assert.ErrorContains(err, "int field")
+	_, err = planner.CreateQueryPlan("select lag(id, 1, 'x') over (order by id) from emp", txn)
+	assert.ErrorContains(err, "default")
+	_, err = planner.CreateQueryPlan("select median(id) over () from emp", txn)
+	assert.ErrorContains(err, "unknown function")
+	_, err = planner.CreateQueryPlan("select rank() over (order by id rows between 1 preceding and current row) from emp", txn)
+	assert.ErrorContains(err, "frame")
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestInsert(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+
+	execute("create table item (id int, name varchar(5), qty int)")
+	assert.Equal(3, execute("insert into item (id, name, qty) values (1, 'a', 10), (2, 'b', 20), (3, null, 30)"))
+	//existing records are added to a new index
+	execute("create index item_id on item (id)")
+	assert.Equal(1, execute("insert into item values (4, 'd', 40)"))
+	//fields left out are null
+	assert.Equal(1, execute("insert into item (id) values (5)"))
+	//the query is read before inserting, so it does not see the new records
+	assert.Equal(2, execute("insert into item (qty, id) select qty, id from item where qty >= 30"))
+
+	rows := readRows(assert, planner, "select id, name, qty from item where id >= 3", txn)
+	assert.ElementsMatch([]map[string]any{
+		{"id": 3, "name": nil, "qty": 30},
+		{"id": 4, "name": "d", "qty": 40},
+		{"id": 5, "name": nil, "qty": nil},
+		{"id": 3, "name": nil, "qty": 30},
+		{"id": 4, "name": nil, "qty": 40},
+	}, rows)
+
+	indexes, err := env.mdm.GetIndexInfo("item", txn)
+	assert.NoError(err)
+	idx := indexes["id"].Open()
+	for id, expected := range map[int]int{1: 1, 3: 2, 4: 2, 5: 1, 6: 0} {
+		assert.NoError(idx.BeforeFirst(id))
+		count := 0
+		for hasNext, err := idx.Next(); hasNext || err != nil; hasNext, err = idx.Next() {
+			assert.NoError(err)
+			count++
+		}
+		assert.Equal(expected, count, "index entries for %d", id)
+	}
+	idx.Close()
+
+	//the values inserted can be computed from the query
+	assert.Equal(1, execute("insert into item select id + 100, 'c', qty * 2 + 1 from item where id = 1"))
+	assert.Equal([]map[string]any{{"id": 101, "name": "c", "qty": 21}},
+		readRows(assert, planner, "select id, name, qty from item where id = 101", txn))
+	//and a field can be selected twice
+	assert.Equal(1, execute("insert into item (id, qty) select id, id from item where id = 2"))
+	assert.Len(readRows(assert, planner, "select id from item where id = 2 and qty = 2", txn), 1)
+
+	for sql, message := range map[string]string{
+		"insert into item (id, name) values (1)":               "expected 2 values",
+		"insert into item (id) values ('x')":                   "field id is an int",
+		"insert into item (name) values (7)":                   "field name is a string",
+		"insert into item (name) values ('abcdef')":            "too long",
+		"insert into item (id, price) values (1, 2)":           "no field price",
+		"insert into item (id, id) values (1, 2)":              "more than once",
+		"insert into item (id, qty) select id from item":       "returns 1 columns",
+		"insert into item (id, name) select id, qty from item": "type of field name",
+		"insert into item (id) select name + 1 from item":      "needs two numbers",
+		"insert into missing (id) values (1)":                  "missing",
+	} {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	//nothing was inserted by the failed statements
+	assert.Equal(9, len(readRows(assert, planner, "select id from item", txn)))
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	accounts := func() []map[string]any {
+		return readRows(assert, planner, "select id, bal, name from acct order by id", txn)
+	}
+
+	execute("create table acct (id int, bal int, name varchar(5))")
+	execute("create index acct_bal on acct (bal)")
+	execute("insert into acct values (1, 10, 'a'), (2, 20, 'b'), (3, null, 'c')")
+
+	assert.Equal(2, execute("update acct set bal = bal * 2 + 5, name = 'x' where id <= 2"))
+	//every value is computed from the record before the update
+	assert.Equal(1, execute("update acct set id = bal, bal = id - 1 where id = 1"))
+	//null stays null
+	assert.Equal(1, execute("update acct set bal = bal + 1 where id = 3"))
+	assert.Equal([]map[string]any{
+		{"id": 2, "bal": 45, "name": "x"},
+		{"id": 3, "bal": nil, "name": "c"},
+		{"id": 25, "bal": 0, "name": "x"},
+	}, accounts())
+
+	//subqueries on the right hand side, correlated or not
+	assert.Equal(1, execute("update acct set name = (select name from acct where id = 3) where id = 2"))
+	assert.Equal(3, execute("update acct set bal = (select a.id from acct a where a.id = acct.id) + 100"))
+
+	for sql, message := range map[string]string{
+		"update acct set bal = 'q'":                  "field bal is an int",
+		"update acct set bal = 1, bal = 2":           "more than once",
+		"update acct set bal = bal / 0 where id = 2": "division by zero",
+		"update acct set nope = 1":                   "no field nope",
+		"update acct set name = 'abcdef'":            "too long",
+		"update acct set bal = nope":                 "unknown field nope",
+		//the first assignment is not applied when the second fails
+		"update acct set name = 'y', bal = name where id = 2": "field bal is an int",
+	} {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	assert.Equal([]map[string]any{
+		{"id": 2, "bal": 102, "name": "c"},
+		{"id": 3, "bal": 103, "name": "c"},
+		{"id": 25, "bal": 125, "name": "x"},
+	}, accounts())
+
+	assert.Equal(1, execute("delete from acct where bal > 120"))
+
+	//the index follows every change of bal
+	indexes, err := env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	idx := indexes["bal"].Open()
+	for bal, expected := range map[any]int{10: 0, 20: 0, 45: 0, 102: 1, 103: 1, 125: 0, nil: 0} {
+		assert.NoError(idx.BeforeFirst(bal))
+		count := 0
+		for hasNext, err := idx.Next(); hasNext || err != nil; hasNext, err = idx.Next() {
+			assert.NoError(err)
+			count++
+		}
+		assert.Equal(expected, count, "index entries for %v", bal)
+	}
+	idx.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
+
+func TestDrop(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	newTxn := func() *tx.Transaction {
+		txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+		assert.NoError(err)
+		return txn
+	}
+	execute := func(sql string, txn *tx.Transaction) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+	}
+	exists := func(filename string) bool {
+		_, err := os.Stat(filepath.Join(env.tempDir, filename))
+		return err == nil
+	}
+	buckets := func() int {
+		entries, err := os.ReadDir(env.tempDir)
+		assert.NoError(err)
+		count := 0
+		for _, entry := range entries {
+			if len(entry.Name()) > 8 && entry.Name()[:8] == "acct_bal" {
+				count++
+			}
+		}
+		return count
+	}
+
+	txn := newTxn()
+	execute("create table acct (id int, bal int)", txn)
+	execute("create index acct_bal on acct (bal)", txn)
+	execute("create view rich as select id from acct where bal > 15", txn)
+	execute("insert into acct values (1, 10), (2, 20)", txn)
+	assert.NoError(txn.Commit())
+	assert.True(exists("acct.tbl"))
+	assert.Less(0, buckets())
+
+	//a table cannot be dropped while a view reads it, in a subquery too
+	txn = newTxn()
+	_, err := planner.ExecuteUpdate("drop table acct", txn)
+	assert.ErrorContains(err, "cannot drop table acct, view rich refers to it")
+	execute("create table other (x int)", txn)
+	execute("create view sub as select x from other where x in (select id from acct)", txn)
+	execute("drop view rich", txn)
+	_, err = planner.ExecuteUpdate("drop table acct", txn)
+	assert.ErrorContains(err, "view sub refers to it")
+	execute("drop view sub", txn)
+	execute("drop table other", txn)
+
+	//nothing is dropped when the transaction rolls back
+	execute("drop table acct", txn)
+	_, err = env.mdm.GetLayout("acct", txn)
+	assert.Error(err)
+	assert.NoError(txn.Rollback())
+	assert.True(exists("acct.tbl"))
+	txn = newTxn()
+	assert.Equal([]map[string]any{{"id": 2}}, readRows(assert, planner, "select id from rich", txn))
+	indexes, err := env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	assert.Len(indexes, 1)
+
+	execute("drop index acct_bal", txn)
+	execute("drop view rich", txn)
+	assert.NoError(txn.Commit())
+	assert.Equal(0, buckets())
+	assert.True(exists("acct.tbl"))
+
+	txn = newTxn()
+	indexes, err = env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	assert.Len(indexes, 0)
+	_, err = planner.CreateQueryPlan("select id from rich", txn)
+	assert.Error(err)
+	execute("create index acct_bal on acct (bal)", txn)
+	execute("drop table acct", txn)
+	assert.NoError(txn.Commit())
+	assert.False(exists("acct.tbl"))
+	assert.Equal(0, buckets())
+
+	//a new table with the same name starts empty
+	txn = newTxn()
+	_, err = planner.CreateQueryPlan("select id from acct", txn)
+	assert.Error(err)
+	indexes, err = env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	assert.Len(indexes, 0)
+	execute("create table acct (id int, bal int)", txn)
+	assert.Empty(readRows(assert, planner, "select id from acct", txn))
+
+	for sql, message := range map[string]string{
+		"drop table nope":   "no table named nope",
+		"drop table tblcat": "catalog table",
+		"drop view nope":    "view nope not found",
+		"drop index nope":   "index nope not found",
+	} {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	assert.NoError(txn.Commit())
+}
+
+func TestAlterTable(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	newTxn := func() *tx.Transaction {
+		txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+		assert.NoError(err)
+		return txn
+	}
+	txn := newTxn()
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	//every value is found through the index of its field
+	indexed := func(tblName string, fldName string, vals ...any) {
+		indexes, err := env.mdm.GetIndexInfo(tblName, txn)
+		assert.NoError(err)
+		idx := indexes[fldName].Open()
+		defer idx.Close()
+		layout, err := env.mdm.GetLayout(tblName, txn)
+		assert.NoError(err)
+		ts, err := scan_types.NewTableScan(txn, tblName, layout)
+		assert.NoError(err)
+		defer ts.Close()
+		for _, val := range vals {
+			assert.NoError(idx.BeforeFirst(val))
+			hasNext, err := idx.Next()
+			assert.NoError(err)
+			assert.True(hasNext, "%v", val)
+			rid, err := idx.GetDataRid()
+			assert.NoError(err)
+			assert.NoError(ts.MoveToRid(rid))
+			found, err := ts.GetVal(fldName)
+			assert.NoError(err)
+			assert.Equal(val, found)
+		}
+	}
+
+	execute("create table acct (id int, name varchar(5))")
+	execute("create index acct_name on acct (name)")
+	for i := 0; i < 200; i++ {
+		execute(fmt.Sprintf("insert into acct values (%d, 'n%d')", i, i%50))
+	}
+	execute("delete from acct where id > 2")
+	assert.NoError(txn.Commit())
+
+	//a rolled back rewrite leaves the old layout and records
+	txn = newTxn()
+	assert.Equal(3, execute("alter table acct add column bal int default 7"))
+	assert.Equal([]map[string]any{{"id": 0, "bal": 7}, {"id": 1, "bal": 7}, {"id": 2, "bal": 7}},
+		readRows(assert, planner, "select id, bal from acct order by id", txn))
+	assert.NoError(txn.Rollback())
+	txn = newTxn()
+	layout, err := env.mdm.GetLayout("acct", txn)
+	assert.NoError(err)
+	assert.Equal([]string{"id", "name"}, layout.Schema().Fields())
+	assert.Equal([]map[string]any{{"id": 0, "name": "n0"}, {"id": 1, "name": "n1"}, {"id": 2, "name": "n2"}},
+		readRows(assert, planner, "select * from acct order by id", txn))
+	indexed("acct", "name", "n0", "n1", "n2")
+
+	assert.Equal(3, execute("alter table acct add column bal int default 7"))
+	execute("alter table acct add nick varchar(3)")
+	execute("create index acct_bal on acct (bal)")
+	execute("update acct set bal = bal + id, nick = 'x' where id > 0")
+	assert.Equal([]map[string]any{
+		{"id": 0, "name": "n0", "bal": 7, "nick": nil},
+		{"id": 1, "name": "n1", "bal": 8, "nick": "x"},
+		{"id": 2, "name": "n2", "bal": 9, "nick": "x"},
+	}, readRows(assert, planner, "select * from acct order by id", txn))
+	indexed("acct", "name", "n0", "n1", "n2")
+	indexed("acct", "bal", 7, 8, 9)
+
+	//the index of a dropped field is dropped with it
+	assert.Equal(3, execute("alter table acct drop column name"))
+	indexes, err := env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	assert.Len(indexes, 1)
+	indexed("acct", "bal", 7, 8, 9)
+
+	execute("alter table acct rename column bal to balance")
+	execute("alter table acct rename to account")
+	assert.NoError(txn.Commit())
+
+	txn = newTxn()
+	assert.Equal([]map[string]any{
+		{"id": 0, "balance": 7, "nick": nil},
+		{"id": 1, "balance": 8, "nick": "x"},
+		{"id": 2, "balance": 9, "nick": "x"},
+	}, readRows(assert, planner, "select * from account order by id", txn))
+	indexed("account", "balance", 7, 8, 9)
+	_, err = env.mdm.GetLayout("acct", txn)
+	assert.Error(err)
+
+	for sql, message := range map[string]string{
+		"alter table account add id int":                     "already has a field id",
+		"alter table account add x int default 'a'":          "field x is an int",
+		"alter table account add x varchar(2) default 'abc'": "too long",
+		"alter table account drop column nope":               "no field nope",
+		"alter table account rename column nick to id":       "already has a field id",
+		"alter table account rename to tblcat":               "already exists",
+		"alter table nope rename to other":                   "no table named nope",
+		"alter table fldcat add x int":                       "catalog table",
+	} {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	execute("alter table account drop nick")
+	execute("alter table account drop balance")
+	_, err = planner.ExecuteUpdate("alter table account drop id", txn)
+	assert.ErrorContains(err, "only field")
+	assert.NoError(txn.Commit())
+}
+
+func TestConstraints(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	violates := func(sql string, constraint string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		var violation *ConstraintViolation
+		if assert.ErrorAs(err, &violation, sql) {
+			assert.Equal(constraint, violation.Constraint.Name(), sql)
+			assert.ErrorContains(err, message, sql)
+		}
+	}
+
+	execute("create table emp (id int primary key, email varchar(10) unique, name varchar(10) not null, " +
+		"dept int, code int, constraint emp_code unique (dept, code))")
+	constraints, err := env.mdm.GetConstraints("emp", txn)
+	assert.NoError(err)
+	names := make([]string, len(constraints))
+	for i, constraint := range constraints {
+		names[i] = constraint.Name()
+	}
+	assert.Equal([]string{"emp_pkey", "emp_email_key", "emp_name_not_null", "emp_code"}, names)
+	//primary key and unique constraints are backed by an index on their first field
+	indexes, err := env.mdm.GetIndexInfo("emp", txn)
+	assert.NoError(err)
+	assert.Len(indexes, 3)
+	assert.Equal("emp_pkey", indexes["id"].IndexName())
+	assert.Equal("emp_email_key", indexes["email"].IndexName())
+	assert.Equal("emp_code", indexes["dept"].IndexName())
+
+	execute("insert into emp values (1, 'a@x', 'ann', 1, 1), (2, null, 'bob', 1, null), (3, null, 'cy', 1, null)")
+	violates("insert into emp values (1, 'b@x', 'dee', 2, 2)", "emp_pkey", "duplicate value (1) for (id)")
+	violates("insert into emp (email, name) values ('b@x', 'dee')", "emp_pkey", "null value for field id")
+	violates("insert into emp (id, email) values (4, 'b@x')", "emp_name_not_null", "null value for field name")
+	violates("insert into emp values (4, 'a@x', 'dee', 2, 2)", "emp_email_key", "duplicate value ('a@x') for (email)")
+	violates("insert into emp values (4, 'b@x', 'dee', 1, 1)", "emp_code", "duplicate value (1, 1) for (dept, code)")
+	//the second row is checked against the first
+	violates("insert into emp values (4, 'b@x', 'dee', 2, 2), (4, 'c@x', 'eve', 3, 3)", "emp_pkey", "duplicate")
+	contents := func() []string {
+		var result []string
+		for _, row := range readRows(assert, planner, "select id, email, code from emp", txn) {
+			result = append(result, fmt.Sprintf("%v %v %v", row["id"], row["email"], row["code"]))
+		}
+		return result
+	}
+	before := []string{"1 a@x 1", "2 <nil> <nil>", "3 <nil> <nil>"}
+	assert.Equal(before, contents())
+	//a statement failing part way leaves no change behind, nor index entry
+	violates("insert into emp values (4, 'b@x', 'dee', 2, 2), (1, 'c@x', 'eve', 3, 3)", "emp_pkey", "duplicate value (1)")
+	assert.Equal(before, contents())
+	assert.Empty(readRows(assert, planner, "select id from emp where id = 4", txn))
+	assert.Empty(readRows(assert, planner, "select id from emp where email = 'b@x'", txn))
+
+	violates("update emp set id = 1 where id = 2", "emp_pkey", "duplicate value (1)")
+	violates("update emp set name = null where id = 2", "emp_name_not_null", "null value")
+	violates("update emp set code = 1 where id = 2", "emp_code", "duplicate value (1, 1)")
+	violates("update emp set code = 5", "emp_code", "duplicate value (1, 5)")
+	assert.Equal(before, contents())
+	//a record can keep its own values, and nulls are never duplicates
+	assert.Equal(1, execute("update emp set id = 1, email = 'a@x' where id = 1"))
+	assert.Equal(2, execute("update emp set code = null, email = null where id > 1"))
+	assert.Equal(1, execute("update emp set id = 10 where id = 3"))
+	execute("insert into emp values (3, 'c@x', 'cy', 1, 3)")
+
+	//an index cannot be created twice, nor a second index on a field
+	_, err = planner.ExecuteUpdate("create index emp_pkey on emp (name)", txn)
+	assert.ErrorContains(err, "index emp_pkey already exists")
+	_, err = planner.ExecuteUpdate("create index emp_id on emp (id)", txn)
+	assert.ErrorContains(err, "already has index emp_pkey")
+	_, err = planner.ExecuteUpdate("create table two (a int primary key, b int primary key)", txn)
+	assert.ErrorContains(err, "one primary key")
+	_, err = planner.ExecuteUpdate("create table two (a int unique, unique (nope))", txn)
+	assert.ErrorContains(err, "no field nope")
+	_, err = planner.ExecuteUpdate("create table two (x int primary key, x int)", txn)
+	assert.ErrorContains(err, "field x appears more than once")
+	_, err = env.mdm.GetLayout("two", txn)
+	assert.Error(err)
+
+	//without its index a unique constraint is checked by a scan
+	execute("drop index emp_email_key")
+	violates("insert into emp values (5, 'c@x', 'eve', 2, 2)", "emp_email_key", "duplicate value ('c@x')")
+
+	execute("alter table emp rename column dept to team")
+	execute("alter table emp drop column code")
+	execute("alter table emp rename to staff")
+	constraints, err = env.mdm.GetConstraints("staff", txn)
+	assert.NoError(err)
+	assert.Len(constraints, 3)
+	violates("insert into staff values (5, 'd@x', null, 2)", "emp_name_not_null", "null value for field name")
+	violates("insert into staff values (3, 'd@x', 'eve', 2)", "emp_pkey", "duplicate value (3)")
+	execute("insert into staff values (5, 'd@x', 'eve', 2)")
+
+	execute("drop table staff")
+	constraints, err = env.mdm.GetConstraints("staff", txn)
+	assert.NoError(err)
+	assert.Empty(constraints)
+	assert.NoError(txn.Commit())
+}
+
+func TestForeignKeys(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	violates := func(sql string, constraint string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		var violation *ConstraintViolation
+		if assert.ErrorAs(err, &violation, sql) {
+			assert.Equal(constraint, violation.Constraint.Name(), sql)
+			assert.ErrorContains(err, message, sql)
+		}
+	}
+	fails := func(sql string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	ids := func(sql string) []any {
+		var result []any
+		for _, row := range readRows(assert, planner, sql, txn) {
+			result = append(result, row["id"])
+		}
+		return result
+	}
+
+	fails("create table emp (id int, dept int references nope)", "table nope referred to by constraint")
+	execute("create table dept (id int primary key, name varchar(10) unique, parent int " +
+		"references dept on delete cascade)")
+	fails("create table emp (id int, dept varchar(5) references dept)", "another type")
+	fails("create table emp (id int, dept int references dept (parent))", "are not a primary key or unique")
+	fails("create table emp (id int, a int, b int, foreign key (a, b) references dept)", "refers to 1")
+	fails("create table emp (id int, dept int not null references dept on delete set null)", "cannot be null")
+	execute("create table emp (id int primary key, dept int references dept, " +
+		"mgr int references emp on delete set null, " +
+		"team varchar(10) constraint emp_team references dept (name) on delete cascade)")
+	constraints, err := env.mdm.GetConstraints("emp", txn)
+	assert.NoError(err)
+	assert.Equal("constraint emp_dept_fkey foreign key (dept) references dept (id) on delete restrict",
+		constraints[1].String())
+
+	//a record can refer to itself, and null refers to nothing
+	execute("insert into dept values (1, 'hq', 1), (2, 'ops', 1), (3, 'lab', 2), (4, 'misc', null)")
+	violates("insert into dept values (5, 'x', 9)", "dept_parent_fkey", "value (9) for (parent)")
+	execute("insert into emp values (1, 1, null, 'hq'), (2, 2, 1, 'ops'), (3, 3, 2, 'lab'), (4, null, 2, null)")
+	violates("insert into emp values (5, 9, null, null)", "emp_dept_fkey", "table dept has no such record")
+	violates("insert into emp values (5, 1, 9, null)", "emp_mgr_fkey", "value (9) for (mgr)")
+	violates("insert into emp values (5, 1, null, 'nope')", "emp_team", "value ('nope') for (team)")
+	violates("update emp set dept = 9 where id = 4", "emp_dept_fkey", "no such record")
+	assert.Equal(1, execute("update emp set dept = 4 where id = 4"))
+
+	//a record still referred to can be neither deleted nor changed by a restrict foreign key
+	violates("delete from dept where id = 4", "emp_dept_fkey", "record of table dept with (4) for (id) is still referenced")
+	violates("update dept set id = 10 where id = 4", "emp_dept_fkey", "still referenced")
+	assert.Equal(1, execute("update dept set name = 'other' where id = 4"))
+	assert.Equal(1, execute("update emp set dept = null where id = 4"))
+	assert.Equal(1, execute("update dept set id = 10 where id = 4"))
+	//the record referring to itself moves with its own change
+	assert.Equal(1, execute("update dept set id = 11, parent = 11 where id = 10"))
+	assert.Equal(1, execute("delete from dept where id = 11"))
+
+	//set null clears the manager of the deleted employee
+	assert.Equal(1, execute("delete from emp where id = 1"))
+	rows := readRows(assert, planner, "select id, mgr from emp where id = 2", txn)
+	assert.Equal([]map[string]any{{"id": 2, "mgr": nil}}, rows)
+
+	//deleting ops cascades to lab, the employees of both still refer to them
+	violates("delete from dept where id = 2", "emp_dept_fkey", "still referenced")
+	assert.Equal([]any{1, 2, 3}, ids("select id from dept"))
+	assert.Equal(3, execute("update emp set dept = null where id > 1"))
+	//the cascade on name removes the employees of the deleted departments
+	assert.Equal(1, execute("delete from dept where id = 2"))
+	assert.Equal([]any{1}, ids("select id from dept"))
+	assert.Equal([]any{4}, ids("select id from emp"))
+
+	//renames carry over to the foreign keys, a table or field referred to cannot be dropped
+	execute("alter table dept rename column id to num")
+	execute("alter table dept rename to unit")
+	violates("insert into emp values (5, 2, null, null)", "emp_dept_fkey", "table unit has no such record")
+	execute("insert into emp values (5, 1, 4, 'hq')")
+	fails("drop table unit", "referred to by constraint emp_dept_fkey of table emp")
+	fails("alter table unit drop column num", "referred to by constraint emp_dept_fkey of table emp")
+	execute("alter table emp drop column dept")
+	execute("alter table emp drop column team")
+	execute("drop table unit")
+	execute("drop table emp")
+	assert.NoError(txn.Commit())
+}
+
+func TestChecksAndDefaults(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	violates := func(sql string, constraint string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		var violation *ConstraintViolation
+		if assert.ErrorAs(err, &violation, sql) {
+			assert.Equal(constraint, violation.Constraint.Name(), sql)
+			assert.ErrorContains(err, message, sql)
+		}
+	}
+	fails := func(sql string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+
+	fails("create table item (qty int check (nope > 0))", "no field nope")
+	fails("create table item (qty int check (1 = 1))", "refers to no field")
+	fails("create table item (qty int check (qty in (select qty from item)))", "cannot have a subquery")
+	fails("create table item (qty int default 'x')", "field qty is an int")
+	fails("create table item (name varchar(2) default 'long')", "too long")
+	execute("create table item (id int primary key, qty int default 0 check (qty >= 0), " +
+		"name varchar(10) default 'n/a' not null, low int, " +
+		"constraint item_range check (low <= qty and qty < 100))")
+	constraints, err := env.mdm.GetConstraints("item", txn)
+	assert.NoError(err)
+	names := make([]string, len(constraints))
+	for i, constraint := range constraints {
+		names[i] = constraint.String()
+	}
+	assert.Equal([]string{
+		"constraint item_pkey primary key (id)",
+		"constraint item_qty_default default (qty) 0",
+		"constraint item_qty_check check (qty>=0)",
+		"constraint item_name_default default (name) 'n/a'",
+		"constraint item_name_not_null not null (name)",
+		"constraint item_range check (low<=qty and qty<100)",
+	}, names)
+
+	//the fields left out get their defaults, a null makes a check unknown and it holds
+	execute("insert into item (id) values (1)")
+	execute("insert into item (id, qty, low) values (2, 5, 1), (3, null, 7)")
+	execute("create table staged (id int)")
+	execute("insert into staged values (11)")
+	execute("insert into item (id) select id from staged")
+	rows := readRows(assert, planner, "select id, qty, name, low from item", txn)
+	assert.Equal([]map[string]any{
+		{"id": 1, "qty": 0, "name": "n/a", "low": nil},
+		{"id": 2, "qty": 5, "name": "n/a", "low": 1},
+		{"id": 3, "qty": nil, "name": "n/a", "low": 7},
+		{"id": 11, "qty": 0, "name": "n/a", "low": nil},
+	}, rows)
+	//an explicit null is not replaced by the default
+	violates("insert into item values (4, 1, null, 0)", "item_name_not_null", "null value for field name")
+
+	violates("insert into item (id, qty) values (4, -1)", "item_qty_check",
+		"value (-1) for (qty) violates check constraint item_qty_check (qty>=0) of table item")
+	violates("insert into item (id, qty, low) values (4, 5, 6)", "item_range", "value (6, 5) for (low, qty)")
+	violates("update item set qty = 100 where id = 2", "item_range", "value (1, 100) for (low, qty)")
+	violates("update item set qty = qty - 10 where id = 2", "item_qty_check", "value (-5)")
+	assert.Equal(1, execute("update item set qty = 50 where id = 2"))
+	assert.Equal(1, execute("update item set qty = 9 where id = 3"))
+
+	//renamed and dropped fields carry over to the checks
+	execute("alter table item rename column qty to amount")
+	violates("update item set amount = -1 where id = 1", "item_qty_check", "(amount>=0)")
+	violates("update item set low = 60 where id = 2", "item_range", "(low<=amount and amount<100)")
+	execute("alter table item drop column low")
+	assert.Equal(1, execute("update item set amount = 500 where id = 2"))
+	//a column added with a default keeps it for later inserts
+	execute("alter table item add column code varchar(5) default 'x1'")
+	execute("insert into item (id) values (5)")
+	rows = readRows(assert, planner, "select amount, code from item where id = 5", txn)
+	assert.Equal([]map[string]any{{"amount": 0, "code": "x1"}}, rows)
+	assert.NoError(txn.Commit())
+}
+
+func TestColumnTypes(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	fails := func(sql string, message string) {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorContains(err, message, sql)
+	}
+	day := func(d int) time.Time {
+		return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	execute("create table ev (id bigint primary key, ok boolean default false, rate double, " +
+		"day date, at timestamp, data blob(4) unique)")
+	execute("create index ev_day on ev (day)")
+	execute("insert into ev values (5000000000, true, 1.5, date '2024-03-02', " +
+		"timestamp '2024-03-02 10:30:00', x'0102')")
+	//an int is stored in a double field as a double
+	execute("insert into ev (id, rate, day, at, data) values (2, 3, date '2024-03-01', " +
+		"timestamp '2024-03-01 23:59:59.999999', x'ff')")
+	execute("insert into ev (id, rate, day) values (3, -0.25, date '2024-03-02')")
+
+	fails("insert into ev (id, ok) values (4, 1)", "field ok is a boolean, got 1")
+	fails("insert into ev (id, rate) values (4, 'x')", "field rate is a double")
+	fails("insert into ev (id, day) values (4, timestamp '2024-03-01 01:00:00')", "cannot have a time of day")
+	fails("insert into ev (id, data) values (4, x'0102030405')", "too long")
+	fails("insert into ev (id, data) values (4, x'ff')", "duplicate value (x'ff') for (data)")
+	fails("create table small (n int default 3000000000)", "out of range")
+
+	rows := readRows(assert, planner, "select id, ok, rate, day, at, data from ev where id = 2", txn)
+	assert.Equal([]map[string]any{{"id": 2, "ok": false, "rate": 3.0, "day": day(1),
+		"at": time.Date(2024, 3, 1, 23, 59, 59, 999999000, time.UTC), "data": record.Blob("\xff")}}, rows)
+
+	//comparisons mix ints and doubles and order dates, timestamps and booleans
+	ids := func(sql string) []any {
+		ids := make([]any, 0)
+		for _, row := range readRows(assert, planner, sql, txn) {
+			ids = append(ids, row["id"])
+		}
+		return ids
+	}
+	assert.Equal([]any{2}, ids("select id from ev where rate = 3"))
+	assert.Equal([]any{5000000000, 2}, ids("select id from ev where rate * 2 >= 3.0 order by rate"))
+	assert.Equal([]any{3, 5000000000}, ids("select id from ev where day = date '2024-03-02' order by rate"))
+	assert.Equal([]any{2}, ids("select id from ev where at < timestamp '2024-03-02 00:00:00'"))
+	assert.Equal([]any{2, 5000000000}, ids("select id from ev where data is not null order by data desc"))
+	assert.Equal([]any{2, 3, 5000000000}, ids("select id from ev order by ok, day, rate"))
+	assert.Equal([]any{5000000000}, ids("select id from ev where ok = true and id > 2147483647"))
+	p, err := planner.CreateQueryPlan("select id from ev where rate / 0.0 > 1", txn)
+	assert.NoError(err)
+	s, err := p.Open()
+	assert.NoError(err)
+	_, err = s.Next()
+	assert.ErrorContains(err, "division by zero")
+	s.Close()
+
+	//the updates of typed fields are undone by a rollback
+	assert.NoError(txn.Commit())
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.Equal(3, execute("update ev set rate = rate + 0.5, day = date '2024-03-09', data = null"))
+	assert.Equal([]any{3, 5000000000, 2}, ids("select id from ev where day = date '2024-03-09' order by rate"))
+	assert.NoError(txn.Rollback())
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rows = readRows(assert, planner, "select id, rate, day, data from ev where day = date '2024-03-02' order by rate", txn)
+	assert.Equal([]map[string]any{
+		{"id": 3, "rate": -0.25, "day": day(2), "data": nil},
+		{"id": 5000000000, "rate": 1.5, "day": day(2), "data": record.Blob("\x01\x02")},
+	}, rows)
+	assert.NoError(txn.Commit())
+}
+
+func TestVariableLengthRecords(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	blocks := func() int {
+		size, err := txn.Size(scan_types.TableFileName("doc"))
+		assert.NoError(err)
+		return size
+	}
+
+	//short values take only their length
+	execute("create table doc (id int primary key, body varchar(500))")
+	for i := range 40 {
+		execute(fmt.Sprintf("insert into doc values (%d, 'note %d')", i, i))
+	}
+	assert.Equal(1, blocks())
+
+	//records that grow move to other blocks and are still found by their rids
+	body := strings.Repeat("b", 300)
+	assert.Equal(40, execute(fmt.Sprintf("update doc set body = '%s'", body)))
+	assert.Greater(blocks(), 1)
+	rows := readRows(assert, planner, "select id, body from doc where id < 3", txn)
+	assert.Equal([]map[string]any{{"id": 0, "body": body}, {"id": 1, "body": body}, {"id": 2, "body": body}}, rows)
+	_, err = planner.ExecuteUpdate("insert into doc values (7, 'again')", txn)
+	assert.ErrorContains(err, "duplicate value (7) for (id)")
+	assert.Equal(1, execute("delete from doc where id = 7"))
+	execute("insert into doc values (7, 'again')")
+	assert.Equal(1, execute("update doc set body = null where id = 8"))
+	rows = readRows(assert, planner, "select id, body from doc where id > 6 and id < 9", txn)
+	assert.Equal([]map[string]any{{"id": 8, "body": nil}, {"id": 7, "body": "again"}}, rows)
+	rows = readRows(assert, planner, "select id from doc", txn)
+	assert.Len(rows, 40)
+	assert.NoError(txn.Commit())
+}
+
+func TestOverflowValues(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+
+	//values larger than a block are stored in overflow chains
+	execute("create table doc (id int, body varchar(20000), data blob(10000))")
+	long := strings.Repeat("0123456789", 1000)
+	execute(fmt.Sprintf("insert into doc values (1, '%s', x'%s')", long, strings.Repeat("ab", 6000)))
+	execute("insert into doc values (2, 'short', x'01')")
+	rows := readRows(assert, planner, "select id, body, data from doc", txn)
+	assert.Equal([]map[string]any{
+		{"id": 1, "body": long, "data": record.Blob(strings.Repeat("\xab", 6000))},
+		{"id": 2, "body": "short", "data": record.Blob("\x01")},
+	}, rows)
+	rows = readRows(assert, planner, fmt.Sprintf("select id from doc where body = '%s'", long), txn)
+	assert.Equal([]map[string]any{{"id": 1}}, rows)
+
+	//updates replace chains and free the old ones for new records
+	longer := strings.Repeat("z", 15000)
+	assert.Equal(2, execute(fmt.Sprintf("update doc set body = '%s'", longer)))
+	rows = readRows(assert, planner, "select id, body from doc", txn)
+	assert.Equal([]map[string]any{{"id": 1, "body": longer}, {"id": 2, "body": longer}}, rows)
+	assert.NoError(txn.Commit())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.Equal(1, execute("delete from doc where id = 1"))
+	assert.NoError(txn.Rollback())
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rows = readRows(assert, planner, "select id, body from doc where id = 1", txn)
+	assert.Equal([]map[string]any{{"id": 1, "body": longer}}, rows)
+	assert.NoError(txn.Commit())
+}
+
+func TestVacuum(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	newTxn := func() *tx.Transaction {
+		txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+		assert.NoError(err)
+		return txn
+	}
+	txn := newTxn()
+	execute := func(sql string) int {
+		count, err := planner.ExecuteUpdate(sql, txn)
+		assert.NoError(err, sql)
+		return count
+	}
+	blocks := func(tblName string) int {
+		size, err := txn.Size(scan_types.TableFileName(tblName))
+		assert.NoError(err)
+		return size
+	}
+
+	execute("create table acct (id int, name varchar(100))")
+	execute("create index acct_id on acct (id)")
+	execute("create table note (id int)")
+	for i := range 300 {
+		execute(fmt.Sprintf("insert into acct values (%d, '%s')", i, strings.Repeat("n", i%100)))
+	}
+	execute("insert into note values (1)")
+	assert.Equal(290, execute("delete from acct where id >= 10"))
+	assert.NoError(txn.Commit())
+	txn = newTxn()
+	before := blocks("acct")
+	assert.Greater(before, 1)
+
+	//a rolled back vacuum leaves the file and its records as they were
+	assert.Equal(10, execute("vacuum acct"))
+	assert.Equal(1, blocks("acct"))
+	assert.NoError(txn.Rollback())
+	txn = newTxn()
+	assert.Equal(before, blocks("acct"))
+	assert.Len(readRows(assert, planner, "select id from acct", txn), 10)
+
+	//the records move into the first blocks and the index follows them
+	assert.Equal(11, execute("vacuum"))
+	assert.Equal(1, blocks("acct"))
+	indexes, err := env.mdm.GetIndexInfo("acct", txn)
+	assert.NoError(err)
+	idx := indexes["id"].Open()
+	assert.NoError(idx.BeforeFirst(7))
+	hasNext, err := idx.Next()
+	assert.NoError(err)
+	assert.True(hasNext)
+	rid, err := idx.GetDataRid()
+	assert.NoError(err)
+	idx.Close()
+	assert.Equal(0, rid.BlockNumber())
+	assert.Equal([]map[string]any{{"id": 7, "name": "nnnnnnn"}}, readRows(assert, planner, "select id, name from acct where id = 7", txn))
+	assert.Equal([]map[string]any{{"id": 1}}, readRows(assert, planner, "select id from note", txn))
+	assert.NoError(txn.Commit())
+
+	txn = newTxn()
+	_, err = planner.ExecuteUpdate("vacuum tblcat", txn)
+	assert.ErrorContains(err, "cannot vacuum catalog table tblcat")
+	_, err = planner.ExecuteUpdate("vacuum nothing", txn)
+	assert.Error(err)
+	assert.NoError(txn.Commit())
+}
+
+func TestScrub(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	_, err = planner.ExecuteUpdate("create table acct (id int)", txn)
+	assert.NoError(err)
+	_, err = planner.ExecuteUpdate("insert into acct values (1)", txn)
+	assert.NoError(err)
+	assert.NoError(txn.Commit())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	checked, err := planner.ExecuteUpdate("scrub", txn)
+	assert.NoError(err)
+	assert.Greater(checked, 1)
+
+	//a block changed on disk behind the database is reported
+	tblFile, err := os.OpenFile(filepath.Join(env.tempDir, scan_types.TableFileName("acct")), os.O_RDWR, 0)
+	assert.NoError(err)
+	_, err = tblFile.WriteAt([]byte("garbage"), 100)
+	assert.NoError(err)
+	assert.NoError(tblFile.Close())
+	_, err = planner.ExecuteUpdate("scrub", txn)
+	var corruption *file.CorruptionError
+	assert.ErrorAs(err, &corruption)
+	assert.EqualError(err, "block 0 of acct.tbl is corrupted, its checksum does not match")
+	assert.NoError(txn.Commit())
+}
+
+func TestTrailingInput(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	planner := NewPlanner(NewBasicQueryPlanner(env.mdm), NewBasicUpdatePlanner(env.mdm))
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	createEmployees(assert, env, txn, 3, 1)
+
+	//input the grammar does not know is not left out of a statement
+	var syntaxErr *parse.SyntaxError
+	for _, sql := range []string{
+		"select id from emp where id = 1 or id = 2",
+		"select id from emp garbage here",
+	} {
+		_, err := planner.CreateQueryPlan(sql, txn)
+		assert.ErrorAs(err, &syntaxErr, sql)
+	}
+	for _, sql := range []string{
+		"delete from emp where id = 1 or id = 2",
+		"insert into emp (id) values (5) garbage",
+		"create table extra (id int) garbage",
+	} {
+		_, err := planner.ExecuteUpdate(sql, txn)
+		assert.ErrorAs(err, &syntaxErr, sql)
+	}
+	assert.Len(readRows(assert, planner, "select id from emp", txn), 3)
+	assert.NoError(txn.Commit())
+}
@@ -0,0 +1,11 @@
+package planner
+
+import (
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/tx"
+)
+
+type QueryPlanner interface {
+	CreatePlan(*parse.QueryData, *tx.Transaction) (plan.Plan, error)
+}
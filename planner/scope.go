@@ -0,0 +1,295 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/record"
+	"strings"
+)
+
+// rangeItem
+// a table or view of the from clause and the name it is referred to by.
+// The fields of an unqualified item are known by their own name
+type rangeItem struct {
+	name        string
+	schema      *record.Schema
+	unqualified bool
+}
+
+// field
+// the internal name of the field fldName of the item
+func (item *rangeItem) field(fldName string) string {
+	if item.unqualified {
+		return fldName
+	}
+	return qualify(item.name, fldName)
+}
+
+// scope
+// resolves the field names used in a query against the tables in its
+// from clause, every field is known internally as range.field so fields
+// with the same name in different tables do not shadow each other
+type scope struct {
+	items []rangeItem
+	outer *correlation
+	with  *commonTable
+}
+
+// correlation
+// links the scope of a subquery to the scope of the query it is nested in.
+// A field that is not found in the subquery is looked up in the outer scope
+// and read from params, sources holds the outer expression of every param
+type correlation struct {
+	parent  *scope
+	params  *query.Params
+	sources map[string]*query.Expression
+}
+
+func newScope() *scope {
+	return &scope{make([]rangeItem, 0), nil, nil}
+}
+
+// newSubqueryScope
+// scope of a subquery nested in the query of parent
+func newSubqueryScope(parent *scope) *scope {
+	return &scope{
+		make([]rangeItem, 0),
+		&correlation{parent, query.NewParams(), make(map[string]*query.Expression)},
+		parent.with,
+	}
+}
+
+// newSiblingScope
+// an empty scope sharing the outer scope and common tables of s, for the
+// tables joined by join ... on and for the operands of a set operation
+func (s *scope) newSiblingScope() *scope {
+	return &scope{make([]rangeItem, 0), s.outer, s.with}
+}
+
+// isCorrelated
+// true when a field of an outer query has been referred to
+func (s *scope) isCorrelated() bool {
+	return s.outer != nil && len(s.outer.sources) > 0
+}
+
+// add
+// registers the fields of schema under name
+func (s *scope) add(name string, schema *record.Schema) error {
+	if s.item(name) != nil {
+		return fmt.Errorf("table name %s specified more than once", name)
+	}
+	s.items = append(s.items, rangeItem{name, schema, false})
+	return nil
+}
+
+// newTableScope
+// scope of a statement modifying the table tblName, which is read directly
+// so its fields keep their own names
+func newTableScope(tblName string, schema *record.Schema) *scope {
+	s := newScope()
+	s.items = append(s.items, rangeItem{tblName, schema, true})
+	return s
+}
+
+func (s *scope) item(name string) *rangeItem {
+	for i := range s.items {
+		if s.items[i].name == name {
+			return &s.items[i]
+		}
+	}
+	return nil
+}
+
+// resolve
+// qualified name of fldName, which may itself be qualified
+func (s *scope) resolve(fldName string) (string, error) {
+	qualified, found, err := s.lookup(fldName)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", unknownFieldError(fldName)
+	}
+	return qualified, nil
+}
+
+// expression
+// resolves fldName to a field of this scope, or to a param holding the
+// value of a field of an outer query
+func (s *scope) expression(fldName string) (*query.Expression, error) {
+	qualified, found, err := s.lookup(fldName)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return query.NewFieldExpression(qualified), nil
+	}
+	if s.outer == nil {
+		return nil, unknownFieldError(fldName)
+	}
+	source, err := s.outer.parent.expression(fldName)
+	if err != nil {
+		return nil, err
+	}
+	name := source.String()
+	s.outer.sources[name] = source
+	return query.NewParamExpression(name, s.outer.params), nil
+}
+
+// lookup
+// qualified name of fldName, false when no table of the scope has it
+func (s *scope) lookup(fldName string) (string, bool, error) {
+	item, column, err := s.find(fldName)
+	if item == nil {
+		return "", false, err
+	}
+	return item.field(column), true, nil
+}
+
+// find
+// the item of the scope having the field fldName and the name of the
+// field in it, nil when no table of the scope has it
+func (s *scope) find(fldName string) (*rangeItem, string, error) {
+	if rangeName, column, ok := strings.Cut(fldName, "."); ok {
+		item := s.item(rangeName)
+		if item == nil {
+			return nil, "", nil
+		}
+		if !item.schema.HasField(column) {
+			return nil, "", fmt.Errorf("unknown field %s", fldName)
+		}
+		return item, column, nil
+	}
+	var found *rangeItem
+	for i := range s.items {
+		if !s.items[i].schema.HasField(fldName) {
+			continue
+		}
+		if found != nil {
+			return nil, "", fmt.Errorf("field %s is ambiguous, it exists in %s and %s",
+				fldName, found.name, s.items[i].name)
+		}
+		found = &s.items[i]
+	}
+	if found == nil {
+		return nil, "", nil
+	}
+	return found, fldName, nil
+}
+
+// fieldType
+// the type and length of the field fldName, of this scope or of an outer
+// query
+func (s *scope) fieldType(fldName string) (int, int, error) {
+	item, column, err := s.find(fldName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if item != nil {
+		return item.schema.Type(column), item.schema.Length(column), nil
+	}
+	if s.outer == nil {
+		return 0, 0, unknownFieldError(fldName)
+	}
+	return s.outer.parent.fieldType(fldName)
+}
+
+func unknownFieldError(fldName string) error {
+	if rangeName, _, ok := strings.Cut(fldName, "."); ok {
+		return fmt.Errorf("unknown table %s in field %s", rangeName, fldName)
+	}
+	return fmt.Errorf("unknown field %s", fldName)
+}
+
+// selectList
+// expands * and table.* and resolves every select item, returning the
+// output name and the qualified source field of each column. Columns
+// without an alias are named after their field, unless that name is
+// shared by fields of several tables in which case the qualified name is
+// used. A name already taken by an earlier column, as for a field listed
+// twice, is replaced by the position of the column, column3 for the
+// third. computed holds the field computing an item that is not a field,
+// empty for the other items
+func (s *scope) selectList(fields []string, aliases []string, computed []string) ([]string, []string, error) {
+	type column struct {
+		source string
+		name   string
+		alias  string
+	}
+	columns := make([]column, 0, len(fields))
+	for i, field := range fields {
+		switch {
+		case computed[i] != "":
+			columns = append(columns, column{computed[i], computed[i], aliases[i]})
+		case field == "*":
+			for _, item := range s.items {
+				for _, fldName := range item.schema.Fields() {
+					columns = append(columns, column{item.field(fldName), fldName, ""})
+				}
+			}
+		case strings.HasSuffix(field, ".*"):
+			rangeName := strings.TrimSuffix(field, ".*")
+			item := s.item(rangeName)
+			if item == nil {
+				return nil, nil, fmt.Errorf("unknown table %s in %s", rangeName, field)
+			}
+			for _, fldName := range item.schema.Fields() {
+				columns = append(columns, column{item.field(fldName), fldName, ""})
+			}
+		default:
+			source, err := s.resolve(field)
+			if err != nil {
+				return nil, nil, err
+			}
+			_, name, _ := strings.Cut(source, ".")
+			columns = append(columns, column{source, name, aliases[i]})
+		}
+	}
+
+	nameSources := make(map[string]map[string]bool)
+	for _, col := range columns {
+		if col.alias != "" {
+			continue
+		}
+		if nameSources[col.name] == nil {
+			nameSources[col.name] = make(map[string]bool)
+		}
+		nameSources[col.name][col.source] = true
+	}
+	outputs := make([]string, len(columns))
+	sources := make([]string, len(columns))
+	seen := make(map[string]bool)
+	for i, col := range columns {
+		switch {
+		case col.alias != "":
+			outputs[i] = col.alias
+		case len(nameSources[col.name]) > 1:
+			outputs[i] = col.source
+		default:
+			outputs[i] = col.name
+		}
+		for position := i + 1; seen[outputs[i]]; position++ {
+			outputs[i] = fmt.Sprintf("column%d", position)
+		}
+		seen[outputs[i]] = true
+		sources[i] = col.source
+	}
+	return outputs, sources, nil
+}
+
+// qualifiedPlan
+// a plan exposing the fields of p qualified by name
+func qualifiedPlan(name string, p plan.Plan) plan.Plan {
+	fields := p.Schema().Fields()
+	qualified := make([]string, len(fields))
+	for i, fldName := range fields {
+		qualified[i] = qualify(name, fldName)
+	}
+	return plan_types.NewRenamePlan(p, qualified, fields)
+}
+
+func qualify(rangeName string, fldName string) string {
+	return rangeName + "." + fldName
+}
@@ -0,0 +1,81 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/tx"
+)
+
+// setOperationPlan
+// plan of selects combined by union, intersect and except. Intersect binds
+// tighter than union and except, which are applied from left to right. The
+// output columns are named after the first select, order by and limit
+// apply to the combined records
+func (qp *BasicQueryPlanner) setOperationPlan(data *parse.QueryData, txn *tx.Transaction, scope *scope) (plan.Plan, error) {
+	first, err := qp.selectPlan(data, txn, scope.newSiblingScope(), false)
+	if err != nil {
+		return nil, err
+	}
+	operands := []plan.Plan{first}
+	for _, setOp := range data.SetOperations() {
+		p, err := qp.selectPlan(setOp.Query(), txn, scope.newSiblingScope(), false)
+		if err != nil {
+			return nil, err
+		}
+		if !first.Schema().IsCompatible(p.Schema()) {
+			return nil, fmt.Errorf("%s operands have incompatible columns, %v and %v",
+				setOp.Operator(), first.Schema().Fields(), p.Schema().Fields())
+		}
+		//operands are matched by position, give them the names of the first
+		operands = append(operands, plan_types.NewRenamePlan(p, first.Schema().Fields(), p.Schema().Fields()))
+	}
+
+	//intersections first
+	setOps := data.SetOperations()
+	plans := []plan.Plan{operands[0]}
+	rest := make([]*parse.SetOperationData, 0)
+	for i, setOp := range setOps {
+		if setOp.Operator() == query.Intersect {
+			plans[len(plans)-1] = combine(plans[len(plans)-1], operands[i+1], setOp)
+			continue
+		}
+		plans = append(plans, operands[i+1])
+		rest = append(rest, setOp)
+	}
+	p := plans[0]
+	for i, setOp := range rest {
+		p = combine(p, plans[i+1], setOp)
+	}
+
+	if len(data.OrderBy()) > 0 {
+		for _, field := range data.OrderBy() {
+			if !p.Schema().HasField(field.FieldName()) {
+				return nil, fmt.Errorf("unknown field %s in order by, only the output columns %v can be ordered on",
+					field.FieldName(), p.Schema().Fields())
+			}
+		}
+		if data.Limit() >= 0 {
+			p = plan_types.NewTopNPlan(p, data.OrderBy(), data.Offset()+data.Limit())
+		} else {
+			p = plan_types.NewSortPlan(txn, p, data.OrderBy())
+		}
+	}
+	if data.Limit() >= 0 || data.Offset() > 0 {
+		p = plan_types.NewLimitPlan(p, data.Limit(), data.Offset())
+	}
+	return p, nil
+}
+
+func combine(p1 plan.Plan, p2 plan.Plan, setOp *parse.SetOperationData) plan.Plan {
+	if setOp.Operator() != query.Union {
+		return plan_types.NewSetOperationPlan(p1, p2, setOp.Operator(), setOp.IsAll())
+	}
+	p := plan.Plan(plan_types.NewConcatPlan(p1, p2))
+	if !setOp.IsAll() {
+		p = plan_types.NewDistinctPlan(p)
+	}
+	return p
+}
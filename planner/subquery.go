@@ -0,0 +1,113 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/parse"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/scan"
+	"jadb/tx"
+)
+
+// resolvePredicate
+// resolves the fields of pred in scope and binds its subqueries
+func (qp *BasicQueryPlanner) resolvePredicate(pred *query.Predicate, scope *scope, txn *tx.Transaction) (*query.Predicate, error) {
+	resolved, err := pred.ResolveFields(scope.expression)
+	if err != nil {
+		return nil, err
+	}
+	for _, subquery := range resolved.Subqueries() {
+		if err := qp.bindSubquery(subquery, scope, txn); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// extendPlan
+// adds the field fldName computed by expr, a select item that is not a
+// field, to p. Its type follows from the fields and subqueries in it
+func (qp *BasicQueryPlanner) extendPlan(p plan.Plan, fldName string, expr *query.Expression,
+	scope *scope, txn *tx.Transaction) (plan.Plan, error) {
+	fldType, length, err := expr.Type(func(e *query.Expression) (int, int, error) {
+		subqueries := e.Subqueries()
+		if len(subqueries) == 0 {
+			return scope.fieldType(e.String())
+		}
+		subPlan, err := qp.subqueryPlan(subqueries[0], scope, txn)
+		if err != nil {
+			return 0, 0, err
+		}
+		column := subPlan.Schema().Fields()[0]
+		return subPlan.Schema().Type(column), subPlan.Schema().Length(column), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := expr.ResolveFields(scope.expression)
+	if err != nil {
+		return nil, err
+	}
+	return plan_types.NewExtendPlan(p, fldName, fldType, length, resolved), nil
+}
+
+func (qp *BasicQueryPlanner) bindSubquery(subquery *query.Subquery, scope *scope, txn *tx.Transaction) error {
+	_, err := qp.subqueryPlan(subquery, scope, txn)
+	return err
+}
+
+// subqueryPlan
+// plans subquery in a scope nested in scope and binds it to the plan. The
+// outer fields it refers to are copied into its params from the outer
+// record before every evaluation
+func (qp *BasicQueryPlanner) subqueryPlan(subquery *query.Subquery, scope *scope, txn *tx.Transaction) (plan.Plan, error) {
+	data, ok := subquery.Definition().(*parse.QueryData)
+	if !ok {
+		return nil, fmt.Errorf("subquery %s is not a query", subquery)
+	}
+	subScope := newSubqueryScope(scope)
+	p, err := qp.createPlan(data, txn, subScope)
+	if err != nil {
+		return nil, err
+	}
+	fields := p.Schema().Fields()
+	if subquery.IsSingleColumn() && len(fields) != 1 {
+		return nil, fmt.Errorf("subquery %s must return a single column, it returns %d", subquery, len(fields))
+	}
+	correlation := subScope.outer
+	rows := func(outer scan.Scan, limit int) ([][]any, error) {
+		for name, source := range correlation.sources {
+			val, err := source.Evaluate(outer)
+			if err != nil {
+				return nil, err
+			}
+			correlation.params.Set(name, val)
+		}
+		s, err := p.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer s.Close()
+		rows := make([][]any, 0)
+		for limit < 0 || len(rows) < limit {
+			hasNext, err := s.Next()
+			if err != nil {
+				return nil, err
+			}
+			if !hasNext {
+				break
+			}
+			row := make([]any, len(fields))
+			for i, fldName := range fields {
+				if row[i], err = s.GetVal(fldName); err != nil {
+					return nil, err
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+	subquery.Bind(rows, subScope.isCorrelated())
+	return p, nil
+}
@@ -0,0 +1,24 @@
+package planner
+
+import (
+	"jadb/parse"
+	"jadb/tx"
+)
+
+// UpdatePlanner
+// executes the statements modifying the database, each returns the number
+// of records affected
+type UpdatePlanner interface {
+	ExecuteInsert(*parse.InsertData, *tx.Transaction) (int, error)
+	ExecuteDelete(*parse.DeleteData, *tx.Transaction) (int, error)
+	ExecuteModify(*parse.ModifyData, *tx.Transaction) (int, error)
+	ExecuteCreateTable(*parse.CreateTableData, *tx.Transaction) (int, error)
+	ExecuteCreateView(*parse.CreateViewData, *tx.Transaction) (int, error)
+	ExecuteCreateIndex(*parse.CreateIndexData, *tx.Transaction) (int, error)
+	ExecuteAlterTable(*parse.AlterTableData, *tx.Transaction) (int, error)
+	ExecuteDropTable(*parse.DropTableData, *tx.Transaction) (int, error)
+	ExecuteDropView(*parse.DropViewData, *tx.Transaction) (int, error)
+	ExecuteDropIndex(*parse.DropIndexData, *tx.Transaction) (int, error)
+	ExecuteVacuum(*parse.VacuumData, *tx.Transaction) (int, error)
+	ExecuteScrub(*parse.ScrubData, *tx.Transaction) (int, error)
+}
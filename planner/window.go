@@ -0,0 +1,78 @@
+package planner
+
+import (
+	"fmt"
+	"jadb/plan"
+	"jadb/plan_types"
+	"jadb/query"
+	"jadb/record"
+	"jadb/tx"
+)
+
+// windowPlan
+// adds the window functions of the select list to p, naming the field of
+// item i computed[i]. The functions are grouped by window, for every
+// window the records are sorted on its partition by and order by fields
+// and all its functions are computed in one pass
+func (qp *BasicQueryPlanner) windowPlan(p plan.Plan, windows []*query.WindowFunction, computed []string,
+	scope *scope, txn *tx.Transaction) (plan.Plan, error) {
+	order := make([]string, 0)
+	groups := make(map[string][]int)
+	resolved := make([]*query.WindowFunction, len(windows))
+	for i, window := range windows {
+		if window == nil {
+			continue
+		}
+		var err error
+		if resolved[i], err = window.ResolveFields(scope.resolve); err != nil {
+			return nil, err
+		}
+		if resolved[i], err = checkWindowFunction(resolved[i], p.Schema()); err != nil {
+			return nil, err
+		}
+		computed[i] = fmt.Sprintf("column%d", i+1)
+		key := resolved[i].Window()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	for _, key := range order {
+		fldNames := make([]string, len(groups[key]))
+		functions := make([]*query.WindowFunction, len(groups[key]))
+		for j, i := range groups[key] {
+			fldNames[j], functions[j] = computed[i], resolved[i]
+		}
+		sortFields := make([]*query.OrderField, 0)
+		for _, fldName := range functions[0].PartitionBy() {
+			sortFields = append(sortFields, query.NewOrderField(fldName, false))
+		}
+		sortFields = append(sortFields, functions[0].OrderBy()...)
+		if len(sortFields) > 0 {
+			p = plan_types.NewSortPlan(txn, p, sortFields)
+		}
+		p = plan_types.NewWindowPlan(p, fldNames, functions)
+	}
+	return p, nil
+}
+
+// checkWindowFunction
+// sum and avg need an int argument, the default of lag and lead has to
+// have the type of their argument. The function is returned with its
+// default converted to that type
+func checkWindowFunction(fn *query.WindowFunction, schema *record.Schema) (*query.WindowFunction, error) {
+	switch fn.Function() {
+	case query.Sum, query.Avg:
+		if fldType := schema.Type(fn.FieldName()); fldType != record.INTEGER && fldType != record.BIGINT {
+			return nil, fmt.Errorf("%s needs an int field, %s is not one", fn.Function(), fn.FieldName())
+		}
+	case query.Lag, query.Lead:
+		defaultVal, err := convertValue(schema, fn.FieldName(), fn.Default())
+		if err != nil {
+			return nil, fmt.Errorf("invalid default of %s: %w", fn.Function(), err)
+		}
+		return query.NewWindowFunction(fn.Function(), fn.FieldName(), fn.Offset(), defaultVal,
+			fn.PartitionBy(), fn.OrderBy(), fn.Frame()), nil
+	}
+	return fn, nil
+}
@@ -0,0 +1,97 @@
+package query
+
+import "fmt"
+
+// ArithmeticOperator
+// combines two int values, the result is null when either value is null
+type ArithmeticOperator int
+
+const (
+	Add ArithmeticOperator = iota
+	Subtract
+	Multiply
+	Divide
+)
+
+func (op ArithmeticOperator) String() string {
+	switch op {
+	case Add:
+		return "+"
+	case Subtract:
+		return "-"
+	case Multiply:
+		return "*"
+	case Divide:
+		return "/"
+	}
+	return ""
+}
+
+// precedence
+// multiplication and division bind tighter than addition and subtraction
+func (op ArithmeticOperator) precedence() int {
+	if op == Multiply || op == Divide {
+		return 2
+	}
+	return 1
+}
+
+func (op ArithmeticOperator) apply(v1 any, v2 any) (any, error) {
+	if v1 == nil || v2 == nil {
+		return nil, nil
+	}
+	val1, ok1 := v1.(int)
+	val2, ok2 := v2.(int)
+	if !ok1 || !ok2 {
+		return op.applyDouble(v1, v2)
+	}
+	switch op {
+	case Add:
+		return val1 + val2, nil
+	case Subtract:
+		return val1 - val2, nil
+	case Multiply:
+		return val1 * val2, nil
+	case Divide:
+		if val2 == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return val1 / val2, nil
+	}
+	return nil, fmt.Errorf("unknown operator %d", op)
+}
+
+// applyDouble
+// an operation with at least one double operand, an int operand is
+// promoted to a double
+func (op ArithmeticOperator) applyDouble(v1 any, v2 any) (any, error) {
+	val1, ok1 := toDouble(v1)
+	val2, ok2 := toDouble(v2)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%s needs two numbers, got %v and %v", op, v1, v2)
+	}
+	switch op {
+	case Add:
+		return val1 + val2, nil
+	case Subtract:
+		return val1 - val2, nil
+	case Multiply:
+		return val1 * val2, nil
+	case Divide:
+		if val2 == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return val1 / val2, nil
+	}
+	return nil, fmt.Errorf("unknown operator %d", op)
+}
+
+func toDouble(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case float64:
+		return val, true
+	}
+	return 0, false
+}
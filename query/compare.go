@@ -0,0 +1,65 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"jadb/record"
+	"time"
+)
+
+// CompareValues
+// -1, 0 or 1 depending on whether v1 is less than, equal to or greater than v2,
+// nulls sort before every other value
+func CompareValues(v1 any, v2 any) (int, error) {
+	if v1 == nil || v2 == nil {
+		switch {
+		case v1 == v2:
+			return 0, nil
+		case v1 == nil:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	switch val1 := v1.(type) {
+	case int:
+		switch val2 := v2.(type) {
+		case int:
+			return cmp.Compare(val1, val2), nil
+		case float64:
+			return cmp.Compare(float64(val1), val2), nil
+		}
+	case float64:
+		switch val2 := v2.(type) {
+		case int:
+			return cmp.Compare(val1, float64(val2)), nil
+		case float64:
+			return cmp.Compare(val1, val2), nil
+		}
+	case string:
+		if val2, ok := v2.(string); ok {
+			return cmp.Compare(val1, val2), nil
+		}
+	case bool:
+		//false sorts before true
+		if val2, ok := v2.(bool); ok {
+			return cmp.Compare(boolRank(val1), boolRank(val2)), nil
+		}
+	case time.Time:
+		if val2, ok := v2.(time.Time); ok {
+			return val1.Compare(val2), nil
+		}
+	case record.Blob:
+		if val2, ok := v2.(record.Blob); ok {
+			return cmp.Compare(val1, val2), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v with %v", v1, v2)
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -2,13 +2,29 @@ package query
 
 import (
 	"fmt"
+	"jadb/constants"
+	"jadb/file"
 	"jadb/record"
 	"jadb/scan"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// Expression
+// a constant, a field, a list of expressions for in, a subquery, a field
+// of an outer query read from the params of a correlated subquery, or an
+// arithmetic operation on two expressions
 type Expression struct {
-	value   any
-	fldName string
+	value    any
+	fldName  string
+	list     []*Expression
+	subquery *Subquery
+	params   *Params
+	op       ArithmeticOperator
+	lhs      *Expression
+	rhs      *Expression
 }
 
 func NewFieldExpression(fldName string) *Expression {
@@ -19,15 +35,105 @@ func NewConstantExpression(value any) *Expression {
 	return &Expression{value: value, fldName: ""}
 }
 
-func (e *Expression) evaluate(scan scan.Scan) (any, error) {
-	if e.value != nil {
+func NewListExpression(list []*Expression) *Expression {
+	return &Expression{list: list}
+}
+
+func NewSubqueryExpression(subquery *Subquery) *Expression {
+	return &Expression{subquery: subquery}
+}
+
+// NewParamExpression
+// the outer field fldName, read from params
+func NewParamExpression(fldName string, params *Params) *Expression {
+	return &Expression{fldName: fldName, params: params}
+}
+
+// NewArithmeticExpression
+// lhs op rhs
+func NewArithmeticExpression(lhs *Expression, op ArithmeticOperator, rhs *Expression) *Expression {
+	return &Expression{op: op, lhs: lhs, rhs: rhs}
+}
+
+// Evaluate
+// value of the expression for the current record of scan, a subquery
+// evaluates to its single value
+func (e *Expression) Evaluate(scan scan.Scan) (any, error) {
+	switch {
+	case e.lhs != nil:
+		v1, err := e.lhs.Evaluate(scan)
+		if err != nil {
+			return nil, err
+		}
+		v2, err := e.rhs.Evaluate(scan)
+		if err != nil {
+			return nil, err
+		}
+		return e.op.apply(v1, v2)
+	case e.params != nil:
+		return e.params.Get(e.fldName), nil
+	case e.subquery != nil:
+		return e.subquery.scalar(scan)
+	case e.list != nil:
+		return nil, fmt.Errorf("list %s can only be used with in", e)
+	case !e.IsFieldName():
 		return e.value, nil
 	}
 	return scan.GetVal(e.fldName)
 }
 
+// Type
+// the type and length of the values of the expression, column gives those
+// of a field, a param or a subquery. An operation on ints is an int, a
+// bigint when one of them is, and an operation on a double a double. A
+// null constant is an int and a time a timestamp
+func (e *Expression) Type(column func(*Expression) (int, int, error)) (int, int, error) {
+	switch {
+	case e.lhs != nil:
+		lhsType, _, err := e.lhs.Type(column)
+		if err != nil {
+			return 0, 0, err
+		}
+		rhsType, _, err := e.rhs.Type(column)
+		if err != nil {
+			return 0, 0, err
+		}
+		numeric := func(fldType int) bool {
+			return fldType == record.INTEGER || fldType == record.BIGINT || fldType == record.DOUBLE
+		}
+		switch {
+		case !numeric(lhsType) || !numeric(rhsType):
+			return 0, 0, fmt.Errorf("%s needs two numbers in %s", e.op, e)
+		case lhsType == record.DOUBLE || rhsType == record.DOUBLE:
+			return record.DOUBLE, constants.DoubleSize, nil
+		case lhsType == record.BIGINT || rhsType == record.BIGINT:
+			return record.BIGINT, constants.IntSize, nil
+		}
+		return record.INTEGER, constants.IntSize, nil
+	case e.list != nil:
+		return 0, 0, fmt.Errorf("list %s can only be used with in", e)
+	case e.subquery != nil || e.fldName != "":
+		return column(e)
+	}
+	switch val := e.value.(type) {
+	case nil, int:
+		return record.INTEGER, constants.IntSize, nil
+	case float64:
+		return record.DOUBLE, constants.DoubleSize, nil
+	case string:
+		return record.VARCHAR, file.MaxLength(utf8.RuneCountInString(val)), nil
+	case bool:
+		return record.BOOLEAN, constants.BoolSize, nil
+	case time.Time:
+		return record.TIMESTAMP, constants.IntSize, nil
+	case record.Blob:
+		return record.BLOB, constants.IntSize + len(val), nil
+	}
+	return 0, 0, fmt.Errorf("constant %s has no type", e)
+}
+
 func (e *Expression) IsFieldName() bool {
-	return e.fldName != ""
+	return e.fldName != "" && e.params == nil
 }
 
 func (e *Expression) asConstant() any {
@@ -38,13 +144,123 @@ func (e *Expression) asFieldName() string {
 	return e.fldName
 }
 
+// ResolveFields
+// copy of the expression with its field replaced by the expression
+// resolve returns for it
+func (e *Expression) ResolveFields(resolve func(string) (*Expression, error)) (*Expression, error) {
+	if e.lhs != nil {
+		lhs, err := e.lhs.ResolveFields(resolve)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := e.rhs.ResolveFields(resolve)
+		if err != nil {
+			return nil, err
+		}
+		return NewArithmeticExpression(lhs, e.op, rhs), nil
+	}
+	if e.list != nil {
+		list := make([]*Expression, len(e.list))
+		for i, item := range e.list {
+			resolved, err := item.ResolveFields(resolve)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return NewListExpression(list), nil
+	}
+	if !e.IsFieldName() {
+		return e, nil
+	}
+	return resolve(e.fldName)
+}
+
+// Subqueries
+// subqueries directly contained in the expression
+func (e *Expression) Subqueries() []*Subquery {
+	if e.subquery != nil {
+		return []*Subquery{e.subquery}
+	}
+	subqueries := make([]*Subquery, 0)
+	for _, item := range e.operands() {
+		subqueries = append(subqueries, item.Subqueries()...)
+	}
+	return subqueries
+}
+
+// operands
+// the expressions directly contained in the expression
+func (e *Expression) operands() []*Expression {
+	if e.lhs != nil {
+		return []*Expression{e.lhs, e.rhs}
+	}
+	return e.list
+}
+
+// AppliesTo
+// a subquery may refer to fields outside of schema, so it never applies
 func (e *Expression) AppliesTo(schema *record.Schema) bool {
-	return e.value != nil || schema.HasField(e.fldName)
+	if len(e.Subqueries()) > 0 {
+		return false
+	}
+	for _, item := range e.operands() {
+		if !item.AppliesTo(schema) {
+			return false
+		}
+	}
+	return !e.IsFieldName() || schema.HasField(e.fldName)
 }
 
 func (e *Expression) String() string {
-	if e.value != nil {
-		return fmt.Sprintf("%v", e.value)
+	switch {
+	case e.lhs != nil:
+		//operands binding looser than the operator need parentheses, on
+		//the right also those binding as tight since - and / are not
+		//associative
+		lhs, rhs := e.lhs.String(), e.rhs.String()
+		if e.lhs.lhs != nil && e.lhs.op.precedence() < e.op.precedence() {
+			lhs = "(" + lhs + ")"
+		}
+		if e.rhs.lhs != nil && e.rhs.op.precedence() <= e.op.precedence() {
+			rhs = "(" + rhs + ")"
+		}
+		return lhs + e.op.String() + rhs
+	case e.subquery != nil:
+		return e.subquery.String()
+	case e.list != nil:
+		items := make([]string, len(e.list))
+		for i, item := range e.list {
+			items[i] = item.String()
+		}
+		return "(" + strings.Join(items, ", ") + ")"
+	case e.fldName != "":
+		return e.fldName
 	}
-	return e.fldName
+	switch val := e.value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("'%s'", val)
+	case float64:
+		//a double always has a fraction or an exponent so it reads back
+		//as a double
+		str := strconv.FormatFloat(val, 'g', -1, 64)
+		if !strings.ContainsAny(str, ".eEnN") {
+			str += ".0"
+		}
+		return str
+	case time.Time:
+		if val.Equal(val.Truncate(24 * time.Hour)) {
+			return fmt.Sprintf("date '%s'", val.Format(time.DateOnly))
+		}
+		return fmt.Sprintf("timestamp '%s'", val.Format(TimestampFormat))
+	case record.Blob:
+		return fmt.Sprintf("x'%x'", string(val))
+	}
+	return fmt.Sprintf("%v", e.value)
 }
+
+// TimestampFormat
+// the layout timestamp literals are written and read in
+const TimestampFormat = "2006-01-02 15:04:05.999999"
@@ -0,0 +1,30 @@
+package query
+
+// JoinType
+// how the rows of two tables are combined, outer joins keep the rows
+// without a match and pad the other side with nulls
+type JoinType int
+
+const (
+	CrossJoin JoinType = iota
+	InnerJoin
+	LeftJoin
+	RightJoin
+	FullJoin
+)
+
+func (j JoinType) String() string {
+	switch j {
+	case CrossJoin:
+		return "cross join"
+	case InnerJoin:
+		return "join"
+	case LeftJoin:
+		return "left join"
+	case RightJoin:
+		return "right join"
+	case FullJoin:
+		return "full join"
+	}
+	return ""
+}
@@ -9,6 +9,12 @@ const (
 	LessThanEqual
 	GreaterThan
 	GreaterThanEqual
+	Is
+	IsNot
+	In
+	NotIn
+	Exists
+	NotExists
 )
 
 func (op Operator) String() string {
@@ -25,6 +31,18 @@ func (op Operator) String() string {
 		return ">"
 	case GreaterThanEqual:
 		return ">="
+	case Is:
+		return " is "
+	case IsNot:
+		return " is not "
+	case In:
+		return " in "
+	case NotIn:
+		return " not in "
+	case Exists:
+		return "exists "
+	case NotExists:
+		return "not exists "
 	}
 	return ""
 }
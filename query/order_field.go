@@ -0,0 +1,25 @@
+package query
+
+type OrderField struct {
+	fldName    string
+	descending bool
+}
+
+func NewOrderField(fldName string, descending bool) *OrderField {
+	return &OrderField{fldName, descending}
+}
+
+func (o *OrderField) FieldName() string {
+	return o.fldName
+}
+
+func (o *OrderField) IsDescending() bool {
+	return o.descending
+}
+
+func (o *OrderField) String() string {
+	if o.descending {
+		return o.fldName + " desc"
+	}
+	return o.fldName
+}
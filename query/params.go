@@ -0,0 +1,20 @@
+package query
+
+// Params
+// values of the outer fields a correlated subquery refers to, they are set
+// before the subquery is evaluated for a record of the outer query
+type Params struct {
+	values map[string]any
+}
+
+func NewParams() *Params {
+	return &Params{make(map[string]any)}
+}
+
+func (p *Params) Set(name string, val any) {
+	p.values[name] = val
+}
+
+func (p *Params) Get(name string) any {
+	return p.values[name]
+}
@@ -22,13 +22,52 @@ func (p *Predicate) CojoinWith(other *Predicate) {
 	p.terms = append(p.terms, other.terms...)
 }
 
-func (p *Predicate) IsSatisfied(inputScan scan.Scan) bool {
+func (p *Predicate) IsSatisfied(inputScan scan.Scan) (bool, error) {
+	result, err := p.Evaluate(inputScan)
+	return result == True, err
+}
+
+// Evaluate
+// conjunction of the terms, an empty predicate is True
+func (p *Predicate) Evaluate(inputScan scan.Scan) (Truth, error) {
+	result := True
 	for _, term := range p.terms {
-		if !term.IsSatisfied(inputScan) {
-			return false
+		termResult, err := term.Evaluate(inputScan)
+		if err != nil {
+			return False, err
 		}
+		result = result.And(termResult)
+		if result == False {
+			return False, nil
+		}
+	}
+	return result, nil
+}
+
+// ResolveFields
+// copy of the predicate with every field replaced by the expression
+// resolve returns for it, used by the planner to resolve field names
+// against the tables in a query
+func (p *Predicate) ResolveFields(resolve func(string) (*Expression, error)) (*Predicate, error) {
+	result := NewPredicate()
+	for _, term := range p.terms {
+		resolved, err := term.ResolveFields(resolve)
+		if err != nil {
+			return nil, err
+		}
+		result.terms = append(result.terms, resolved)
+	}
+	return result, nil
+}
+
+// Subqueries
+// every subquery in the predicate, nested subqueries excluded
+func (p *Predicate) Subqueries() []*Subquery {
+	subqueries := make([]*Subquery, 0)
+	for _, term := range p.terms {
+		subqueries = append(subqueries, term.Subqueries()...)
 	}
-	return true
+	return subqueries
 }
 
 func (p *Predicate) ReductionFactor(queryPlan plan.Plan) int {
@@ -0,0 +1,23 @@
+package query
+
+// SetOperator
+// how the records of two queries with compatible schemas are combined
+type SetOperator int
+
+const (
+	Union SetOperator = iota
+	Intersect
+	Except
+)
+
+func (op SetOperator) String() string {
+	switch op {
+	case Union:
+		return "union"
+	case Intersect:
+		return "intersect"
+	case Except:
+		return "except"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package query
+
+import (
+	"fmt"
+	"jadb/scan"
+)
+
+// SubqueryRows
+// reads at most limit records of a subquery for the current record of
+// outer, every record when limit is negative
+type SubqueryRows func(outer scan.Scan, limit int) ([][]any, error)
+
+// Subquery
+// a select nested in a predicate or select list. The parser only keeps its
+// definition, the planner binds it to the rows of its plan. An uncorrelated
+// subquery is materialised the first time it is evaluated and reused after
+// that, a correlated one is evaluated again for every outer record
+type Subquery struct {
+	def          fmt.Stringer
+	singleColumn bool
+	rows         SubqueryRows
+	correlated   bool
+	result       *subqueryResult
+}
+
+type subqueryResult struct {
+	rows    [][]any
+	values  map[any]bool
+	hasNull bool
+}
+
+// NewSubquery
+// def is the parsed select, singleColumn is set for scalar and in
+// subqueries that have to return exactly one column
+func NewSubquery(def fmt.Stringer, singleColumn bool) *Subquery {
+	return &Subquery{def: def, singleColumn: singleColumn}
+}
+
+func (s *Subquery) Definition() fmt.Stringer {
+	return s.def
+}
+
+func (s *Subquery) IsSingleColumn() bool {
+	return s.singleColumn
+}
+
+func (s *Subquery) Bind(rows SubqueryRows, correlated bool) {
+	s.rows = rows
+	s.correlated = correlated
+	s.result = nil
+}
+
+func (s *Subquery) IsCorrelated() bool {
+	return s.correlated
+}
+
+// exists
+// true when the subquery returns at least one record
+func (s *Subquery) exists(outer scan.Scan) (bool, error) {
+	if s.correlated {
+		rows, err := s.read(outer, 1)
+		return len(rows) > 0, err
+	}
+	result, err := s.materialise(outer)
+	if err != nil {
+		return false, err
+	}
+	return len(result.rows) > 0, nil
+}
+
+// scalar
+// the single value returned by the subquery, null when it returns no record
+func (s *Subquery) scalar(outer scan.Scan) (any, error) {
+	var rows [][]any
+	var err error
+	if s.correlated {
+		rows, err = s.read(outer, 2)
+	} else {
+		var result *subqueryResult
+		result, err = s.materialise(outer)
+		if result != nil {
+			rows = result.rows
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch len(rows) {
+	case 0:
+		return nil, nil
+	case 1:
+		return rows[0][0], nil
+	}
+	return nil, fmt.Errorf("subquery %s returned more than one record", s)
+}
+
+// contains
+// whether val is among the values returned by the subquery, Unknown
+// when it is not found but a null was
+func (s *Subquery) contains(outer scan.Scan, val any) (Truth, error) {
+	result, err := s.materialise(outer)
+	if err != nil {
+		return False, err
+	}
+	if len(result.rows) == 0 {
+		return False, nil
+	}
+	if val == nil {
+		return Unknown, nil
+	}
+	if result.values[val] {
+		return True, nil
+	}
+	if result.hasNull {
+		return Unknown, nil
+	}
+	return False, nil
+}
+
+func (s *Subquery) materialise(outer scan.Scan) (*subqueryResult, error) {
+	if s.result != nil && !s.correlated {
+		return s.result, nil
+	}
+	rows, err := s.read(outer, -1)
+	if err != nil {
+		return nil, err
+	}
+	result := &subqueryResult{rows: rows, values: make(map[any]bool)}
+	for _, row := range rows {
+		if row[0] == nil {
+			result.hasNull = true
+		} else {
+			result.values[row[0]] = true
+		}
+	}
+	s.result = result
+	return result, nil
+}
+
+func (s *Subquery) read(outer scan.Scan, limit int) ([][]any, error) {
+	if s.rows == nil {
+		return nil, fmt.Errorf("subquery %s has not been planned", s)
+	}
+	return s.rows(outer, limit)
+}
+
+func (s *Subquery) String() string {
+	return "(" + s.def.String() + ")"
+}
@@ -1,11 +1,15 @@
 package query
 
 import (
+	"fmt"
 	"jadb/plan"
 	"jadb/record"
 	"jadb/scan"
 )
 
+// Term
+// a comparison of two expressions. For exists and not exists lhe is the
+// subquery and rhe is nil
 type Term struct {
 	lhe *Expression
 	rhe *Expression
@@ -16,30 +20,141 @@ func NewTerm(lhe *Expression, rhe *Expression, op Operator) *Term {
 	return &Term{lhe, rhe, op}
 }
 
-func (t *Term) IsSatisfied(inputScan scan.Scan) bool {
-	var lheVal any
-	var err error
-	var rheVal any
-	if lheVal, err = t.lhe.evaluate(inputScan); err != nil {
-		return false
+// NewExistsTerm
+// exists (subquery), or not exists when negated
+func NewExistsTerm(subquery *Subquery, negated bool) *Term {
+	if negated {
+		return &Term{NewSubqueryExpression(subquery), nil, NotExists}
 	}
-	if rheVal, err = t.rhe.evaluate(inputScan); err != nil {
-		return false
+	return &Term{NewSubqueryExpression(subquery), nil, Exists}
+}
+
+func (t *Term) IsSatisfied(inputScan scan.Scan) (bool, error) {
+	result, err := t.Evaluate(inputScan)
+	return result == True, err
+}
+
+// Evaluate
+// Unknown when a null is compared with anything but is [not]
+func (t *Term) Evaluate(inputScan scan.Scan) (Truth, error) {
+	switch t.op {
+	case Exists, NotExists:
+		exists, err := t.lhe.subquery.exists(inputScan)
+		if err != nil {
+			return False, err
+		}
+		return truthOf(exists == (t.op == Exists)), nil
+	}
+	lheVal, err := t.lhe.Evaluate(inputScan)
+	if err != nil {
+		return False, err
+	}
+	switch t.op {
+	case In:
+		return t.contains(inputScan, lheVal)
+	case NotIn:
+		result, err := t.contains(inputScan, lheVal)
+		return result.Not(), err
+	}
+	rheVal, err := t.rhe.Evaluate(inputScan)
+	if err != nil {
+		return False, err
 	}
 	switch t.op {
+	case Is:
+		return truthOf(lheVal == rheVal), nil
+	case IsNot:
+		return truthOf(lheVal != rheVal), nil
+	}
+	return compare(lheVal, rheVal, t.op)
+}
+
+// contains
+// lheVal in rhe, where rhe is a list or a subquery
+func (t *Term) contains(inputScan scan.Scan, lheVal any) (Truth, error) {
+	if t.rhe.subquery != nil {
+		return t.rhe.subquery.contains(inputScan, lheVal)
+	}
+	result := False
+	for _, item := range t.rhe.list {
+		val, err := item.Evaluate(inputScan)
+		if err != nil {
+			return False, err
+		}
+		equal, err := compare(lheVal, val, Equal)
+		if err != nil {
+			return False, err
+		}
+		result = result.Or(equal)
+		if result == True {
+			return True, nil
+		}
+	}
+	return result, nil
+}
+
+func compare(lheVal any, rheVal any, op Operator) (Truth, error) {
+	if lheVal == nil || rheVal == nil {
+		return Unknown, nil
+	}
+	result, err := CompareValues(lheVal, rheVal)
+	switch op {
 	case Equal:
-		return lheVal == rheVal
+		//values of types that cannot be compared are never equal
+		return truthOf(err == nil && result == 0), nil
 	case NotEqual:
-		return lheVal != rheVal
+		return truthOf(err != nil || result != 0), nil
+	}
+	if err != nil {
+		return False, err
 	}
-	return false
+	switch op {
+	case LessThan:
+		return truthOf(result < 0), nil
+	case LessThanEqual:
+		return truthOf(result <= 0), nil
+	case GreaterThan:
+		return truthOf(result > 0), nil
+	case GreaterThanEqual:
+		return truthOf(result >= 0), nil
+	}
+	return False, fmt.Errorf("unknown operator %d", op)
+}
+
+// ResolveFields
+// copy of the term with every field replaced by the expression resolve
+// returns for it
+func (t *Term) ResolveFields(resolve func(string) (*Expression, error)) (*Term, error) {
+	lhe, err := t.lhe.ResolveFields(resolve)
+	if err != nil {
+		return nil, err
+	}
+	if t.rhe == nil {
+		return NewTerm(lhe, nil, t.op), nil
+	}
+	rhe, err := t.rhe.ResolveFields(resolve)
+	if err != nil {
+		return nil, err
+	}
+	return NewTerm(lhe, rhe, t.op), nil
+}
+
+func (t *Term) Subqueries() []*Subquery {
+	subqueries := t.lhe.Subqueries()
+	if t.rhe != nil {
+		subqueries = append(subqueries, t.rhe.Subqueries()...)
+	}
+	return subqueries
 }
 
 func (t *Term) AppliesTo(schema *record.Schema) bool {
-	return t.lhe.AppliesTo(schema) && t.rhe.AppliesTo(schema)
+	return t.lhe.AppliesTo(schema) && (t.rhe == nil || t.rhe.AppliesTo(schema))
 }
 
 func (t *Term) reductionFactor(queryPlan plan.Plan) int {
+	if t.op != Equal {
+		return 1
+	}
 	if t.lhe.IsFieldName() && t.rhe.IsFieldName() {
 		return max(queryPlan.DistinctValues(t.lhe.asFieldName()),
 			queryPlan.DistinctValues(t.rhe.asFieldName()))
@@ -57,6 +172,9 @@ func (t *Term) reductionFactor(queryPlan plan.Plan) int {
 }
 
 func (t *Term) equatesWithConstant(fldName string) any {
+	if t.op != Equal {
+		return nil
+	}
 	if t.lhe.IsFieldName() && t.lhe.asFieldName() == fldName && !t.rhe.IsFieldName() {
 		return t.rhe.asConstant()
 	} else if t.rhe.IsFieldName() && t.rhe.asFieldName() == fldName && !t.lhe.IsFieldName() {
@@ -66,6 +184,9 @@ func (t *Term) equatesWithConstant(fldName string) any {
 }
 
 func (t *Term) equatesWithField(fldName string) string {
+	if t.op != Equal {
+		return ""
+	}
 	if t.lhe.IsFieldName() && t.lhe.asFieldName() == fldName && t.rhe.IsFieldName() {
 		return t.rhe.asFieldName()
 	} else if t.rhe.IsFieldName() && t.rhe.asFieldName() == fldName && t.lhe.IsFieldName() {
@@ -75,5 +196,15 @@ func (t *Term) equatesWithField(fldName string) string {
 }
 
 func (t *Term) String() string {
+	if t.rhe == nil {
+		return t.op.String() + t.lhe.String()
+	}
 	return t.lhe.String() + t.op.String() + t.rhe.String()
 }
+
+func truthOf(b bool) Truth {
+	if b {
+		return True
+	}
+	return False
+}
@@ -0,0 +1,36 @@
+package query
+
+// Truth
+// result of evaluating a condition under three valued logic, a comparison
+// with null is Unknown and a record is only selected when its predicate is True
+type Truth int
+
+const (
+	False Truth = iota
+	Unknown
+	True
+)
+
+func (t Truth) And(other Truth) Truth {
+	return min(t, other)
+}
+
+func (t Truth) Or(other Truth) Truth {
+	return max(t, other)
+}
+
+func (t Truth) Not() Truth {
+	return True - t
+}
+
+func (t Truth) String() string {
+	switch t {
+	case False:
+		return "false"
+	case Unknown:
+		return "unknown"
+	case True:
+		return "true"
+	}
+	return ""
+}
@@ -0,0 +1,208 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// WindowFunctionType
+// the function computed over the window of every record
+type WindowFunctionType int
+
+const (
+	RowNumber WindowFunctionType = iota
+	Rank
+	DenseRank
+	Lag
+	Lead
+	Sum
+	Avg
+)
+
+func (w WindowFunctionType) String() string {
+	switch w {
+	case RowNumber:
+		return "row_number"
+	case Rank:
+		return "rank"
+	case DenseRank:
+		return "dense_rank"
+	case Lag:
+		return "lag"
+	case Lead:
+		return "lead"
+	case Sum:
+		return "sum"
+	case Avg:
+		return "avg"
+	}
+	return ""
+}
+
+// IsRanking
+// true for the functions without an argument
+func (w WindowFunctionType) IsRanking() bool {
+	return w == RowNumber || w == Rank || w == DenseRank
+}
+
+const (
+	UnboundedPreceding = math.MinInt
+	UnboundedFollowing = math.MaxInt
+)
+
+// Frame
+// the records of the partition sum and avg are computed over, start and
+// end are relative to the current record, negative ones precede it
+type Frame struct {
+	start int
+	end   int
+}
+
+func NewFrame(start int, end int) *Frame {
+	return &Frame{start, end}
+}
+
+func (f *Frame) Start() int {
+	return f.start
+}
+
+func (f *Frame) End() int {
+	return f.end
+}
+
+func (f *Frame) String() string {
+	return "rows between " + frameBound(f.start) + " and " + frameBound(f.end)
+}
+
+func frameBound(offset int) string {
+	switch {
+	case offset == UnboundedPreceding:
+		return "unbounded preceding"
+	case offset == UnboundedFollowing:
+		return "unbounded following"
+	case offset < 0:
+		return fmt.Sprintf("%d preceding", -offset)
+	case offset > 0:
+		return fmt.Sprintf("%d following", offset)
+	}
+	return "current row"
+}
+
+// WindowFunction
+// fn computed for every record over the records of its partition, the
+// records with the same values for partitionBy, in the order of orderBy.
+// fldName is the argument of lag, lead, sum and avg, lag and lead read it
+// offset records away and return defaultVal when there is no such record.
+// Without a frame sum and avg cover the partition up to the last record
+// ordered the same as the current one, or the whole partition when it is
+// not ordered
+type WindowFunction struct {
+	fn          WindowFunctionType
+	fldName     string
+	offset      int
+	defaultVal  any
+	partitionBy []string
+	orderBy     []*OrderField
+	frame       *Frame
+}
+
+func NewWindowFunction(fn WindowFunctionType, fldName string, offset int, defaultVal any,
+	partitionBy []string, orderBy []*OrderField, frame *Frame) *WindowFunction {
+	return &WindowFunction{fn, fldName, offset, defaultVal, partitionBy, orderBy, frame}
+}
+
+func (w *WindowFunction) Function() WindowFunctionType {
+	return w.fn
+}
+
+func (w *WindowFunction) FieldName() string {
+	return w.fldName
+}
+
+func (w *WindowFunction) Offset() int {
+	return w.offset
+}
+
+func (w *WindowFunction) Default() any {
+	return w.defaultVal
+}
+
+func (w *WindowFunction) PartitionBy() []string {
+	return w.partitionBy
+}
+
+func (w *WindowFunction) OrderBy() []*OrderField {
+	return w.orderBy
+}
+
+// Frame
+// nil when the function has the default frame
+func (w *WindowFunction) Frame() *Frame {
+	return w.frame
+}
+
+// ResolveFields
+// a copy of w with its field names replaced by resolve
+func (w *WindowFunction) ResolveFields(resolve func(string) (string, error)) (*WindowFunction, error) {
+	resolved := *w
+	var err error
+	if w.fldName != "" {
+		if resolved.fldName, err = resolve(w.fldName); err != nil {
+			return nil, err
+		}
+	}
+	resolved.partitionBy = make([]string, len(w.partitionBy))
+	for i, fldName := range w.partitionBy {
+		if resolved.partitionBy[i], err = resolve(fldName); err != nil {
+			return nil, err
+		}
+	}
+	resolved.orderBy = make([]*OrderField, len(w.orderBy))
+	for i, field := range w.orderBy {
+		fldName, err := resolve(field.FieldName())
+		if err != nil {
+			return nil, err
+		}
+		resolved.orderBy[i] = NewOrderField(fldName, field.IsDescending())
+	}
+	return &resolved, nil
+}
+
+// Window
+// the partition by and order by of the function, functions with the same
+// window can be computed over the same sorted records
+func (w *WindowFunction) Window() string {
+	parts := make([]string, 0, 2)
+	if len(w.partitionBy) > 0 {
+		parts = append(parts, "partition by "+strings.Join(w.partitionBy, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		orderBy := make([]string, len(w.orderBy))
+		for i, field := range w.orderBy {
+			orderBy[i] = field.String()
+		}
+		parts = append(parts, "order by "+strings.Join(orderBy, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (w *WindowFunction) String() string {
+	args := make([]string, 0, 3)
+	if w.fldName != "" {
+		args = append(args, w.fldName)
+	}
+	if w.fn == Lag || w.fn == Lead {
+		if w.offset != 1 || w.defaultVal != nil {
+			args = append(args, fmt.Sprint(w.offset))
+		}
+		if w.defaultVal != nil {
+			args = append(args, NewConstantExpression(w.defaultVal).String())
+		}
+	}
+	over := w.Window()
+	if w.frame != nil {
+		over = strings.TrimSpace(over + " " + w.frame.String())
+	}
+	return fmt.Sprintf("%s(%s) over (%s)", w.fn, strings.Join(args, ", "), over)
+}
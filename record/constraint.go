@@ -0,0 +1,172 @@
+package record
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConstraintType
+// the rule a constraint enforces. A primary key is unique and none of its
+// fields can be null, a foreign key refers to a record of another table.
+// A check is a condition no record can make false, and a default the value
+// a field gets when an insert leaves it out
+type ConstraintType int
+
+const (
+	PrimaryKey ConstraintType = iota
+	Unique
+	NotNull
+	ForeignKey
+	Check
+	Default
+)
+
+func (c ConstraintType) String() string {
+	switch c {
+	case PrimaryKey:
+		return "primary key"
+	case Unique:
+		return "unique"
+	case NotNull:
+		return "not null"
+	case ForeignKey:
+		return "foreign key"
+	case Check:
+		return "check"
+	case Default:
+		return "default"
+	}
+	return ""
+}
+
+// ReferentialAction
+// what happens to the records referring to a deleted record
+type ReferentialAction int
+
+const (
+	Restrict ReferentialAction = iota
+	Cascade
+	SetNull
+)
+
+func (r ReferentialAction) String() string {
+	switch r {
+	case Restrict:
+		return "restrict"
+	case Cascade:
+		return "cascade"
+	case SetNull:
+		return "set null"
+	}
+	return ""
+}
+
+// Constraint
+// a rule the records of a table have to follow on some of its fields. A
+// constraint declared without a name gets one when its table is created.
+// The condition of a check and the value of a default are kept as the
+// text of their definition
+type Constraint struct {
+	name       string
+	kind       ConstraintType
+	fields     []string
+	refTable   string
+	refFields  []string
+	onDelete   ReferentialAction
+	definition string
+}
+
+func NewConstraint(name string, kind ConstraintType, fields []string) *Constraint {
+	return &Constraint{name: name, kind: kind, fields: fields}
+}
+
+// NewForeignKey
+// fields refer to refFields of refTable, which are its primary key when
+// refFields is empty
+func NewForeignKey(name string, fields []string, refTable string, refFields []string,
+	onDelete ReferentialAction) *Constraint {
+	return &Constraint{name: name, kind: ForeignKey, fields: fields, refTable: refTable, refFields: refFields,
+		onDelete: onDelete}
+}
+
+// NewCheck
+// definition is the condition, fields the fields it refers to
+func NewCheck(name string, fields []string, definition string) *Constraint {
+	return &Constraint{name: name, kind: Check, fields: fields, definition: definition}
+}
+
+// NewDefault
+// definition is the constant the field gets
+func NewDefault(name string, fldName string, definition string) *Constraint {
+	return &Constraint{name: name, kind: Default, fields: []string{fldName}, definition: definition}
+}
+
+// Named
+// a copy of the constraint with another name
+func (c *Constraint) Named(name string) *Constraint {
+	named := *c
+	named.name = name
+	return &named
+}
+
+func (c *Constraint) Name() string {
+	return c.name
+}
+
+func (c *Constraint) Type() ConstraintType {
+	return c.kind
+}
+
+func (c *Constraint) Fields() []string {
+	return c.fields
+}
+
+func (c *Constraint) RefTable() string {
+	return c.refTable
+}
+
+func (c *Constraint) RefFields() []string {
+	return c.refFields
+}
+
+func (c *Constraint) OnDelete() ReferentialAction {
+	return c.onDelete
+}
+
+func (c *Constraint) Definition() string {
+	return c.definition
+}
+
+// IsUnique
+// true when no two records can have the same values for the fields
+func (c *Constraint) IsUnique() bool {
+	return c.kind == PrimaryKey || c.kind == Unique
+}
+
+// IsNotNull
+// true when none of the fields can be null
+func (c *Constraint) IsNotNull() bool {
+	return c.kind == PrimaryKey || c.kind == NotNull
+}
+
+func (c *Constraint) String() string {
+	result := ""
+	if c.name != "" {
+		result = "constraint " + c.name + " "
+	}
+	if c.kind == Check {
+		return result + "check (" + c.definition + ")"
+	}
+	result += fmt.Sprintf("%s (%s)", c.kind, strings.Join(c.fields, ", "))
+	if c.kind == Default {
+		return result + " " + c.definition
+	}
+	if c.kind != ForeignKey {
+		return result
+	}
+	result += " references " + c.refTable
+	if len(c.refFields) > 0 {
+		result += " (" + strings.Join(c.refFields, ", ") + ")"
+	}
+	return result + " on delete " + c.onDelete.String()
+}
@@ -3,8 +3,49 @@ package record
 const (
 	INTEGER = iota
 	VARCHAR
+	BIGINT
+	BOOLEAN
+	DOUBLE
+	DATE
+	TIMESTAMP
+	BLOB
 )
 
+// TypeName
+// the values of the field type, for messages
+func TypeName(fieldType int) string {
+	switch fieldType {
+	case INTEGER:
+		return "an int"
+	case VARCHAR:
+		return "a string"
+	case BIGINT:
+		return "a bigint"
+	case BOOLEAN:
+		return "a boolean"
+	case DOUBLE:
+		return "a double"
+	case DATE:
+		return "a date"
+	case TIMESTAMP:
+		return "a timestamp"
+	case BLOB:
+		return "a blob"
+	}
+	return "an unknown type"
+}
+
+// isVariable
+// true for the types whose values take only their own length in a record
+func isVariable(fieldType int) bool {
+	return fieldType == VARCHAR || fieldType == BLOB
+}
+
+// Blob
+// the value of a blob field. It holds the bytes in a string so that values
+// compare with == and can be map keys like the values of the other types
+type Blob string
+
 type FieldInfo struct {
 	fieldType int
 	length    int
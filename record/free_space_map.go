@@ -0,0 +1,116 @@
+package record
+
+import (
+	"jadb/file"
+	"jadb/tx"
+)
+
+// freeSpaceLevels
+// the entry of a block is the space its records take in units of
+// 1/freeSpaceLevels of the block, rounded up, so it fits in a byte
+const freeSpaceLevels = 255
+
+// FreeSpaceMap
+// a file with a byte for every block of a table file telling how much of
+// the block is taken, so an insert goes straight to a block with room
+// instead of reading the blocks before it. The map is a hint: a block
+// whose entry is out of date is found full on insert and its entry
+// corrected, and a block without an entry yet counts as empty
+type FreeSpaceMap struct {
+	tx       *tx.Transaction
+	filename string
+}
+
+func NewFreeSpaceMap(tx *tx.Transaction, filename string) *FreeSpaceMap {
+	return &FreeSpaceMap{
+		tx,
+		filename,
+	}
+}
+
+// Find
+// the first block from start up to size whose entry leaves room for a new
+// record of layout, -1 when there is none
+func (fsm *FreeSpaceMap) Find(layout *Layout, start int, size int) (int, error) {
+	blockSize := fsm.tx.BlockSize()
+	needed := layout.insertSize(blockSize)
+	mapSize, err := fsm.tx.Size(fsm.filename)
+	if err != nil {
+		return -1, err
+	}
+	for blockNumber := start; blockNumber < size; {
+		mapBlock := blockNumber / blockSize
+		if mapBlock >= mapSize {
+			return blockNumber, nil
+		}
+		blk := file.NewBlock(fsm.filename, mapBlock)
+		if err := fsm.tx.Pin(blk); err != nil {
+			return -1, err
+		}
+		entries, err := fsm.tx.GetRaw(blk, 0, blockSize)
+		fsm.tx.Unpin(blk)
+		if err != nil {
+			return -1, err
+		}
+		for ; blockNumber < size && blockNumber/blockSize == mapBlock; blockNumber++ {
+			if blockSize-int(entries[blockNumber%blockSize])*freeSpaceUnit(blockSize) >= needed {
+				return blockNumber, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// Update
+// sets the entry of the block of page to the space its records take now,
+// growing the map when the block has no entry yet
+func (fsm *FreeSpaceMap) Update(page *RecordPage) error {
+	blockSize := fsm.tx.BlockSize()
+	free, err := page.FreeSpace()
+	if err != nil {
+		return err
+	}
+	unit := freeSpaceUnit(blockSize)
+	entry := byte((blockSize - free + unit - 1) / unit)
+	blockNumber := page.Block().GetBlockNumber()
+	mapSize, err := fsm.tx.Size(fsm.filename)
+	if err != nil {
+		return err
+	}
+	for ; mapSize <= blockNumber/blockSize; mapSize++ {
+		if _, err := fsm.tx.Append(fsm.filename); err != nil {
+			return err
+		}
+	}
+	blk := file.NewBlock(fsm.filename, blockNumber/blockSize)
+	if err := fsm.tx.Pin(blk); err != nil {
+		return err
+	}
+	defer fsm.tx.Unpin(blk)
+	old, err := fsm.tx.GetRaw(blk, blockNumber%blockSize, 1)
+	if err != nil || old[0] == entry {
+		return err
+	}
+	return fsm.tx.SetRaw(blk, blockNumber%blockSize, []byte{entry}, true)
+}
+
+// Clear
+// empties the map, for a table file whose blocks were all cleared
+func (fsm *FreeSpaceMap) Clear() error {
+	mapSize, err := fsm.tx.Size(fsm.filename)
+	if err != nil {
+		return err
+	}
+	for mapBlock := range mapSize {
+		if err := ClearBlock(fsm.tx, file.NewBlock(fsm.filename, mapBlock)); err != nil {
+			return err
+		}
+	}
+	return fsm.tx.Truncate(fsm.filename, 0)
+}
+
+// freeSpaceUnit
+// the bytes an entry counts in one step
+func freeSpaceUnit(blockSize int) int {
+	return (blockSize + freeSpaceLevels - 1) / freeSpaceLevels
+}
@@ -1,35 +1,66 @@
 package record
 
 import (
+	"cmp"
 	"jadb/constants"
-	"jadb/file"
+	"slices"
 )
 
+// nullBitsPerWord
+// every int of the null bitmap holds the null flags of this many fields
+const nullBitsPerWord = constants.IntSize * 8
+
+// Layout
+// a record is the flag, a null bitmap with a bit per field in the order
+// the fields are stored, the fixed size fields at their offsets and then
+// the varchar and blob fields. Those take only the length of their value
+// so their offsets are where they would be at their maximum length, which
+// only gives their order. The slot size is the largest a record can be
 type Layout struct {
-	schema   *Schema
-	offsets  map[string]int
-	slotSize int
+	schema    *Schema
+	offsets   map[string]int
+	nullBits  map[string]int
+	slotSize  int
+	varFields []string
+	fixedSize int
 }
 
 func NewLayout(schema *Schema) *Layout {
 	offsets := make(map[string]int)
 	offset := constants.IntSize // flag accountability
-	for _, fldName := range schema.Fields() {
-		offsets[fldName] = offset
-		offset += schema.Length(fldName)
-	}
-	return &Layout{
-		schema:   schema,
-		offsets:  offsets,
-		slotSize: offset,
+	offset += nullWords(len(schema.Fields())) * constants.IntSize
+	//the fixed size fields come first so their offsets hold in every record
+	for _, variable := range []bool{false, true} {
+		for _, fldName := range schema.Fields() {
+			if isVariable(schema.Type(fldName)) == variable {
+				offsets[fldName] = offset
+				offset += schema.Length(fldName)
+			}
+		}
 	}
+	layout := NewLayout1(schema, offsets, offset)
+	return &layout
 }
 
 func NewLayout1(schema *Schema, offsets map[string]int, slotSize int) Layout {
+	fields := storedOrder(offsets)
+	varFields := make([]string, 0)
+	fixedSize := slotSize
+	for _, fldName := range fields {
+		if isVariable(schema.Type(fldName)) {
+			if len(varFields) == 0 {
+				fixedSize = offsets[fldName]
+			}
+			varFields = append(varFields, fldName)
+		}
+	}
 	return Layout{
 		schema,
 		offsets,
+		nullBits(offsets),
 		slotSize,
+		varFields,
+		fixedSize,
 	}
 }
 
@@ -45,11 +76,63 @@ func (layout *Layout) SlotSize() int {
 	return layout.slotSize
 }
 
-func (layout *Layout) lengthInBytes(fldName string) int {
-	fldType := layout.schema.Type(fldName)
-	if fldType == INTEGER {
-		return constants.IntSize
-	} else {
-		return file.MaxLength(layout.schema.Length(fldName))
+// MinSize
+// the size of a record whose varchar and blob fields are empty
+func (layout *Layout) MinSize() int {
+	return layout.fixedSize + len(layout.varFields)*constants.IntSize
+}
+
+// varIndex
+// position of fldName among the varchar and blob fields, -1 for a fixed
+// size field
+func (layout *Layout) varIndex(fldName string) int {
+	return slices.Index(layout.varFields, fldName)
+}
+
+// NullBitmapOffset
+// offset of the null bitmap within a slot, right after the flag
+func (layout *Layout) NullBitmapOffset() int {
+	return constants.IntSize
+}
+
+// NullBitmapSize
+// size in bytes of the null bitmap of a slot
+func (layout *Layout) NullBitmapSize() int {
+	return nullWords(len(layout.offsets)) * constants.IntSize
+}
+
+// nullBit
+// offset within the slot of the bitmap word holding the null flag of
+// fldName, and the mask of the flag in that word
+func (layout *Layout) nullBit(fldName string) (int, int) {
+	bit := layout.nullBits[fldName]
+	return layout.NullBitmapOffset() + (bit/nullBitsPerWord)*constants.IntSize, 1 << (bit % nullBitsPerWord)
+}
+
+// nullBits
+// fields get their bit in the order they are stored in the slot, so a layout
+// read back from the catalog agrees with the one the records were written with
+func nullBits(offsets map[string]int) map[string]int {
+	bits := make(map[string]int)
+	for i, fldName := range storedOrder(offsets) {
+		bits[fldName] = i
 	}
+	return bits
+}
+
+// storedOrder
+// the fields sorted by their offsets
+func storedOrder(offsets map[string]int) []string {
+	fields := make([]string, 0, len(offsets))
+	for fldName := range offsets {
+		fields = append(fields, fldName)
+	}
+	slices.SortFunc(fields, func(a, b string) int {
+		return cmp.Compare(offsets[a], offsets[b])
+	})
+	return fields
+}
+
+func nullWords(fieldCount int) int {
+	return (fieldCount + nullBitsPerWord - 1) / nullBitsPerWord
 }
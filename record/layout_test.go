@@ -15,12 +15,13 @@ func TestLayout(t *testing.T) {
 	schema.AddIntField(fields[0])
 	schema.AddIntField(fields[1])
 	schema.AddStringField(fields[2], 10)
-	expectedSlotSize := constants.IntSize*3 + file.MaxLength(10)
+	//flag, a single null bitmap word and the fields
+	expectedSlotSize := constants.IntSize*4 + file.MaxLength(10)
 	layout := NewLayout(schema)
 
 	assert.Equalf(expectedSlotSize, layout.SlotSize(), "expected %d slot size, got %d",
 		expectedSlotSize, layout.SlotSize())
-	expectedOffset := constants.IntSize * 2 // count for used and free flag too
+	expectedOffset := constants.IntSize * 3 // count for used and free flag and null bitmap too
 	assert.Equalf(expectedOffset, layout.Offset("testInt2"), "expected offset %d,got %d",
 		expectedOffset, layout.Offset("testInt2"))
 }
@@ -0,0 +1,109 @@
+package record
+
+import (
+	"jadb/constants"
+	"jadb/file"
+	"jadb/tx"
+)
+
+// overflowPage
+// the slot count of a block holding part of a large value instead of
+// records, record scans find no slot in it
+const overflowPage = -1
+
+const (
+	overflowNextOffset   = constants.IntSize
+	overflowLengthOffset = 2 * constants.IntSize
+	overflowDataOffset   = 3 * constants.IntSize
+	// overflowFraction
+	// a varchar or blob value longer than this fraction of a block is
+	// stored in a chain of overflow blocks
+	overflowFraction = 4
+)
+
+// writeOverflow
+// stores data in a chain of new blocks at the end of the file, every block
+// holds the number of the next one, -1 in the last, and the length of its
+// part of data. The number of the first block is returned
+func writeOverflow(txn *tx.Transaction, filename string, data []byte) (int, error) {
+	chunk := txn.BlockSize() - overflowDataOffset
+	blocks := make([]*file.BlockId, (len(data)+chunk-1)/chunk)
+	for i := range blocks {
+		var err error
+		if blocks[i], err = txn.Append(filename); err != nil {
+			return -1, err
+		}
+	}
+	for i, blk := range blocks {
+		part := data[i*chunk : min((i+1)*chunk, len(data))]
+		next := -1
+		if i+1 < len(blocks) {
+			next = blocks[i+1].GetBlockNumber()
+		}
+		image := file.NewPage(overflowDataOffset + len(part))
+		image.SetInt(slotCountOffset, overflowPage)
+		image.SetInt(overflowNextOffset, next)
+		image.SetInt(overflowLengthOffset, len(part))
+		image.SetRaw(overflowDataOffset, part)
+		if err := txn.Pin(blk); err != nil {
+			return -1, err
+		}
+		err := txn.SetRaw(blk, 0, image.Contents(), true)
+		txn.Unpin(blk)
+		if err != nil {
+			return -1, err
+		}
+	}
+	return blocks[0].GetBlockNumber(), nil
+}
+
+// readOverflow
+// the length bytes of the chain starting at block first
+func readOverflow(txn *tx.Transaction, filename string, first int, length int) ([]byte, error) {
+	data := make([]byte, 0, length)
+	for blockNumber := first; blockNumber >= 0 && len(data) < length; {
+		blk := file.NewBlock(filename, blockNumber)
+		if err := txn.Pin(blk); err != nil {
+			return nil, err
+		}
+		header, err := txn.GetRaw(blk, 0, overflowDataOffset)
+		if err != nil {
+			txn.Unpin(blk)
+			return nil, err
+		}
+		p := file.NewPageWithBuffer(header)
+		part, err := txn.GetRaw(blk, overflowDataOffset, p.GetInt(overflowLengthOffset))
+		txn.Unpin(blk)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, part...)
+		blockNumber = p.GetInt(overflowNextOffset)
+	}
+	return data, nil
+}
+
+// freeOverflow
+// turns the blocks of the chain starting at block first into blocks
+// without records, which take new records like any other
+func freeOverflow(txn *tx.Transaction, filename string, first int) error {
+	for blockNumber := first; blockNumber >= 0; {
+		blk := file.NewBlock(filename, blockNumber)
+		if err := txn.Pin(blk); err != nil {
+			return err
+		}
+		next, err := txn.GetInt(blk, overflowNextOffset)
+		if err == nil {
+			err = txn.SetInt(blk, slotCountOffset, 0, true)
+		}
+		if err == nil {
+			err = txn.SetInt(blk, dataSizeOffset, 0, true)
+		}
+		txn.Unpin(blk)
+		if err != nil {
+			return err
+		}
+		blockNumber = next
+	}
+	return nil
+}
@@ -1,21 +1,80 @@
 package record
 
 import (
+	"bytes"
+	"fmt"
+	"jadb/constants"
 	"jadb/file"
 	"jadb/tx"
+	"time"
 )
 
+// the flag a record starts with, a slot without a record is EMPTY
 const (
 	EMPTY = iota
 	USED
+	// FORWARDED
+	// the record moved to another block, the rid after the flag says where
+	FORWARDED
+	// MOVED
+	// a record that moved here, the rid after the flag is the slot it
+	// belongs to
+	MOVED
 )
 
+const (
+	slotCountOffset = 0
+	dataSizeOffset  = constants.IntSize
+	directoryOffset = 2 * constants.IntSize
+	// entrySize
+	// a directory entry is the offset and the length of the record of a slot
+	entrySize = 2 * constants.IntSize
+	// linkSize
+	// the rid after the flag of a forwarded or moved record
+	linkSize = 2 * constants.IntSize
+	// insertReserve
+	// a record is only inserted into a block with room for it to grow to
+	// its largest size, or to this fraction of the block when that is less
+	insertReserve = 8
+)
+
+// RecordPage
+// a slotted page. The block starts with the number of slots, the number of
+// bytes taken by the records at the end of the block, and a directory with
+// the offset and length of the record of every slot, where an offset of 0
+// marks an empty slot. Records are added from the end of the block towards
+// the directory, so a record takes only the length of its varchar and blob
+// values. A record keeps its slot when it moves, within the block when
+// deleted space is compacted, or to another block when it grows too large
+// for its own, leaving a forwarding pointer in its slot
 type RecordPage struct {
 	tx     *tx.Transaction
 	blk    *file.BlockId
 	layout *Layout
 }
 
+// stored
+// a record where it is stored, page holds it in slot at offset with room
+// for capacity bytes. image is the record in the form of a used record in
+// its own slot, so the offsets of the layout apply to it. home is the rid
+// a moved record belongs to
+type stored struct {
+	page     *RecordPage
+	slot     int
+	offset   int
+	capacity int
+	home     *RID
+	image    []byte
+}
+
+// release
+// unpins the block a forwarded record was read from
+func (rec *stored) release(page *RecordPage) {
+	if rec.page != page {
+		page.tx.Unpin(rec.page.blk)
+	}
+}
+
 func NewRecordPage(tx *tx.Transaction, blk *file.BlockId, layout *Layout) (*RecordPage, error) {
 	if err := tx.Pin(blk); err != nil {
 		return nil, err
@@ -28,88 +87,834 @@ func NewRecordPage(tx *tx.Transaction, blk *file.BlockId, layout *Layout) (*Reco
 }
 
 func (page *RecordPage) GetInt(slot int, fldName string) (int, error) {
-	fldPos := page.offset(slot) + page.layout.Offset(fldName)
-	return page.tx.GetInt(page.blk, fldPos)
+	val, err := page.GetVal(slot, fldName)
+	if err != nil {
+		return 0, err
+	}
+	intVal, ok := val.(int)
+	if !ok {
+		return 0, fmt.Errorf("field %s is not an int", fldName)
+	}
+	return intVal, nil
 }
 
 func (page *RecordPage) GetString(slot int, fldName string) (string, error) {
-	fldPos := page.offset(slot) + page.layout.Offset(fldName)
-	return page.tx.GetString(page.blk, fldPos)
+	val, err := page.GetVal(slot, fldName)
+	if err != nil {
+		return "", err
+	}
+	strVal, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s is not a string", fldName)
+	}
+	return strVal, nil
 }
 
 func (page *RecordPage) SetInt(slot int, fldName string, val int) error {
-	fldPos := page.offset(slot) + page.layout.Offset(fldName)
-	return page.tx.SetInt(page.blk, fldPos, val, true)
+	return page.SetVal(slot, fldName, val)
 }
 
 func (page *RecordPage) SetString(slot int, fldName string, val string) error {
-	fldPos := page.offset(slot) + page.layout.Offset(fldName)
-	return page.tx.SetString(page.blk, fldPos, val, true)
+	return page.SetVal(slot, fldName, val)
 }
 
-func (page *RecordPage) Delete(slot int) error {
-	return page.setFlag(slot, EMPTY)
+// GetVal
+// the value of the field as the go type of its field type, an int for int
+// and bigint, a string for varchar, a bool, a float64 for double, a
+// time.Time in UTC for date and timestamp and a Blob. The null flag is not
+// looked at
+func (page *RecordPage) GetVal(slot int, fldName string) (any, error) {
+	rec, err := page.read(slot)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.release(page)
+	image := file.NewPageWithBuffer(rec.image)
+	pos := page.layout.position(rec.image, fldName)
+	if first := page.layout.overflow(rec.image, fldName); first >= 0 {
+		data, err := readOverflow(page.tx, page.blk.GetFileName(), first, -image.GetInt(pos))
+		if err != nil {
+			return nil, err
+		}
+		if page.layout.Schema().Type(fldName) == BLOB {
+			return Blob(data), nil
+		}
+		return string(data), nil
+	}
+	switch page.layout.Schema().Type(fldName) {
+	case INTEGER, BIGINT:
+		return image.GetInt(pos), nil
+	case VARCHAR:
+		return image.GetString(pos), nil
+	case BOOLEAN:
+		return image.GetBool(pos), nil
+	case DOUBLE:
+		return image.GetDouble(pos), nil
+	case DATE, TIMESTAMP:
+		return image.GetTime(pos), nil
+	case BLOB:
+		return Blob(image.GetBytes(pos)), nil
+	}
+	return nil, fmt.Errorf("field %s has an unknown type", fldName)
 }
 
-func (page *RecordPage) Format() error {
-	slot := 0
-	for page.isValidSlot(slot) {
-		if err := page.tx.SetInt(page.blk, page.offset(slot), EMPTY, false); err != nil {
+// SetVal
+// val has to have the go type GetVal returns for the field. A record that
+// grows too large for its block moves to another one. A long varchar or
+// blob value is stored in overflow blocks, as are the largest values of a
+// record that would not fit in a block otherwise
+func (page *RecordPage) SetVal(slot int, fldName string, val any) error {
+	value, err := page.layout.encode(fldName, val)
+	if err != nil {
+		return err
+	}
+	if page.layout.varIndex(fldName) >= 0 && len(value)-constants.IntSize > page.tx.BlockSize()/overflowFraction {
+		if value, err = page.spill(value); err != nil {
 			return err
 		}
-		sch := page.layout.Schema()
-		for _, fldName := range sch.Fields() {
-			fldPos := page.offset(slot) + page.layout.Offset(fldName)
-			if sch.Type(fldName) == INTEGER {
-				if err := page.tx.SetInt(page.blk, fldPos, 0, false); err != nil {
-					return err
-				}
-			} else {
-				if err := page.tx.SetString(page.blk, fldPos, "", false); err != nil {
-					return err
-				}
-			}
+	}
+	rec, err := page.read(slot)
+	if err != nil {
+		return err
+	}
+	defer rec.release(page)
+	image, err := page.fit(page.layout.withValue(rec.image, fldName, value, false))
+	if err != nil {
+		return err
+	}
+	if err := page.write(slot, rec, image); err != nil {
+		return err
+	}
+	return page.freeOverflow(rec.image, fldName)
+}
+
+// IsNull
+// true when the field has been set to null, or not been set since the
+// record was inserted
+func (page *RecordPage) IsNull(slot int, fldName string) (bool, error) {
+	rec, err := page.read(slot)
+	if err != nil {
+		return false, err
+	}
+	defer rec.release(page)
+	wordOffset, mask := page.layout.nullBit(fldName)
+	return file.NewPageWithBuffer(rec.image).GetInt(wordOffset)&mask != 0, nil
+}
+
+// SetNull
+// a null varchar or blob field takes no space
+func (page *RecordPage) SetNull(slot int, fldName string) error {
+	rec, err := page.read(slot)
+	if err != nil {
+		return err
+	}
+	defer rec.release(page)
+	var value []byte
+	if page.layout.varIndex(fldName) >= 0 {
+		value = make([]byte, constants.IntSize)
+	}
+	if err := page.write(slot, rec, page.layout.withValue(rec.image, fldName, value, true)); err != nil {
+		return err
+	}
+	return page.freeOverflow(rec.image, fldName)
+}
+
+// Delete
+// empties the slot, and the one the record moved to, and frees the
+// overflow blocks of its values. The space of the record is reused once
+// the block is compacted
+func (page *RecordPage) Delete(slot int) error {
+	rec, err := page.read(slot)
+	if err != nil {
+		return err
+	}
+	defer rec.release(page)
+	if rec.page != page {
+		if err := rec.page.setEntry(rec.slot, 0, 0); err != nil {
+			return err
+		}
+	}
+	if err := page.setEntry(slot, 0, 0); err != nil {
+		return err
+	}
+	for _, fldName := range page.layout.varFields {
+		if err := page.freeOverflow(rec.image, fldName); err != nil {
+			return err
 		}
-		slot++
 	}
 	return nil
 }
 
+// Format
+// a block without slots
+func (page *RecordPage) Format() error {
+	if err := page.tx.SetInt(page.blk, slotCountOffset, 0, false); err != nil {
+		return err
+	}
+	return page.tx.SetInt(page.blk, dataSizeOffset, 0, false)
+}
+
+// NextAfter
+// the next slot with a record of the block, moved records are found
+// through the slot they belong to
 func (page *RecordPage) NextAfter(slot int) int {
-	return page.searchAfter(slot, USED)
+	count, err := page.getInt(slotCountOffset)
+	if err != nil {
+		return -1
+	}
+	for slot += 1; slot < count; slot++ {
+		flag, err := page.flag(slot)
+		if err == nil && (flag == USED || flag == FORWARDED) {
+			return slot
+		}
+	}
+	return -1
 }
 
+// InsertAfter
+// a new record with every field null, in an empty slot after slot or a
+// new one. -1 when the block does not have room for it to grow
 func (page *RecordPage) InsertAfter(slot int) (int, error) {
-	newSlot := page.searchAfter(slot, EMPTY)
-	if newSlot >= 0 {
-		if err := page.setFlag(newSlot, USED); err != nil {
-			return -1, err
-		}
+	newSlot, err := page.insert(page.layout.emptyImage(), page.layout.insertSize(page.tx.BlockSize()), slot)
+	if err != nil || newSlot < 0 {
+		return -1, err
 	}
 	return newSlot, nil
 }
 
+// FreeSpace
+// the bytes a record in a new slot of the block could take, once the
+// block is compacted. 0 for a block holding overflow data
+func (page *RecordPage) FreeSpace() (int, error) {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil || count == overflowPage {
+		return 0, err
+	}
+	live, err := page.liveSize(-1)
+	if err != nil {
+		return 0, err
+	}
+	return max(page.tx.BlockSize()-directoryOffset-(count+1)*entrySize-live, 0), nil
+}
+
 func (page *RecordPage) Block() *file.BlockId {
 	return page.blk
 }
 
-func (page *RecordPage) searchAfter(slot int, flag int) int {
-	for slot += 1; page.isValidSlot(slot); slot++ {
-		if val, err := page.tx.GetInt(page.blk, page.offset(slot)); err == nil && val == flag {
-			return slot
+// read
+// the record of slot, following its forwarding pointer. The block of a
+// forwarded record stays pinned until it is released
+func (page *RecordPage) read(slot int) (*stored, error) {
+	rec, err := page.readStored(slot)
+	if err != nil {
+		return nil, err
+	}
+	flag := file.NewPageWithBuffer(rec.image).GetInt(0)
+	switch flag {
+	case USED:
+		return rec, nil
+	case FORWARDED:
+		target := page.link(rec.image)
+		targetPage, err := NewRecordPage(page.tx, file.NewBlock(page.blk.GetFileName(), target.BlockNumber()), page.layout)
+		if err != nil {
+			return nil, err
 		}
+		moved, err := targetPage.readStored(target.Slot())
+		if err == nil && moved.home == nil {
+			err = fmt.Errorf("slot %d of block %d does not hold the record of slot %d of block %d",
+				target.Slot(), target.BlockNumber(), slot, page.blk.GetBlockNumber())
+		}
+		if err != nil {
+			page.tx.Unpin(targetPage.blk)
+			return nil, err
+		}
+		return moved, nil
 	}
-	return -1
+	return nil, fmt.Errorf("slot %d of block %d has no record", slot, page.blk.GetBlockNumber())
+}
+
+// readStored
+// the record in slot of this block, a moved record is given the form of a
+// used one. A forwarding pointer is returned as it is
+func (page *RecordPage) readStored(slot int) (*stored, error) {
+	offset, capacity, err := page.entry(slot)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		return nil, fmt.Errorf("slot %d of block %d has no record", slot, page.blk.GetBlockNumber())
+	}
+	size, err := page.size(offset)
+	if err != nil {
+		return nil, err
+	}
+	image, err := page.tx.GetRaw(page.blk, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	rec := &stored{page: page, slot: slot, offset: offset, capacity: capacity, image: image}
+	if file.NewPageWithBuffer(image).GetInt(0) == MOVED {
+		rec.home = page.link(image)
+		rec.image = append(image[:constants.IntSize:constants.IntSize], image[constants.IntSize+linkSize:]...)
+		file.NewPageWithBuffer(rec.image).SetInt(0, USED)
+	}
+	return rec, nil
+}
+
+// write
+// replaces the record of slot, read as rec, by image. It is written over
+// the old one when it fits, elsewhere in the block after compacting it or
+// else in another block
+func (page *RecordPage) write(slot int, rec *stored, image []byte) error {
+	old := rec.page.storedForm(rec.image, rec.home)
+	newStored := rec.page.storedForm(image, rec.home)
+	if len(newStored) <= rec.capacity {
+		return rec.page.writeChanges(rec.offset, old, newStored)
+	}
+	offset, err := rec.page.allocate(len(newStored), len(newStored), 0, rec.slot)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if err := rec.page.tx.SetRaw(rec.page.blk, offset, newStored, true); err != nil {
+			return err
+		}
+		return rec.page.setEntry(rec.slot, offset, len(newStored))
+	}
+	return page.moveOut(slot, rec, image)
+}
+
+// moveOut
+// moves the record of slot to another block, leaving a forwarding pointer
+// in slot. A record that had already moved is freed where it was
+func (page *RecordPage) moveOut(slot int, rec *stored, image []byte) error {
+	home := NewRID(page.blk.GetBlockNumber(), slot)
+	movedForm := page.storedForm(image, home)
+	if directoryOffset+entrySize+len(movedForm) > page.tx.BlockSize() {
+		return fmt.Errorf("a record of %d bytes does not fit in a block of %d bytes", len(image), page.tx.BlockSize())
+	}
+	target, targetSlot, err := page.placeElsewhere(movedForm)
+	if err != nil {
+		return err
+	}
+	defer page.tx.Unpin(target.blk)
+	if rec.page != page {
+		if err := rec.page.setEntry(rec.slot, 0, 0); err != nil {
+			return err
+		}
+	}
+	forward := page.forwardImage(NewRID(target.blk.GetBlockNumber(), targetSlot))
+	homeRec, err := page.readStored(slot)
+	if err != nil {
+		return err
+	}
+	//a forwarding pointer is never larger than a record with a varchar or
+	//blob field, the only records that grow
+	return page.writeChanges(homeRec.offset, page.storedForm(homeRec.image, homeRec.home), forward)
+}
+
+// placeElsewhere
+// inserts a moved record into the last block of the file, or a new one
+// when it has no room. The block is returned pinned
+func (page *RecordPage) placeElsewhere(movedForm []byte) (*RecordPage, int, error) {
+	filename := page.blk.GetFileName()
+	size, err := page.tx.Size(filename)
+	if err != nil {
+		return nil, -1, err
+	}
+	if size-1 != page.blk.GetBlockNumber() {
+		last, err := NewRecordPage(page.tx, file.NewBlock(filename, size-1), page.layout)
+		if err != nil {
+			return nil, -1, err
+		}
+		slot, err := last.insert(movedForm, len(movedForm), -1)
+		if err != nil || slot >= 0 {
+			return last, slot, err
+		}
+		page.tx.Unpin(last.blk)
+	}
+	blk, err := page.tx.Append(filename)
+	if err != nil {
+		return nil, -1, err
+	}
+	newPage, err := NewRecordPage(page.tx, blk, page.layout)
+	if err != nil {
+		return nil, -1, err
+	}
+	if err := newPage.Format(); err != nil {
+		return nil, -1, err
+	}
+	slot, err := newPage.insert(movedForm, len(movedForm), -1)
+	return newPage, slot, err
+}
+
+// insert
+// stores image in an empty slot after slot or in a new one when the block
+// has room for reserve bytes. -1 when it has not, or holds overflow data
+func (page *RecordPage) insert(image []byte, reserve int, slot int) (int, error) {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil || count == overflowPage {
+		return -1, err
+	}
+	newSlot := count
+	for s := slot + 1; s < count; s++ {
+		offset, _, err := page.entry(s)
+		if err != nil {
+			return -1, err
+		}
+		if offset == 0 {
+			newSlot = s
+			break
+		}
+	}
+	extra := 0
+	if newSlot == count {
+		extra = entrySize
+	}
+	offset, err := page.allocate(len(image), reserve, extra, -1)
+	if err != nil || offset == 0 {
+		return -1, err
+	}
+	if newSlot == count {
+		if err := page.tx.SetInt(page.blk, slotCountOffset, count+1, true); err != nil {
+			return -1, err
+		}
+	}
+	if err := page.tx.SetRaw(page.blk, offset, image, true); err != nil {
+		return -1, err
+	}
+	return newSlot, page.setEntry(newSlot, offset, len(image))
+}
+
+// allocate
+// the offset of length free bytes when the block has room for reserve
+// bytes and extra more for the directory, compacting it when that makes
+// room. The record of skip is left out of the compaction, as it is being
+// moved. 0 when there is no room
+func (page *RecordPage) allocate(length int, reserve int, extra int, skip int) (int, error) {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil {
+		return 0, err
+	}
+	dataSize, err := page.getInt(dataSizeOffset)
+	if err != nil {
+		return 0, err
+	}
+	directoryEnd := directoryOffset + count*entrySize + extra
+	if page.tx.BlockSize()-dataSize-directoryEnd < reserve {
+		live, err := page.liveSize(skip)
+		if err != nil {
+			return 0, err
+		}
+		if page.tx.BlockSize()-live-directoryEnd < reserve {
+			return 0, nil
+		}
+		if err := page.compact(skip); err != nil {
+			return 0, err
+		}
+		dataSize = live
+	}
+	if err := page.tx.SetInt(page.blk, dataSizeOffset, dataSize+length, true); err != nil {
+		return 0, err
+	}
+	return page.tx.BlockSize() - dataSize - length, nil
 }
 
-func (page *RecordPage) isValidSlot(slot int) bool {
-	return page.offset(slot+1) <= page.tx.BlockSize()
+// liveSize
+// the bytes the records of the block other than skip take
+func (page *RecordPage) liveSize(skip int) (int, error) {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil {
+		return 0, err
+	}
+	live := 0
+	for slot := range count {
+		offset, _, err := page.entry(slot)
+		if err != nil {
+			return 0, err
+		}
+		if offset == 0 || slot == skip {
+			continue
+		}
+		size, err := page.size(offset)
+		if err != nil {
+			return 0, err
+		}
+		live += size
+	}
+	return live, nil
 }
 
-func (page *RecordPage) offset(slot int) int {
-	return slot * page.layout.SlotSize()
+// compact
+// moves the records other than skip to the end of the block next to each
+// other, so the space of deleted and moved records is free again. Every
+// record keeps its slot and takes only its own length
+func (page *RecordPage) compact(skip int) error {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil {
+		return err
+	}
+	images := make(map[int][]byte)
+	live := 0
+	for slot := range count {
+		offset, _, err := page.entry(slot)
+		if err != nil {
+			return err
+		}
+		if offset == 0 || slot == skip {
+			continue
+		}
+		size, err := page.size(offset)
+		if err != nil {
+			return err
+		}
+		if images[slot], err = page.tx.GetRaw(page.blk, offset, size); err != nil {
+			return err
+		}
+		live += size
+	}
+	start := page.tx.BlockSize() - live
+	area := make([]byte, 0, live)
+	for slot := range count {
+		image, ok := images[slot]
+		if !ok {
+			continue
+		}
+		if err := page.setEntry(slot, start+len(area), len(image)); err != nil {
+			return err
+		}
+		area = append(area, image...)
+	}
+	if err := page.tx.SetRaw(page.blk, start, area, true); err != nil {
+		return err
+	}
+	return page.tx.SetInt(page.blk, dataSizeOffset, live, true)
 }
 
-func (page *RecordPage) setFlag(slot int, flag int) error {
-	return page.tx.SetInt(page.blk, page.offset(slot), flag, true)
+// spill
+// stores the data of value, a varchar or blob value, in overflow blocks
+// and returns what the record keeps of it, the negated length of the data
+// and the number of the first block
+func (page *RecordPage) spill(value []byte) ([]byte, error) {
+	data := value[constants.IntSize:]
+	first, err := writeOverflow(page.tx, page.blk.GetFileName(), data)
+	if err != nil {
+		return nil, err
+	}
+	ref := file.NewPage(2 * constants.IntSize)
+	ref.SetInt(0, -len(data))
+	ref.SetInt(constants.IntSize, first)
+	return ref.Contents(), nil
+}
+
+// fit
+// image with its largest varchar and blob values spilled until it fits in
+// a block, also as a moved record
+func (page *RecordPage) fit(image []byte) ([]byte, error) {
+	limit := page.tx.BlockSize() - directoryOffset - entrySize - linkSize
+	for len(image) > limit {
+		p := file.NewPageWithBuffer(image)
+		largest, length := "", 0
+		for _, fldName := range page.layout.varFields {
+			if l := p.GetInt(page.layout.position(image, fldName)); l > length {
+				largest, length = fldName, l
+			}
+		}
+		if largest == "" {
+			return nil, fmt.Errorf("a record of %d bytes does not fit in a block of %d bytes", len(image), page.tx.BlockSize())
+		}
+		pos := page.layout.position(image, largest)
+		ref, err := page.spill(image[pos : pos+constants.IntSize+length])
+		if err != nil {
+			return nil, err
+		}
+		image = page.layout.withValue(image, largest, ref, false)
+	}
+	return image, nil
+}
+
+// freeOverflow
+// frees the overflow blocks of fldName in image, if it has any
+func (page *RecordPage) freeOverflow(image []byte, fldName string) error {
+	if first := page.layout.overflow(image, fldName); first >= 0 {
+		return freeOverflow(page.tx, page.blk.GetFileName(), first)
+	}
+	return nil
+}
+
+// writeChanges
+// writes the bytes from the first to the last one where image differs
+// from old, the record at offset
+func (page *RecordPage) writeChanges(offset int, old []byte, image []byte) error {
+	prefix := 0
+	for prefix < min(len(old), len(image)) && old[prefix] == image[prefix] {
+		prefix++
+	}
+	end := len(image)
+	if len(old) == len(image) {
+		for end > prefix && old[end-1] == image[end-1] {
+			end--
+		}
+	}
+	if end == prefix {
+		return nil
+	}
+	return page.tx.SetRaw(page.blk, offset+prefix, image[prefix:end], true)
+}
+
+// storedForm
+// image, a record in the form of a used one, as it is stored in a block. A
+// moved record gets its flag and its home after it
+func (page *RecordPage) storedForm(image []byte, home *RID) []byte {
+	if home == nil {
+		return image
+	}
+	link := page.forwardImage(home)
+	file.NewPageWithBuffer(link).SetInt(0, MOVED)
+	return append(link, image[constants.IntSize:]...)
+}
+
+// forwardImage
+// a forwarding pointer to rid
+func (page *RecordPage) forwardImage(rid *RID) []byte {
+	image := file.NewPage(constants.IntSize + linkSize)
+	image.SetInt(0, FORWARDED)
+	image.SetInt(constants.IntSize, rid.BlockNumber())
+	image.SetInt(2*constants.IntSize, rid.Slot())
+	return image.Contents()
+}
+
+// link
+// the rid after the flag of a forwarded or moved record
+func (page *RecordPage) link(image []byte) *RID {
+	p := file.NewPageWithBuffer(image)
+	return NewRID(p.GetInt(constants.IntSize), p.GetInt(2*constants.IntSize))
+}
+
+// size
+// the bytes taken by the record at offset
+func (page *RecordPage) size(offset int) (int, error) {
+	flag, err := page.getInt(offset)
+	if err != nil {
+		return 0, err
+	}
+	if flag == FORWARDED {
+		return constants.IntSize + linkSize, nil
+	}
+	start := offset
+	if flag == MOVED {
+		start += linkSize
+	}
+	pos := start + page.layout.fixedSize
+	for range page.layout.varFields {
+		length, err := page.getInt(pos)
+		if err != nil {
+			return 0, err
+		}
+		pos += constants.IntSize + max(length, 0)
+		if length < 0 {
+			pos += constants.IntSize
+		}
+	}
+	return pos - offset, nil
+}
+
+// flag
+// the flag of the record of slot, EMPTY when it has none
+func (page *RecordPage) flag(slot int) (int, error) {
+	offset, _, err := page.entry(slot)
+	if err != nil || offset == 0 {
+		return EMPTY, err
+	}
+	return page.getInt(offset)
+}
+
+// entry
+// the offset and the length of the record of slot
+func (page *RecordPage) entry(slot int) (int, int, error) {
+	count, err := page.getInt(slotCountOffset)
+	if err != nil || slot < 0 || slot >= count {
+		return 0, 0, err
+	}
+	pos := directoryOffset + slot*entrySize
+	offset, err := page.getInt(pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := page.getInt(pos + constants.IntSize)
+	return offset, length, err
+}
+
+func (page *RecordPage) setEntry(slot int, offset int, length int) error {
+	pos := directoryOffset + slot*entrySize
+	if err := page.tx.SetInt(page.blk, pos, offset, true); err != nil {
+		return err
+	}
+	return page.tx.SetInt(page.blk, pos+constants.IntSize, length, true)
+}
+
+func (page *RecordPage) getInt(offset int) (int, error) {
+	return page.tx.GetInt(page.blk, offset)
+}
+
+// encode
+// the bytes of val as they are stored for fldName
+func (layout *Layout) encode(fldName string, val any) ([]byte, error) {
+	fldType := layout.schema.Type(fldName)
+	var image *file.Page
+	switch v := val.(type) {
+	case int:
+		if fldType == INTEGER || fldType == BIGINT {
+			image = file.NewPage(constants.IntSize)
+			image.SetInt(0, v)
+		}
+	case string:
+		if fldType == VARCHAR && constants.IntSize+len(v) <= layout.schema.Length(fldName) {
+			image = file.NewPage(constants.IntSize + len(v))
+			if err := image.SetString(0, v); err != nil {
+				return nil, err
+			}
+		} else if fldType == VARCHAR {
+			return nil, fmt.Errorf("'%s' does not fit in field %s", v, fldName)
+		}
+	case bool:
+		if fldType == BOOLEAN {
+			image = file.NewPage(constants.BoolSize)
+			image.SetBool(0, v)
+		}
+	case float64:
+		if fldType == DOUBLE {
+			image = file.NewPage(constants.DoubleSize)
+			image.SetDouble(0, v)
+		}
+	case time.Time:
+		if fldType == DATE || fldType == TIMESTAMP {
+			image = file.NewPage(constants.IntSize)
+			image.SetTime(0, v)
+		}
+	case Blob:
+		if fldType == BLOB && constants.IntSize+len(v) <= layout.schema.Length(fldName) {
+			image = file.NewPage(constants.IntSize + len(v))
+			image.SetBytes(0, []byte(v))
+		} else if fldType == BLOB {
+			return nil, fmt.Errorf("%d bytes do not fit in field %s", len(v), fldName)
+		}
+	}
+	if image == nil {
+		return nil, fmt.Errorf("expected %s for field %s, got %v", TypeName(fldType), fldName, val)
+	}
+	return image.Contents(), nil
+}
+
+// position
+// where the value of fldName starts in image, a record in the form of a
+// used one
+func (layout *Layout) position(image []byte, fldName string) int {
+	index := layout.varIndex(fldName)
+	if index < 0 {
+		return layout.offsets[fldName]
+	}
+	p := file.NewPageWithBuffer(image)
+	pos := layout.fixedSize
+	for range index {
+		pos += varSize(p, pos)
+	}
+	return pos
+}
+
+// varSize
+// the bytes taken by the varchar or blob value at pos. A value stored in
+// overflow blocks has its length negated and the number of its first block
+func varSize(p *file.Page, pos int) int {
+	if length := p.GetInt(pos); length >= 0 {
+		return constants.IntSize + length
+	}
+	return 2 * constants.IntSize
+}
+
+// overflow
+// the first overflow block of fldName in image, -1 when the value is in
+// the record
+func (layout *Layout) overflow(image []byte, fldName string) int {
+	if layout.varIndex(fldName) < 0 {
+		return -1
+	}
+	p := file.NewPageWithBuffer(image)
+	pos := layout.position(image, fldName)
+	if p.GetInt(pos) >= 0 {
+		return -1
+	}
+	return p.GetInt(pos + constants.IntSize)
+}
+
+// withValue
+// a copy of image with value as the bytes of fldName and its null flag
+// set to isNull. A nil value keeps the bytes of the field
+func (layout *Layout) withValue(image []byte, fldName string, value []byte, isNull bool) []byte {
+	result := bytes.Clone(image)
+	if value != nil {
+		pos := layout.position(image, fldName)
+		end := pos + len(value)
+		if layout.varIndex(fldName) >= 0 {
+			end = pos + varSize(file.NewPageWithBuffer(image), pos)
+		}
+		result = append(append(result[:pos:pos], value...), image[end:]...)
+	}
+	wordOffset, mask := layout.nullBit(fldName)
+	p := file.NewPageWithBuffer(result)
+	word := p.GetInt(wordOffset) &^ mask
+	if isNull {
+		word |= mask
+	}
+	p.SetInt(wordOffset, word)
+	return result
+}
+
+// insertSize
+// the room a block needs for a new record, enough for it to grow to its
+// largest size or to a fraction of the block when that is less
+func (layout *Layout) insertSize(blockSize int) int {
+	return max(min(layout.SlotSize(), blockSize/insertReserve), layout.MinSize())
+}
+
+// emptyImage
+// a used record with every field null, its varchar and blob fields empty
+func (layout *Layout) emptyImage() []byte {
+	image := file.NewPage(layout.MinSize())
+	image.SetInt(0, USED)
+	for pos := layout.NullBitmapOffset(); pos < layout.NullBitmapOffset()+layout.NullBitmapSize(); pos += constants.IntSize {
+		image.SetInt(pos, -1)
+	}
+	return image.Contents()
+}
+
+// ClearBlock
+// zeroes the block with logging, leaving a block without records. The
+// block is logged as a before-image, so a rollback restores it whatever
+// the layout of its records was, which logging strings could not do
+func ClearBlock(tx *tx.Transaction, blk *file.BlockId) error {
+	if err := tx.Pin(blk); err != nil {
+		return err
+	}
+	defer tx.Unpin(blk)
+	return tx.SetBlock(blk, make([]byte, tx.BlockSize()), true)
+}
+
+// CopyBlock
+// copies src into dest with logging, so the slots of dest hold the
+// records of src at the same positions
+func CopyBlock(tx *tx.Transaction, src *file.BlockId, dest *file.BlockId) error {
+	if err := tx.Pin(src); err != nil {
+		return err
+	}
+	defer tx.Unpin(src)
+	if err := tx.Pin(dest); err != nil {
+		return err
+	}
+	defer tx.Unpin(dest)
+	image, err := tx.GetRaw(src, 0, tx.BlockSize())
+	if err != nil {
+		return err
+	}
+	return tx.SetBlock(dest, image, true)
 }
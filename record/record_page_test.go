@@ -10,7 +10,9 @@ import (
 	"jadb/tx"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 type TestEnv struct {
@@ -66,8 +68,8 @@ func TestRecordPage(t *testing.T) {
 	schema.AddStringField(name, 5)
 	schema.AddIntField(age)
 
-	// 16+10 = 26
-	expectedSlotSize := constants.IntSize*2 + file.MaxLength(5) + constants.IntSize
+	// flag, null bitmap and fields
+	expectedSlotSize := constants.IntSize*3 + file.MaxLength(5) + constants.IntSize
 
 	// layout using the schema
 	layout := NewLayout(schema)
@@ -84,40 +86,26 @@ func TestRecordPage(t *testing.T) {
 	rp, err := NewRecordPage(txn, block, layout)
 	assert.NoError(err)
 
-	// if we choose blockSize of 100 then 100/26 = 3
-	// 3 slots can be fit in a block
-	// test for invalid slot
-	// slots start from 0
-	slot, valid := 10, false
-	assert.Equalf(valid, rp.isValidSlot(slot),
-		"expected %b for slot %d,got %b", valid, slot, rp.isValidSlot(slot))
-
-	slot, valid = 1, true
-	assert.Equalf(valid, rp.isValidSlot(slot),
-		"expected %b for slot %d,got %b", valid, slot, rp.isValidSlot(slot))
-
-	slot = 1
-	// check if offset returns correct result
-	expectedOffset := slot * rp.layout.SlotSize()
-	assert.Equalf(expectedOffset, rp.offset(slot), "expected %d offset for slot %d, found %d",
-		expectedOffset, slot, rp.offset(slot))
-
+	// a new record goes to a new slot and every slot keeps its record
 	type Record struct {
 		id   int
 		name string
 		age  int
 	}
-	record := Record{1, "ankit", 22}
-
-	// insert into record at slot 0
-	err = rp.SetInt(0, id, record.id)
-	assert.NoError(err)
-	err = rp.SetString(0, name, record.name)
-	assert.NoError(err)
-	err = rp.SetInt(0, age, record.age)
-	assert.NoError(err)
-	err = rp.setFlag(0, USED)
-	assert.NoError(err)
+	records := []Record{{1, "ankit", 22}, {2, "", 0}}
+	slot := -1
+	for i, record := range records {
+		slot, err = rp.InsertAfter(slot)
+		assert.NoError(err)
+		assert.Equal(i, slot)
+		assert.NoError(rp.SetInt(slot, id, record.id))
+		assert.NoError(rp.SetString(slot, name, record.name))
+		assert.NoError(rp.SetInt(slot, age, record.age))
+	}
+	assert.Equal(0, rp.NextAfter(-1))
+	assert.Equal(1, rp.NextAfter(0))
+	assert.Equal(-1, rp.NextAfter(1))
+	record := records[0]
 
 	//commit transaction
 	err = txn.Commit()
@@ -141,3 +129,310 @@ func TestRecordPage(t *testing.T) {
 
 	clearEnv(t)
 }
+
+func TestRecordPageNulls(t *testing.T) {
+	assert := assertPkg.New(t)
+	initEnv(assert)
+	schema := NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("name", 5)
+	layout := NewLayout(schema)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(env.dbFile)
+	assert.NoError(err)
+	rp, err := NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.Format())
+
+	// every field of a new record is null
+	slot, err := rp.InsertAfter(-1)
+	assert.NoError(err)
+	assert.Equal(0, slot)
+	for _, fldName := range schema.Fields() {
+		isNull, err := rp.IsNull(slot, fldName)
+		assert.NoError(err)
+		assert.True(isNull)
+	}
+
+	// setting a field clears its null flag only
+	assert.NoError(rp.SetInt(slot, "id", 7))
+	isNull, err := rp.IsNull(slot, "id")
+	assert.NoError(err)
+	assert.False(isNull)
+	isNull, err = rp.IsNull(slot, "name")
+	assert.NoError(err)
+	assert.True(isNull)
+
+	assert.NoError(rp.SetNull(slot, "id"))
+	isNull, err = rp.IsNull(slot, "id")
+	assert.NoError(err)
+	assert.True(isNull)
+	assert.NoError(txn.Commit())
+
+	// null flags survive a commit and are undone by a rollback
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.SetString(slot, "name", "abc"))
+	assert.NoError(txn.Rollback())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	for _, fldName := range schema.Fields() {
+		isNull, err := rp.IsNull(slot, fldName)
+		assert.NoError(err)
+		assert.True(isNull)
+	}
+	assert.NoError(txn.Rollback())
+
+	clearEnv(t)
+}
+
+func TestRecordPageTypedValues(t *testing.T) {
+	assert := assertPkg.New(t)
+	initEnv(assert)
+	schema := NewSchema()
+	schema.AddBigIntField("big")
+	schema.AddBoolField("ok")
+	schema.AddDoubleField("rate")
+	schema.AddDateField("day")
+	schema.AddTimestampField("at")
+	schema.AddBlobField("data", 4)
+	layout := NewLayout(schema)
+	assert.Equal(constants.BoolSize, schema.Length("ok"))
+	assert.Equal(constants.IntSize+4, schema.Length("data"))
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(env.dbFile)
+	assert.NoError(err)
+	rp, err := NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.Format())
+	slot, err := rp.InsertAfter(-1)
+	assert.NoError(err)
+
+	at := time.Date(2024, 2, 29, 13, 4, 5, 123456000, time.UTC)
+	vals := map[string]any{
+		"big":  1 << 40,
+		"ok":   true,
+		"rate": 2.5,
+		"day":  time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"at":   at,
+		"data": Blob("\x00\xffab"),
+	}
+	for fldName, val := range vals {
+		assert.NoError(rp.SetVal(slot, fldName, val))
+	}
+	for fldName, val := range vals {
+		got, err := rp.GetVal(slot, fldName)
+		assert.NoError(err)
+		assert.Equal(val, got, fldName)
+		isNull, err := rp.IsNull(slot, fldName)
+		assert.NoError(err)
+		assert.False(isNull)
+	}
+
+	// a value has to have the type of its field and a blob has to fit
+	assert.Error(rp.SetVal(slot, "ok", 1))
+	assert.Error(rp.SetVal(slot, "at", "2024-02-29"))
+	assert.Error(rp.SetVal(slot, "data", Blob("abcde")))
+	assert.NoError(txn.Commit())
+
+	// a rollback restores the old values, a shorter blob included
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.SetVal(slot, "rate", -0.125))
+	assert.NoError(rp.SetVal(slot, "at", at.Add(time.Hour)))
+	assert.NoError(rp.SetVal(slot, "data", Blob("z")))
+	assert.NoError(txn.Rollback())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	for fldName, val := range vals {
+		got, err := rp.GetVal(slot, fldName)
+		assert.NoError(err)
+		assert.Equal(val, got, fldName)
+	}
+	assert.NoError(txn.Rollback())
+
+	clearEnv(t)
+}
+
+func TestRecordPageVariableLength(t *testing.T) {
+	assert := assertPkg.New(t)
+	initEnv(assert)
+	schema := NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("note", 40)
+	layout := NewLayout(schema)
+	//a slot of the largest record would not leave room for a second one
+	assert.Greater(2*layout.SlotSize(), env.blockSize)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(env.dbFile)
+	assert.NoError(err)
+	rp, err := NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.Format())
+	slot := -1
+	for i, note := range []string{"a", "b", "c"} {
+		slot, err = rp.InsertAfter(slot)
+		assert.NoError(err)
+		assert.Equal(i, slot)
+		assert.NoError(rp.SetInt(slot, "id", i))
+		assert.NoError(rp.SetString(slot, "note", note))
+	}
+	notes := func(rp *RecordPage) []string {
+		notes := make([]string, 0)
+		for slot := rp.NextAfter(-1); slot >= 0; slot = rp.NextAfter(slot) {
+			note, err := rp.GetString(slot, "note")
+			assert.NoError(err)
+			notes = append(notes, note)
+		}
+		return notes
+	}
+
+	//a record that grows gets room by compacting the block
+	long := strings.Repeat("x", 20)
+	assert.NoError(rp.SetString(1, "note", long))
+	assert.Equal([]string{"a", long, "c"}, notes(rp))
+	assert.NoError(txn.Commit())
+
+	//one too large for the block moves to another and keeps its slot
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	longer := strings.Repeat("y", 40)
+	assert.NoError(rp.SetString(1, "note", longer))
+	assert.Equal([]string{"a", longer, "c"}, notes(rp))
+	size, err := txn.Size(env.dbFile)
+	assert.NoError(err)
+	assert.Equal(2, size)
+	other, err := NewRecordPage(txn, file.NewBlock(env.dbFile, 1), layout)
+	assert.NoError(err)
+	assert.Equal(-1, other.NextAfter(-1))
+	id, err := rp.GetInt(1, "id")
+	assert.NoError(err)
+	assert.Equal(1, id)
+	assert.NoError(rp.SetNull(1, "note"))
+	isNull, err := rp.IsNull(1, "note")
+	assert.NoError(err)
+	assert.True(isNull)
+	txn.Unpin(other.Block())
+	assert.NoError(txn.Rollback())
+
+	//the rollback undoes the move, and a deleted slot is reused
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.Equal([]string{"a", long, "c"}, notes(rp))
+	assert.NoError(rp.Delete(0))
+	assert.Equal([]string{long, "c"}, notes(rp))
+	slot, err = rp.InsertAfter(-1)
+	assert.NoError(err)
+	assert.Equal(0, slot)
+	assert.NoError(rp.SetString(slot, "note", "d"))
+	assert.Equal([]string{"d", long, "c"}, notes(rp))
+	assert.NoError(txn.Commit())
+
+	clearEnv(t)
+}
+
+func TestRecordPageOverflow(t *testing.T) {
+	assert := assertPkg.New(t)
+	initEnv(assert)
+	schema := NewSchema()
+	schema.AddIntField("id")
+	schema.AddBlobField("data", 1000)
+	schema.AddStringField("a", 45)
+	schema.AddStringField("b", 45)
+	schema.AddStringField("c", 45)
+	layout := NewLayout(schema)
+	assert.Greater(layout.SlotSize(), env.blockSize)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(env.dbFile)
+	assert.NoError(err)
+	rp, err := NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.Format())
+	slot, err := rp.InsertAfter(-1)
+	assert.NoError(err)
+
+	//a value longer than a quarter block goes to a chain of overflow blocks
+	data := make([]byte, 700)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	assert.NoError(rp.SetVal(slot, "data", Blob(data)))
+	assert.NoError(rp.SetInt(slot, "id", 1))
+	size, err := txn.Size(env.dbFile)
+	assert.NoError(err)
+	assert.Equal(1+(700+env.blockSize-overflowDataOffset-1)/(env.blockSize-overflowDataOffset), size)
+	val, err := rp.GetVal(slot, "data")
+	assert.NoError(err)
+	assert.Equal(Blob(data), val)
+	//overflow blocks hold no records
+	overflow, err := NewRecordPage(txn, file.NewBlock(env.dbFile, 1), layout)
+	assert.NoError(err)
+	assert.Equal(-1, overflow.NextAfter(-1))
+	newSlot, err := overflow.InsertAfter(-1)
+	assert.NoError(err)
+	assert.Equal(-1, newSlot)
+	txn.Unpin(overflow.Block())
+
+	//shorter values that do not fit together spill the largest of them
+	values := map[string]string{"a": strings.Repeat("a", 45), "b": strings.Repeat("b", 44), "c": strings.Repeat("c", 43)}
+	for fldName, val := range values {
+		assert.NoError(rp.SetString(slot, fldName, val))
+	}
+	for fldName, val := range values {
+		got, err := rp.GetString(slot, fldName)
+		assert.NoError(err)
+		assert.Equal(val, got)
+	}
+	assert.NoError(txn.Commit())
+
+	//replacing a value frees its chain, a rollback brings it back
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	assert.NoError(rp.SetVal(slot, "data", Blob("short")))
+	val, err = rp.GetVal(slot, "data")
+	assert.NoError(err)
+	assert.Equal(Blob("short"), val)
+	overflow, err = NewRecordPage(txn, file.NewBlock(env.dbFile, 1), layout)
+	assert.NoError(err)
+	newSlot, err = overflow.InsertAfter(-1)
+	assert.NoError(err)
+	assert.Equal(0, newSlot)
+	txn.Unpin(overflow.Block())
+	assert.NoError(txn.Rollback())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	rp, err = NewRecordPage(txn, block, layout)
+	assert.NoError(err)
+	val, err = rp.GetVal(slot, "data")
+	assert.NoError(err)
+	assert.Equal(Blob(data), val)
+	assert.NoError(rp.Delete(slot))
+	assert.NoError(txn.Commit())
+
+	clearEnv(t)
+}
@@ -31,6 +31,34 @@ func (schema *Schema) AddStringField(fldName string, length int) {
 	schema.AddField(fldName, VARCHAR, file.MaxLength(length))
 }
 
+func (schema *Schema) AddBigIntField(fldName string) {
+	schema.AddField(fldName, BIGINT, constants.IntSize)
+}
+
+func (schema *Schema) AddBoolField(fldName string) {
+	schema.AddField(fldName, BOOLEAN, constants.BoolSize)
+}
+
+func (schema *Schema) AddDoubleField(fldName string) {
+	schema.AddField(fldName, DOUBLE, constants.DoubleSize)
+}
+
+// AddDateField
+// a date is stored as the timestamp of its midnight in UTC
+func (schema *Schema) AddDateField(fldName string) {
+	schema.AddField(fldName, DATE, constants.IntSize)
+}
+
+func (schema *Schema) AddTimestampField(fldName string) {
+	schema.AddField(fldName, TIMESTAMP, constants.IntSize)
+}
+
+// AddBlobField
+// length is the most bytes the field holds
+func (schema *Schema) AddBlobField(fldName string, length int) {
+	schema.AddField(fldName, BLOB, constants.IntSize+length)
+}
+
 func (schema *Schema) Add(fldName string, sch *Schema) {
 	schema.AddField(fldName, sch.Type(fldName), sch.Length(fldName))
 }
@@ -73,3 +101,18 @@ func (schema *Schema) Equals(schema1 *Schema) bool {
 	}
 	return true
 }
+
+// IsCompatible
+// true when both schemas have the same number of fields and the fields in
+// the same position have the same type, names and lengths may differ
+func (schema *Schema) IsCompatible(other *Schema) bool {
+	if len(schema.fields) != len(other.fields) {
+		return false
+	}
+	for i, fldName := range schema.fields {
+		if schema.Type(fldName) != other.Type(other.fields[i]) {
+			return false
+		}
+	}
+	return true
+}
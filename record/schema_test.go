@@ -25,3 +25,24 @@ func TestSchema(t *testing.T) {
 	assert.Equal(INTEGER, schema.Type("testInt"))
 	assert.Equal(VARCHAR, schema.Type("testString"))
 }
+
+func TestSchemaIsCompatible(t *testing.T) {
+	assert := assertPkg.New(t)
+	schema := NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("name", 10)
+
+	other := NewSchema()
+	other.AddIntField("key")
+	other.AddStringField("title", 40)
+	assert.True(schema.IsCompatible(other))
+
+	swapped := NewSchema()
+	swapped.AddStringField("name", 10)
+	swapped.AddIntField("id")
+	assert.False(schema.IsCompatible(swapped))
+
+	shorter := NewSchema()
+	shorter.AddIntField("id")
+	assert.False(schema.IsCompatible(shorter))
+}
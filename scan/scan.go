@@ -6,6 +6,7 @@ type Scan interface {
 	GetInt(string) (int, error)
 	GetString(string) (string, error)
 	GetVal(string) (any, error)
+	IsNull(string) (bool, error)
 	HasField(string) bool
 	Close()
 }
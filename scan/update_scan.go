@@ -7,6 +7,7 @@ type UpdateScan interface {
 	SetInt(string, int) error
 	SetString(string, string) error
 	SetVal(string, any) error
+	SetNull(string) error
 	Insert() error
 	Delete() error
 	GetRid() *record.RID
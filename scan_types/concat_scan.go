@@ -0,0 +1,60 @@
+package scan_types
+
+import "jadb/scan"
+
+var _ scan.Scan = (*ConcatScan)(nil)
+
+// ConcatScan
+// the records of s1 followed by the records of s2, both scans have to
+// have the same field names
+type ConcatScan struct {
+	s1      scan.Scan
+	s2      scan.Scan
+	current scan.Scan
+}
+
+func NewConcatScan(s1 scan.Scan, s2 scan.Scan) *ConcatScan {
+	return &ConcatScan{s1, s2, s1}
+}
+
+func (c *ConcatScan) BeforeFirst() error {
+	c.current = c.s1
+	if err := c.s1.BeforeFirst(); err != nil {
+		return err
+	}
+	return c.s2.BeforeFirst()
+}
+
+func (c *ConcatScan) Next() (bool, error) {
+	hasNext, err := c.current.Next()
+	if err != nil || hasNext || c.current == c.s2 {
+		return hasNext, err
+	}
+	c.current = c.s2
+	return c.s2.Next()
+}
+
+func (c *ConcatScan) GetInt(fldName string) (int, error) {
+	return c.current.GetInt(fldName)
+}
+
+func (c *ConcatScan) GetString(fldName string) (string, error) {
+	return c.current.GetString(fldName)
+}
+
+func (c *ConcatScan) GetVal(fldName string) (any, error) {
+	return c.current.GetVal(fldName)
+}
+
+func (c *ConcatScan) IsNull(fldName string) (bool, error) {
+	return c.current.IsNull(fldName)
+}
+
+func (c *ConcatScan) HasField(fldName string) bool {
+	return c.s1.HasField(fldName)
+}
+
+func (c *ConcatScan) Close() {
+	c.s1.Close()
+	c.s2.Close()
+}
@@ -0,0 +1,88 @@
+package scan_types
+
+import (
+	"fmt"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*DistinctScan)(nil)
+
+// DistinctScan
+// hash based duplicate elimination, a row is only returned the first
+// time its combination of values over fields is seen
+type DistinctScan struct {
+	s      scan.Scan
+	fields []string
+	seen   map[string]bool
+}
+
+func NewDistinctScan(s scan.Scan, fields []string) *DistinctScan {
+	return &DistinctScan{s, fields, make(map[string]bool)}
+}
+
+func (d *DistinctScan) BeforeFirst() error {
+	d.seen = make(map[string]bool)
+	return d.s.BeforeFirst()
+}
+
+func (d *DistinctScan) Next() (bool, error) {
+	for {
+		hasNext, err := d.s.Next()
+		if err != nil || !hasNext {
+			return false, err
+		}
+		key, err := recordKey(d.s, d.fields)
+		if err != nil {
+			return false, err
+		}
+		if !d.seen[key] {
+			d.seen[key] = true
+			return true, nil
+		}
+	}
+}
+
+func (d *DistinctScan) GetInt(s string) (int, error) {
+	return d.s.GetInt(s)
+}
+
+func (d *DistinctScan) GetString(s string) (string, error) {
+	return d.s.GetString(s)
+}
+
+func (d *DistinctScan) GetVal(s string) (any, error) {
+	return d.s.GetVal(s)
+}
+
+func (d *DistinctScan) IsNull(s string) (bool, error) {
+	return d.s.IsNull(s)
+}
+
+func (d *DistinctScan) HasField(s string) bool {
+	return d.s.HasField(s)
+}
+
+func (d *DistinctScan) Close() {
+	d.s.Close()
+}
+
+// recordKey
+// key of the values of fields in the current record of s
+func recordKey(s scan.Scan, fields []string) (string, error) {
+	values := make([]any, len(fields))
+	for i, fldName := range fields {
+		val, err := s.GetVal(fldName)
+		if err != nil {
+			return "", err
+		}
+		values[i] = val
+	}
+	return RecordKey(values), nil
+}
+
+// RecordKey
+// key equal records hash to, %#v quotes strings so values containing
+// separators can not collide
+func RecordKey(values []any) string {
+	return fmt.Sprintf("%#v", values)
+}
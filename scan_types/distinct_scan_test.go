@@ -0,0 +1,55 @@
+package scan_types
+
+import (
+	"fmt"
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestDistinctScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	testTableSchema := record.NewSchema()
+	testTableSchema.AddIntField("id")
+	testTableSchema.AddStringField("name", 10)
+	layout := record.NewLayout(testTableSchema)
+	ts, err := NewTableScan(txn, "distinct_table", layout)
+	assert.NoError(err)
+	//every (id,name) pair is inserted twice
+	testRecordCount := 100
+	for i := 0; i < testRecordCount*2; i++ {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i%testRecordCount))
+		assert.NoError(ts.SetString("name", fmt.Sprintf("nam%d", (i%testRecordCount)%10)))
+	}
+	ts.Close()
+
+	ts, err = NewTableScan(txn, "distinct_table", layout)
+	assert.NoError(err)
+	distinctScan := NewDistinctScan(ts, []string{"id", "name"})
+	count := 0
+	for hasNext, err := distinctScan.Next(); hasNext; hasNext, err = distinctScan.Next() {
+		assert.NoError(err)
+		count++
+	}
+	assert.Equal(testRecordCount, count)
+
+	//only the name field
+	distinctScan = NewDistinctScan(ts, []string{"name"})
+	assert.NoError(distinctScan.BeforeFirst())
+	count = 0
+	for hasNext, err := distinctScan.Next(); hasNext; hasNext, err = distinctScan.Next() {
+		assert.NoError(err)
+		count++
+	}
+	assert.Equal(10, count)
+	distinctScan.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
@@ -0,0 +1,89 @@
+package scan_types
+
+import (
+	"jadb/query"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*ExtendScan)(nil)
+
+// ExtendScan
+// adds the field fldName to the underlying scan, its value is expr
+// evaluated for the current record. The value is computed once per record
+type ExtendScan struct {
+	s       scan.Scan
+	fldName string
+	expr    *query.Expression
+	val     any
+	hasVal  bool
+}
+
+func NewExtendScan(s scan.Scan, fldName string, expr *query.Expression) *ExtendScan {
+	return &ExtendScan{s: s, fldName: fldName, expr: expr}
+}
+
+func (e *ExtendScan) BeforeFirst() error {
+	e.hasVal = false
+	return e.s.BeforeFirst()
+}
+
+func (e *ExtendScan) Next() (bool, error) {
+	e.hasVal = false
+	return e.s.Next()
+}
+
+func (e *ExtendScan) GetInt(fldName string) (int, error) {
+	if fldName != e.fldName {
+		return e.s.GetInt(fldName)
+	}
+	val, err := e.value()
+	if err != nil || val == nil {
+		return 0, err
+	}
+	return val.(int), nil
+}
+
+func (e *ExtendScan) GetString(fldName string) (string, error) {
+	if fldName != e.fldName {
+		return e.s.GetString(fldName)
+	}
+	val, err := e.value()
+	if err != nil || val == nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+func (e *ExtendScan) GetVal(fldName string) (any, error) {
+	if fldName != e.fldName {
+		return e.s.GetVal(fldName)
+	}
+	return e.value()
+}
+
+func (e *ExtendScan) IsNull(fldName string) (bool, error) {
+	if fldName != e.fldName {
+		return e.s.IsNull(fldName)
+	}
+	val, err := e.value()
+	return val == nil, err
+}
+
+func (e *ExtendScan) HasField(fldName string) bool {
+	return fldName == e.fldName || e.s.HasField(fldName)
+}
+
+func (e *ExtendScan) Close() {
+	e.s.Close()
+}
+
+func (e *ExtendScan) value() (any, error) {
+	if !e.hasVal {
+		val, err := e.expr.Evaluate(e.s)
+		if err != nil {
+			return nil, err
+		}
+		e.val, e.hasVal = val, true
+	}
+	return e.val, nil
+}
@@ -0,0 +1,71 @@
+package scan_types
+
+import "jadb/scan"
+
+var _ scan.Scan = (*LimitScan)(nil)
+
+// LimitScan
+// skips offset records and stops pulling from the underlying scan once
+// limit records have been returned, a negative limit means no limit
+type LimitScan struct {
+	s        scan.Scan
+	limit    int
+	offset   int
+	returned int
+	skipped  bool
+}
+
+func NewLimitScan(s scan.Scan, limit int, offset int) *LimitScan {
+	return &LimitScan{s, limit, offset, 0, false}
+}
+
+func (l *LimitScan) BeforeFirst() error {
+	l.returned = 0
+	l.skipped = false
+	return l.s.BeforeFirst()
+}
+
+func (l *LimitScan) Next() (bool, error) {
+	if l.limit >= 0 && l.returned >= l.limit {
+		return false, nil
+	}
+	if !l.skipped {
+		l.skipped = true
+		for i := 0; i < l.offset; i++ {
+			hasNext, err := l.s.Next()
+			if err != nil || !hasNext {
+				return false, err
+			}
+		}
+	}
+	hasNext, err := l.s.Next()
+	if err != nil || !hasNext {
+		return false, err
+	}
+	l.returned++
+	return true, nil
+}
+
+func (l *LimitScan) GetInt(s string) (int, error) {
+	return l.s.GetInt(s)
+}
+
+func (l *LimitScan) GetString(s string) (string, error) {
+	return l.s.GetString(s)
+}
+
+func (l *LimitScan) GetVal(s string) (any, error) {
+	return l.s.GetVal(s)
+}
+
+func (l *LimitScan) IsNull(s string) (bool, error) {
+	return l.s.IsNull(s)
+}
+
+func (l *LimitScan) HasField(s string) bool {
+	return l.s.HasField(s)
+}
+
+func (l *LimitScan) Close() {
+	l.s.Close()
+}
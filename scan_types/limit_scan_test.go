@@ -0,0 +1,54 @@
+package scan_types
+
+import (
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestLimitScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	testTableSchema := record.NewSchema()
+	testTableSchema.AddIntField("id")
+	layout := record.NewLayout(testTableSchema)
+	ts, err := NewTableScan(txn, "limit_table", layout)
+	assert.NoError(err)
+	testRecordCount := 100
+	for i := 0; i < testRecordCount; i++ {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+	}
+
+	assert.NoError(ts.BeforeFirst())
+	limitScan := NewLimitScan(ts, 10, 5)
+	expected := 5
+	for hasNext, err := limitScan.Next(); hasNext; hasNext, err = limitScan.Next() {
+		assert.NoError(err)
+		id, err := limitScan.GetInt("id")
+		assert.NoError(err)
+		assert.Equal(expected, id)
+		expected++
+	}
+	assert.Equal(15, expected)
+
+	//the underlying scan must not be read past the limit
+	id, err := ts.GetInt("id")
+	assert.NoError(err)
+	assert.Equal(14, id)
+
+	//offset past the end
+	limitScan = NewLimitScan(ts, 10, testRecordCount)
+	assert.NoError(limitScan.BeforeFirst())
+	hasNext, err := limitScan.Next()
+	assert.NoError(err)
+	assert.False(hasNext)
+	limitScan.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
@@ -0,0 +1,161 @@
+package scan_types
+
+import (
+	"jadb/query"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*OuterJoinScan)(nil)
+
+// OuterJoinScan
+// nested loop join of left and right on pred, a left record without a
+// match is returned once with the fields of right set to null. When full is
+// set the right records without a match are returned afterwards with the
+// fields of left set to null
+type OuterJoinScan struct {
+	left       scan.Scan
+	right      scan.Scan
+	pred       *query.Predicate
+	full       bool
+	onLeft     bool
+	leftDone   bool
+	rightPass  bool
+	matched    bool
+	padLeft    bool
+	padRight   bool
+	rightPos   int
+	rightMatch map[int]bool
+}
+
+func NewOuterJoinScan(left scan.Scan, right scan.Scan, pred *query.Predicate, full bool) *OuterJoinScan {
+	return &OuterJoinScan{left: left, right: right, pred: pred, full: full, rightMatch: make(map[int]bool)}
+}
+
+func (o *OuterJoinScan) BeforeFirst() error {
+	o.onLeft, o.leftDone, o.rightPass, o.matched = false, false, false, false
+	o.padLeft, o.padRight = false, false
+	o.rightMatch = make(map[int]bool)
+	if err := o.left.BeforeFirst(); err != nil {
+		return err
+	}
+	return o.right.BeforeFirst()
+}
+
+func (o *OuterJoinScan) Next() (bool, error) {
+	o.padLeft, o.padRight = false, false
+	for !o.leftDone {
+		if !o.onLeft {
+			hasNext, err := o.left.Next()
+			if err != nil {
+				return false, err
+			}
+			if !hasNext {
+				o.leftDone = true
+				break
+			}
+			if err := o.right.BeforeFirst(); err != nil {
+				return false, err
+			}
+			o.onLeft, o.matched, o.rightPos = true, false, -1
+		}
+		hasNext, err := o.right.Next()
+		if err != nil {
+			return false, err
+		}
+		if !hasNext {
+			o.onLeft = false
+			if !o.matched {
+				o.padRight = true
+				return true, nil
+			}
+			continue
+		}
+		o.rightPos++
+		satisfied, err := o.pred.IsSatisfied(o)
+		if err != nil {
+			return false, err
+		}
+		if satisfied {
+			o.matched = true
+			o.rightMatch[o.rightPos] = true
+			return true, nil
+		}
+	}
+	if !o.full {
+		return false, nil
+	}
+	return o.nextUnmatchedRight()
+}
+
+// nextUnmatchedRight
+// second pass over right for the records no left record matched
+func (o *OuterJoinScan) nextUnmatchedRight() (bool, error) {
+	if !o.rightPass {
+		if err := o.right.BeforeFirst(); err != nil {
+			return false, err
+		}
+		o.rightPass, o.rightPos = true, -1
+	}
+	for {
+		hasNext, err := o.right.Next()
+		if err != nil || !hasNext {
+			return false, err
+		}
+		o.rightPos++
+		if !o.rightMatch[o.rightPos] {
+			o.padLeft = true
+			return true, nil
+		}
+	}
+}
+
+func (o *OuterJoinScan) GetInt(fldName string) (int, error) {
+	val, err := o.GetVal(fldName)
+	if err != nil || val == nil {
+		return 0, err
+	}
+	return val.(int), nil
+}
+
+func (o *OuterJoinScan) GetString(fldName string) (string, error) {
+	val, err := o.GetVal(fldName)
+	if err != nil || val == nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+func (o *OuterJoinScan) GetVal(fldName string) (any, error) {
+	if o.left.HasField(fldName) {
+		if o.padLeft {
+			return nil, nil
+		}
+		return o.left.GetVal(fldName)
+	}
+	if o.padRight {
+		return nil, nil
+	}
+	return o.right.GetVal(fldName)
+}
+
+func (o *OuterJoinScan) IsNull(fldName string) (bool, error) {
+	if o.left.HasField(fldName) {
+		if o.padLeft {
+			return true, nil
+		}
+		return o.left.IsNull(fldName)
+	}
+	if o.padRight {
+		return true, nil
+	}
+	return o.right.IsNull(fldName)
+}
+
+func (o *OuterJoinScan) HasField(fldName string) bool {
+	return o.left.HasField(fldName) || o.right.HasField(fldName)
+}
+
+func (o *OuterJoinScan) Close() {
+	o.left.Close()
+	o.right.Close()
+}
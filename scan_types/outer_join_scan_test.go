@@ -0,0 +1,67 @@
+package scan_types
+
+import (
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/query"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestOuterJoinScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	leftSchema := record.NewSchema()
+	leftSchema.AddIntField("lid")
+	leftLayout := record.NewLayout(leftSchema)
+	ts, err := NewTableScan(txn, "join_left", leftLayout)
+	assert.NoError(err)
+	for _, id := range []int{1, 2, 3} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("lid", id))
+	}
+	ts.Close()
+	rightSchema := record.NewSchema()
+	rightSchema.AddIntField("rid")
+	rightLayout := record.NewLayout(rightSchema)
+	ts, err = NewTableScan(txn, "join_right", rightLayout)
+	assert.NoError(err)
+	for _, id := range []int{2, 3, 3, 4} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("rid", id))
+	}
+	ts.Close()
+
+	pred := query.NewPredicateFromTerm(query.NewTerm(
+		query.NewFieldExpression("lid"), query.NewFieldExpression("rid"), query.Equal))
+	readPairs := func(full bool) [][2]any {
+		left, err := NewTableScan(txn, "join_left", leftLayout)
+		assert.NoError(err)
+		right, err := NewTableScan(txn, "join_right", rightLayout)
+		assert.NoError(err)
+		joinScan := NewOuterJoinScan(left, right, pred, full)
+		defer joinScan.Close()
+		pairs := make([][2]any, 0)
+		for hasNext, err := joinScan.Next(); hasNext || err != nil; hasNext, err = joinScan.Next() {
+			assert.NoError(err)
+			lid, err := joinScan.GetVal("lid")
+			assert.NoError(err)
+			rid, err := joinScan.GetVal("rid")
+			assert.NoError(err)
+			isNull, err := joinScan.IsNull("rid")
+			assert.NoError(err)
+			assert.Equal(rid == nil, isNull)
+			pairs = append(pairs, [2]any{lid, rid})
+		}
+		return pairs
+	}
+
+	assert.Equal([][2]any{{1, nil}, {2, 2}, {3, 3}, {3, 3}}, readPairs(false))
+	assert.Equal([][2]any{{1, nil}, {2, 2}, {3, 3}, {3, 3}, {nil, 4}}, readPairs(true))
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
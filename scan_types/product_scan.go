@@ -68,6 +68,13 @@ func (p *ProductScan) GetVal(s string) (any, error) {
 	return p.s2.GetVal(s)
 }
 
+func (p *ProductScan) IsNull(s string) (bool, error) {
+	if p.s1.HasField(s) {
+		return p.s1.IsNull(s)
+	}
+	return p.s2.IsNull(s)
+}
+
 func (p *ProductScan) HasField(s string) bool {
 	return p.s1.HasField(s) || p.s2.HasField(s)
 }
@@ -45,6 +45,13 @@ func (p ProjectScan) GetVal(s string) (any, error) {
 	return nil, fmt.Errorf("field %s not found", s)
 }
 
+func (p ProjectScan) IsNull(s string) (bool, error) {
+	if p.HasField(s) {
+		return p.s.IsNull(s)
+	}
+	return false, fmt.Errorf("field %s not found", s)
+}
+
 func (p ProjectScan) HasField(s string) bool {
 	_, ok := p.fldList[s]
 	return ok
@@ -0,0 +1,56 @@
+package scan_types
+
+import (
+	"jadb/query"
+	"jadb/scan"
+)
+
+type RecordComparator struct {
+	fields []*query.OrderField
+}
+
+func NewRecordComparator(fields []*query.OrderField) *RecordComparator {
+	return &RecordComparator{fields}
+}
+
+// Compare
+// compares the current records of both scans on the sort fields
+func (c *RecordComparator) Compare(s1 scan.Scan, s2 scan.Scan) (int, error) {
+	key1, err := c.key(s1)
+	if err != nil {
+		return 0, err
+	}
+	key2, err := c.key(s2)
+	if err != nil {
+		return 0, err
+	}
+	return c.compareKeys(key1, key2)
+}
+
+func (c *RecordComparator) key(s scan.Scan) ([]any, error) {
+	key := make([]any, len(c.fields))
+	for i, field := range c.fields {
+		val, err := s.GetVal(field.FieldName())
+		if err != nil {
+			return nil, err
+		}
+		key[i] = val
+	}
+	return key, nil
+}
+
+func (c *RecordComparator) compareKeys(key1 []any, key2 []any) (int, error) {
+	for i, field := range c.fields {
+		result, err := query.CompareValues(key1[i], key2[i])
+		if err != nil {
+			return 0, err
+		}
+		if result != 0 {
+			if field.IsDescending() {
+				return -result, nil
+			}
+			return result, nil
+		}
+	}
+	return 0, nil
+}
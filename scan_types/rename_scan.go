@@ -0,0 +1,67 @@
+package scan_types
+
+import (
+	"fmt"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*RenameScan)(nil)
+
+// RenameScan
+// exposes fields of the underlying scan under new names, fields that
+// are not renamed are hidden
+type RenameScan struct {
+	s      scan.Scan
+	fields map[string]string
+}
+
+// NewRenameScan
+// fields maps the new name of a field to its name in s
+func NewRenameScan(s scan.Scan, fields map[string]string) *RenameScan {
+	return &RenameScan{s, fields}
+}
+
+func (r *RenameScan) BeforeFirst() error {
+	return r.s.BeforeFirst()
+}
+
+func (r *RenameScan) Next() (bool, error) {
+	return r.s.Next()
+}
+
+func (r *RenameScan) GetInt(fldName string) (int, error) {
+	if source, ok := r.fields[fldName]; ok {
+		return r.s.GetInt(source)
+	}
+	return -1, fmt.Errorf("field %s not found", fldName)
+}
+
+func (r *RenameScan) GetString(fldName string) (string, error) {
+	if source, ok := r.fields[fldName]; ok {
+		return r.s.GetString(source)
+	}
+	return "", fmt.Errorf("field %s not found", fldName)
+}
+
+func (r *RenameScan) GetVal(fldName string) (any, error) {
+	if source, ok := r.fields[fldName]; ok {
+		return r.s.GetVal(source)
+	}
+	return nil, fmt.Errorf("field %s not found", fldName)
+}
+
+func (r *RenameScan) IsNull(fldName string) (bool, error) {
+	if source, ok := r.fields[fldName]; ok {
+		return r.s.IsNull(source)
+	}
+	return false, fmt.Errorf("field %s not found", fldName)
+}
+
+func (r *RenameScan) HasField(fldName string) bool {
+	_, ok := r.fields[fldName]
+	return ok
+}
+
+func (r *RenameScan) Close() {
+	r.s.Close()
+}
@@ -22,15 +22,19 @@ func (selectScan *SelectScan) BeforeFirst() error {
 }
 
 func (selectScan *SelectScan) Next() (bool, error) {
-	for hasNext, err := selectScan.s.Next(); hasNext; hasNext, err = selectScan.Next() {
+	for {
+		hasNext, err := selectScan.s.Next()
+		if err != nil || !hasNext {
+			return false, err
+		}
+		satisfied, err := selectScan.pred.IsSatisfied(selectScan.s)
 		if err != nil {
 			return false, err
 		}
-		if selectScan.pred.IsSatisfied(selectScan.s) {
+		if satisfied {
 			return true, nil
 		}
 	}
-	return false, nil
 }
 
 func (selectScan *SelectScan) GetInt(s2 string) (int, error) {
@@ -45,6 +49,10 @@ func (selectScan *SelectScan) GetVal(s2 string) (any, error) {
 	return selectScan.s.GetVal(s2)
 }
 
+func (selectScan *SelectScan) IsNull(s2 string) (bool, error) {
+	return selectScan.s.IsNull(s2)
+}
+
 func (selectScan *SelectScan) HasField(s2 string) bool {
 	return selectScan.s.HasField(s2)
 }
@@ -68,6 +76,11 @@ func (selectScan *SelectScan) SetVal(s2 string, a any) error {
 	return updateScan.SetVal(s2, a)
 }
 
+func (selectScan *SelectScan) SetNull(s2 string) error {
+	updateScan := selectScan.s.(scan.UpdateScan)
+	return updateScan.SetNull(s2)
+}
+
 func (selectScan *SelectScan) Insert() error {
 	updateScan := selectScan.s.(scan.UpdateScan)
 	return updateScan.Insert()
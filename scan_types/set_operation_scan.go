@@ -0,0 +1,127 @@
+package scan_types
+
+import (
+	"fmt"
+	"jadb/query"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*SetOperationScan)(nil)
+
+// SetOperationScan
+// hash based intersect and except of left and right. The records of right
+// are counted by value the first time Next is called, the records of left
+// are then returned depending on how many equal records right has. Without
+// all every record is returned at most once, with all intersect returns a
+// record as often as both sides have it and except as often as left has
+// it more than right. Nulls are equal to each other
+type SetOperationScan struct {
+	left   scan.Scan
+	right  scan.Scan
+	op     query.SetOperator
+	all    bool
+	fields []string
+	counts map[string]int
+	seen   map[string]bool
+}
+
+// NewSetOperationScan
+// fields are the fields records are compared on, right has to have the
+// same field names as left
+func NewSetOperationScan(left scan.Scan, right scan.Scan, op query.SetOperator, all bool, fields []string) *SetOperationScan {
+	return &SetOperationScan{left: left, right: right, op: op, all: all, fields: fields}
+}
+
+func (s *SetOperationScan) BeforeFirst() error {
+	s.counts, s.seen = nil, nil
+	return s.left.BeforeFirst()
+}
+
+func (s *SetOperationScan) Next() (bool, error) {
+	if s.counts == nil {
+		if err := s.countRight(); err != nil {
+			return false, err
+		}
+	}
+	for {
+		hasNext, err := s.left.Next()
+		if err != nil || !hasNext {
+			return false, err
+		}
+		key, err := recordKey(s.left, s.fields)
+		if err != nil {
+			return false, err
+		}
+		if s.keep(key) {
+			return true, nil
+		}
+	}
+}
+
+func (s *SetOperationScan) keep(key string) bool {
+	if !s.all {
+		if s.seen[key] {
+			return false
+		}
+		inRight := s.counts[key] > 0
+		if inRight != (s.op == query.Intersect) {
+			return false
+		}
+		s.seen[key] = true
+		return true
+	}
+	count := s.counts[key]
+	if count > 0 {
+		s.counts[key]--
+	}
+	if s.op == query.Intersect {
+		return count > 0
+	}
+	return count == 0
+}
+
+func (s *SetOperationScan) countRight() error {
+	if s.op != query.Intersect && s.op != query.Except {
+		return fmt.Errorf("set operation %s is not supported", s.op)
+	}
+	s.counts, s.seen = make(map[string]int), make(map[string]bool)
+	if err := s.right.BeforeFirst(); err != nil {
+		return err
+	}
+	for {
+		hasNext, err := s.right.Next()
+		if err != nil || !hasNext {
+			return err
+		}
+		key, err := recordKey(s.right, s.fields)
+		if err != nil {
+			return err
+		}
+		s.counts[key]++
+	}
+}
+
+func (s *SetOperationScan) GetInt(fldName string) (int, error) {
+	return s.left.GetInt(fldName)
+}
+
+func (s *SetOperationScan) GetString(fldName string) (string, error) {
+	return s.left.GetString(fldName)
+}
+
+func (s *SetOperationScan) GetVal(fldName string) (any, error) {
+	return s.left.GetVal(fldName)
+}
+
+func (s *SetOperationScan) IsNull(fldName string) (bool, error) {
+	return s.left.IsNull(fldName)
+}
+
+func (s *SetOperationScan) HasField(fldName string) bool {
+	return s.left.HasField(fldName)
+}
+
+func (s *SetOperationScan) Close() {
+	s.left.Close()
+	s.right.Close()
+}
@@ -0,0 +1,75 @@
+package scan_types
+
+import (
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/query"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestSetOperationScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	layout := record.NewLayout(schema)
+	for tblName, ids := range map[string][]int{
+		"set_left":  {1, 1, 1, 2, 3},
+		"set_right": {1, 1, 3, 4},
+	} {
+		ts, err := NewTableScan(txn, tblName, layout)
+		assert.NoError(err)
+		for _, id := range ids {
+			assert.NoError(ts.Insert())
+			assert.NoError(ts.SetInt("id", id))
+		}
+		ts.Close()
+	}
+
+	readIds := func(op query.SetOperator, all bool) []int {
+		left, err := NewTableScan(txn, "set_left", layout)
+		assert.NoError(err)
+		right, err := NewTableScan(txn, "set_right", layout)
+		assert.NoError(err)
+		s := NewSetOperationScan(left, right, op, all, []string{"id"})
+		defer s.Close()
+		ids := make([]int, 0)
+		for hasNext, err := s.Next(); hasNext || err != nil; hasNext, err = s.Next() {
+			assert.NoError(err)
+			id, err := s.GetInt("id")
+			assert.NoError(err)
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	assert.Equal([]int{1, 3}, readIds(query.Intersect, false))
+	assert.Equal([]int{1, 1, 3}, readIds(query.Intersect, true))
+	assert.Equal([]int{2}, readIds(query.Except, false))
+	assert.Equal([]int{1, 2}, readIds(query.Except, true))
+
+	//concatenation keeps every record of both sides
+	left, err := NewTableScan(txn, "set_left", layout)
+	assert.NoError(err)
+	right, err := NewTableScan(txn, "set_right", layout)
+	assert.NoError(err)
+	concat := NewConcatScan(left, right)
+	count := 0
+	for hasNext, err := concat.Next(); hasNext || err != nil; hasNext, err = concat.Next() {
+		assert.NoError(err)
+		count++
+	}
+	assert.Equal(9, count)
+	assert.NoError(concat.BeforeFirst())
+	hasNext, err := concat.Next()
+	assert.NoError(err)
+	assert.True(hasNext)
+	concat.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
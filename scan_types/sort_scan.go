@@ -0,0 +1,117 @@
+package scan_types
+
+import (
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*SortScan)(nil)
+
+// SortScan
+// merges the last one or two sorted runs of an external sort
+type SortScan struct {
+	s1          *TableScan
+	s2          *TableScan
+	currentScan *TableScan
+	comp        *RecordComparator
+	hasMore1    bool
+	hasMore2    bool
+}
+
+func NewSortScan(runs []*TempTable, comp *RecordComparator) (*SortScan, error) {
+	sortScan := &SortScan{comp: comp}
+	var err error
+	if sortScan.s1, err = runs[0].Open(); err != nil {
+		return nil, err
+	}
+	if len(runs) > 1 {
+		if sortScan.s2, err = runs[1].Open(); err != nil {
+			sortScan.s1.Close()
+			return nil, err
+		}
+	}
+	if err := sortScan.BeforeFirst(); err != nil {
+		sortScan.Close()
+		return nil, err
+	}
+	return sortScan, nil
+}
+
+func (s *SortScan) BeforeFirst() error {
+	var err error
+	s.currentScan = nil
+	if err = s.s1.BeforeFirst(); err != nil {
+		return err
+	}
+	if s.hasMore1, err = s.s1.Next(); err != nil {
+		return err
+	}
+	if s.s2 != nil {
+		if err = s.s2.BeforeFirst(); err != nil {
+			return err
+		}
+		if s.hasMore2, err = s.s2.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SortScan) Next() (bool, error) {
+	var err error
+	if s.currentScan != nil {
+		if s.currentScan == s.s1 {
+			s.hasMore1, err = s.s1.Next()
+		} else {
+			s.hasMore2, err = s.s2.Next()
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	switch {
+	case !s.hasMore1 && !s.hasMore2:
+		return false, nil
+	case s.hasMore1 && s.hasMore2:
+		result, err := s.comp.Compare(s.s1, s.s2)
+		if err != nil {
+			return false, err
+		}
+		if result <= 0 {
+			s.currentScan = s.s1
+		} else {
+			s.currentScan = s.s2
+		}
+	case s.hasMore1:
+		s.currentScan = s.s1
+	default:
+		s.currentScan = s.s2
+	}
+	return true, nil
+}
+
+func (s *SortScan) GetInt(fldName string) (int, error) {
+	return s.currentScan.GetInt(fldName)
+}
+
+func (s *SortScan) GetString(fldName string) (string, error) {
+	return s.currentScan.GetString(fldName)
+}
+
+func (s *SortScan) GetVal(fldName string) (any, error) {
+	return s.currentScan.GetVal(fldName)
+}
+
+func (s *SortScan) IsNull(fldName string) (bool, error) {
+	return s.currentScan.IsNull(fldName)
+}
+
+func (s *SortScan) HasField(fldName string) bool {
+	return s.s1.HasField(fldName)
+}
+
+func (s *SortScan) Close() {
+	s.s1.Close()
+	if s.s2 != nil {
+		s.s2.Close()
+	}
+}
@@ -13,12 +13,25 @@ type TableScan struct {
 	tx          *tx.Transaction
 	layout      *record.Layout
 	filename    string
+	fsm         *record.FreeSpaceMap
 	rp          *record.RecordPage
 	currentSlot int
 }
 
+// TableFileName
+// the file holding the records of the table
+func TableFileName(tablename string) string {
+	return tablename + ".tbl"
+}
+
+// FreeSpaceFileName
+// the file holding the free space map of the table
+func FreeSpaceFileName(tablename string) string {
+	return tablename + ".fsm"
+}
+
 func NewTableScan(tx *tx.Transaction, tablename string, layout *record.Layout) (*TableScan, error) {
-	filename := tablename + ".tbl"
+	filename := TableFileName(tablename)
 	size, err := tx.Size(filename)
 	if err != nil {
 		return nil, err
@@ -28,6 +41,7 @@ func NewTableScan(tx *tx.Transaction, tablename string, layout *record.Layout) (
 		tx,
 		layout,
 		filename,
+		record.NewFreeSpaceMap(tx, FreeSpaceFileName(tablename)),
 		rp,
 		-1,
 	}
@@ -75,20 +89,35 @@ func (ts *TableScan) Next() (bool, error) {
 	return true, nil
 }
 
+// GetInt
+// 0 when the field is null
 func (ts *TableScan) GetInt(fldName string) (int, error) {
+	if isNull, err := ts.IsNull(fldName); err != nil || isNull {
+		return 0, err
+	}
 	return ts.rp.GetInt(ts.currentSlot, fldName)
 }
 
+// GetString
+// empty when the field is null
 func (ts *TableScan) GetString(fldName string) (string, error) {
+	if isNull, err := ts.IsNull(fldName); err != nil || isNull {
+		return "", err
+	}
 	return ts.rp.GetString(ts.currentSlot, fldName)
 }
 
+// GetVal
+// nil when the field is null
 func (ts *TableScan) GetVal(fldName string) (any, error) {
-	if ts.layout.Schema().Type(fldName) == record.INTEGER {
-		return ts.GetInt(fldName)
-	} else {
-		return ts.GetString(fldName)
+	if isNull, err := ts.IsNull(fldName); err != nil || isNull {
+		return nil, err
 	}
+	return ts.rp.GetVal(ts.currentSlot, fldName)
+}
+
+func (ts *TableScan) IsNull(fldName string) (bool, error) {
+	return ts.rp.IsNull(ts.currentSlot, fldName)
 }
 
 func (ts *TableScan) HasField(fldName string) bool {
@@ -103,44 +132,62 @@ func (ts *TableScan) SetString(fldName string, val string) error {
 	return ts.rp.SetString(ts.currentSlot, fldName, val)
 }
 
+func (ts *TableScan) SetNull(fldName string) error {
+	return ts.rp.SetNull(ts.currentSlot, fldName)
+}
+
+// SetVal
+// a nil val sets the field to null
 func (ts *TableScan) SetVal(fldName string, val any) error {
-	if ts.layout.Schema().Type(fldName) == record.INTEGER {
-		return ts.SetInt(fldName, val.(int))
-	} else {
-		return ts.SetString(fldName, val.(string))
+	if val == nil {
+		return ts.SetNull(fldName)
 	}
+	return ts.rp.SetVal(ts.currentSlot, fldName, val)
 }
 
+// Insert
+// a new record in the first block the free space map shows room in, or in
+// a new block at the end of the file. A block found full on the way gets
+// its entry in the map corrected
 func (ts *TableScan) Insert() error {
-	var err error
-	ts.currentSlot, err = ts.rp.InsertAfter(ts.currentSlot)
+	size, err := ts.tx.Size(ts.filename)
 	if err != nil {
 		return err
 	}
-	for ts.currentSlot < 0 {
-		atLast, err := ts.atLastBlock()
-		if err != nil {
+	blockNumber, err := ts.fsm.Find(ts.layout, 0, size)
+	for ; blockNumber >= 0; blockNumber, err = ts.fsm.Find(ts.layout, blockNumber+1, size) {
+		if err := ts.moveToBlock(blockNumber); err != nil {
 			return err
 		}
-		if atLast {
-			if err = ts.moveToNewBlock(); err != nil {
-				return err
-			}
-		} else {
-			if err = ts.moveToBlock(ts.rp.Block().GetBlockNumber() + 1); err != nil {
-				return err
-			}
+		if ts.currentSlot, err = ts.rp.InsertAfter(-1); err != nil {
+			return err
 		}
-		ts.currentSlot, err = ts.rp.InsertAfter(ts.currentSlot)
-		if err != nil {
+		if err := ts.fsm.Update(ts.rp); err != nil {
 			return err
 		}
+		if ts.currentSlot >= 0 {
+			return nil
+		}
 	}
-	return nil
+	if err != nil {
+		return err
+	}
+	if err := ts.moveToNewBlock(); err != nil {
+		return err
+	}
+	if ts.currentSlot, err = ts.rp.InsertAfter(-1); err != nil {
+		return err
+	}
+	return ts.fsm.Update(ts.rp)
 }
 
+// Delete
+// the space of the record shows in the free space map right away
 func (ts *TableScan) Delete() error {
-	return ts.rp.Delete(ts.currentSlot)
+	if err := ts.rp.Delete(ts.currentSlot); err != nil {
+		return err
+	}
+	return ts.fsm.Update(ts.rp)
 }
 
 func (ts *TableScan) MoveToRid(rid *record.RID) error {
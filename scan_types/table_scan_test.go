@@ -116,3 +116,59 @@ func TestTableScan(t *testing.T) {
 	assert.NoError(err)
 	clearEnv(t, env)
 }
+
+func TestTableScanFreeSpaceMap(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+	defer clearEnv(t, env)
+	schema := record.NewSchema()
+	schema.AddIntField("id")
+	schema.AddStringField("name", 200)
+	layout := record.NewLayout(schema)
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	ts, err := NewTableScan(txn, "fsm", layout)
+	assert.NoError(err)
+	for i := range 200 {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", i))
+		assert.NoError(ts.SetString("name", fmt.Sprintf("%0200d", i)))
+	}
+	size, err := txn.Size(TableFileName("fsm"))
+	assert.NoError(err)
+	assert.Greater(size, 5)
+
+	//the space freed in the first block is found by the next insert
+	assert.NoError(ts.BeforeFirst())
+	for {
+		hasNext, err := ts.Next()
+		assert.NoError(err)
+		if !hasNext || ts.GetRid().BlockNumber() > 0 {
+			break
+		}
+		assert.NoError(ts.Delete())
+	}
+	ts.Close()
+	assert.NoError(txn.Commit())
+
+	txn, err = tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	ts, err = NewTableScan(txn, "fsm", layout)
+	assert.NoError(err)
+	assert.NoError(ts.Insert())
+	assert.Equal(0, ts.GetRid().BlockNumber())
+	assert.NoError(ts.SetInt("id", 1000))
+
+	//blocks the map shows room in but are full get their entries corrected
+	fsm := record.NewFreeSpaceMap(txn, FreeSpaceFileName("fsm"))
+	assert.NoError(fsm.Clear())
+	for ts.GetRid().BlockNumber() == 0 {
+		assert.NoError(ts.Insert())
+	}
+	next, err := fsm.Find(layout, 1, size)
+	assert.NoError(err)
+	assert.Equal(ts.GetRid().BlockNumber(), next)
+	assert.Greater(next, 1)
+	ts.Close()
+	assert.NoError(txn.Commit())
+}
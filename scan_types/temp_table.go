@@ -0,0 +1,43 @@
+package scan_types
+
+import (
+	"fmt"
+	"jadb/record"
+	"jadb/tx"
+	"sync"
+)
+
+var nextTableNum = 0
+var tableNumLock sync.Mutex
+
+// TempTable
+// table used to materialize intermediate results, files named temp*
+// are removed by the file manager on startup
+type TempTable struct {
+	txn     *tx.Transaction
+	tblName string
+	layout  *record.Layout
+}
+
+func NewTempTable(txn *tx.Transaction, schema *record.Schema) *TempTable {
+	return &TempTable{txn, nextTableName(), record.NewLayout(schema)}
+}
+
+func (t *TempTable) Open() (*TableScan, error) {
+	return NewTableScan(t.txn, t.tblName, t.layout)
+}
+
+func (t *TempTable) TableName() string {
+	return t.tblName
+}
+
+func (t *TempTable) Layout() *record.Layout {
+	return t.layout
+}
+
+func nextTableName() string {
+	tableNumLock.Lock()
+	defer tableNumLock.Unlock()
+	nextTableNum++
+	return fmt.Sprintf("temp%d", nextTableNum)
+}
@@ -0,0 +1,177 @@
+package scan_types
+
+import (
+	"container/heap"
+	"fmt"
+	"jadb/scan"
+)
+
+var _ scan.Scan = (*TopNScan)(nil)
+
+type topNRow struct {
+	key    []any
+	values map[string]any
+}
+
+// topNHeap
+// max heap on the sort order, the root is the worst of the rows kept
+type topNHeap struct {
+	rows []*topNRow
+	comp *RecordComparator
+	err  error
+}
+
+func (h *topNHeap) Len() int {
+	return len(h.rows)
+}
+
+func (h *topNHeap) Less(i, j int) bool {
+	result, err := h.comp.compareKeys(h.rows[i].key, h.rows[j].key)
+	if err != nil && h.err == nil {
+		h.err = err
+	}
+	return result > 0
+}
+
+func (h *topNHeap) Swap(i, j int) {
+	h.rows[i], h.rows[j] = h.rows[j], h.rows[i]
+}
+
+func (h *topNHeap) Push(x any) {
+	h.rows = append(h.rows, x.(*topNRow))
+}
+
+func (h *topNHeap) Pop() any {
+	last := h.rows[len(h.rows)-1]
+	h.rows = h.rows[:len(h.rows)-1]
+	return last
+}
+
+// TopNScan
+// returns the first n records of the underlying scan in sort order,
+// only n records are kept in memory instead of sorting the whole input
+type TopNScan struct {
+	s       scan.Scan
+	comp    *RecordComparator
+	n       int
+	fields  []string
+	rows    []*topNRow
+	current int
+	loaded  bool
+}
+
+func NewTopNScan(s scan.Scan, comp *RecordComparator, n int, fields []string) *TopNScan {
+	return &TopNScan{s: s, comp: comp, n: n, fields: fields, current: -1}
+}
+
+func (t *TopNScan) BeforeFirst() error {
+	t.current = -1
+	return nil
+}
+
+func (t *TopNScan) Next() (bool, error) {
+	if !t.loaded {
+		if err := t.load(); err != nil {
+			return false, err
+		}
+		t.loaded = true
+	}
+	t.current++
+	return t.current < len(t.rows), nil
+}
+
+func (t *TopNScan) GetInt(fldName string) (int, error) {
+	val, err := t.GetVal(fldName)
+	if err != nil || val == nil {
+		return 0, err
+	}
+	return val.(int), nil
+}
+
+func (t *TopNScan) GetString(fldName string) (string, error) {
+	val, err := t.GetVal(fldName)
+	if err != nil || val == nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+func (t *TopNScan) GetVal(fldName string) (any, error) {
+	val, ok := t.rows[t.current].values[fldName]
+	if !ok {
+		return nil, fmt.Errorf("field %s not found", fldName)
+	}
+	return val, nil
+}
+
+func (t *TopNScan) IsNull(fldName string) (bool, error) {
+	val, err := t.GetVal(fldName)
+	if err != nil {
+		return false, err
+	}
+	return val == nil, nil
+}
+
+func (t *TopNScan) HasField(fldName string) bool {
+	return t.s.HasField(fldName)
+}
+
+func (t *TopNScan) Close() {
+	t.s.Close()
+}
+
+func (t *TopNScan) load() error {
+	h := &topNHeap{rows: make([]*topNRow, 0, t.n), comp: t.comp}
+	if t.n > 0 {
+		for hasNext, err := t.s.Next(); hasNext || err != nil; hasNext, err = t.s.Next() {
+			if err != nil {
+				return err
+			}
+			key, err := t.comp.key(t.s)
+			if err != nil {
+				return err
+			}
+			if h.Len() == t.n {
+				//only keep the row if it sorts before the worst row kept
+				result, err := t.comp.compareKeys(key, h.rows[0].key)
+				if err != nil {
+					return err
+				}
+				if result >= 0 {
+					continue
+				}
+			}
+			row, err := t.readRow(key)
+			if err != nil {
+				return err
+			}
+			if h.Len() == t.n {
+				h.rows[0] = row
+				heap.Fix(h, 0)
+			} else {
+				heap.Push(h, row)
+			}
+			if h.err != nil {
+				return h.err
+			}
+		}
+	}
+	//popping returns the worst row first
+	t.rows = make([]*topNRow, h.Len())
+	for i := len(t.rows) - 1; i >= 0; i-- {
+		t.rows[i] = heap.Pop(h).(*topNRow)
+	}
+	return h.err
+}
+
+func (t *TopNScan) readRow(key []any) (*topNRow, error) {
+	values := make(map[string]any, len(t.fields))
+	for _, fldName := range t.fields {
+		val, err := t.s.GetVal(fldName)
+		if err != nil {
+			return nil, err
+		}
+		values[fldName] = val
+	}
+	return &topNRow{key, values}, nil
+}
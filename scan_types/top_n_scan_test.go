@@ -0,0 +1,61 @@
+package scan_types
+
+import (
+	"fmt"
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/query"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestTopNScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	testTableSchema := record.NewSchema()
+	testTableSchema.AddIntField("id")
+	testTableSchema.AddStringField("name", 10)
+	layout := record.NewLayout(testTableSchema)
+	ts, err := NewTableScan(txn, "top_n_table", layout)
+	assert.NoError(err)
+	//ids inserted out of order
+	testRecordCount := 500
+	for i := 0; i < testRecordCount; i++ {
+		id := (i * 37) % testRecordCount
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetInt("id", id))
+		assert.NoError(ts.SetString("name", fmt.Sprintf("nam%d", id)))
+	}
+	assert.NoError(ts.BeforeFirst())
+
+	comp := NewRecordComparator([]*query.OrderField{query.NewOrderField("id", true)})
+	topNScan := NewTopNScan(ts, comp, 10, testTableSchema.Fields())
+	expected := testRecordCount - 1
+	for hasNext, err := topNScan.Next(); hasNext; hasNext, err = topNScan.Next() {
+		assert.NoError(err)
+		id, err := topNScan.GetInt("id")
+		assert.NoError(err)
+		assert.Equal(expected, id)
+		name, err := topNScan.GetString("name")
+		assert.NoError(err)
+		assert.Equal(fmt.Sprintf("nam%d", id), name)
+		expected--
+	}
+	assert.Equal(testRecordCount-11, expected)
+
+	//rewinding does not read the input again
+	assert.NoError(topNScan.BeforeFirst())
+	hasNext, err := topNScan.Next()
+	assert.NoError(err)
+	assert.True(hasNext)
+	id, err := topNScan.GetInt("id")
+	assert.NoError(err)
+	assert.Equal(testRecordCount-1, id)
+	topNScan.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
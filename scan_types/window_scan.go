@@ -0,0 +1,292 @@
+package scan_types
+
+import (
+	"fmt"
+	"jadb/query"
+	"jadb/scan"
+	"slices"
+)
+
+var _ scan.Scan = (*WindowScan)(nil)
+
+// WindowScan
+// adds the window functions to the records of the underlying scan, which
+// has to be sorted on the partition by and then the order by fields of the
+// functions. All functions have the same window. The records of a
+// partition are read into memory, the function values of all of them are
+// computed and then they are returned one by one
+type WindowScan struct {
+	s         scan.Scan
+	fields    []string
+	fldNames  []string
+	functions []*query.WindowFunction
+	partition []map[string]any
+	current   int
+	next      map[string]any
+	exhausted bool
+}
+
+// NewWindowScan
+// fields are the fields of s kept for every record, the value of
+// functions[i] is the field fldNames[i]
+func NewWindowScan(s scan.Scan, fields []string, fldNames []string, functions []*query.WindowFunction) *WindowScan {
+	return &WindowScan{s: s, fields: fields, fldNames: fldNames, functions: functions}
+}
+
+func (w *WindowScan) BeforeFirst() error {
+	w.partition, w.current, w.next, w.exhausted = nil, 0, nil, false
+	return w.s.BeforeFirst()
+}
+
+func (w *WindowScan) Next() (bool, error) {
+	w.current++
+	if w.current < len(w.partition) {
+		return true, nil
+	}
+	if err := w.loadPartition(); err != nil {
+		return false, err
+	}
+	w.current = 0
+	return len(w.partition) > 0, nil
+}
+
+func (w *WindowScan) GetInt(fldName string) (int, error) {
+	val, err := w.GetVal(fldName)
+	if err != nil || val == nil {
+		return 0, err
+	}
+	return val.(int), nil
+}
+
+func (w *WindowScan) GetString(fldName string) (string, error) {
+	val, err := w.GetVal(fldName)
+	if err != nil || val == nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+func (w *WindowScan) GetVal(fldName string) (any, error) {
+	val, ok := w.partition[w.current][fldName]
+	if !ok {
+		return nil, fmt.Errorf("field %s not found", fldName)
+	}
+	return val, nil
+}
+
+func (w *WindowScan) IsNull(fldName string) (bool, error) {
+	val, err := w.GetVal(fldName)
+	if err != nil {
+		return false, err
+	}
+	return val == nil, nil
+}
+
+func (w *WindowScan) HasField(fldName string) bool {
+	return slices.Contains(w.fldNames, fldName) || w.s.HasField(fldName)
+}
+
+func (w *WindowScan) Close() {
+	w.s.Close()
+}
+
+// loadPartition
+// reads the records up to the first one of the next partition, which is
+// kept for the next call
+func (w *WindowScan) loadPartition() error {
+	w.partition = make([]map[string]any, 0)
+	if w.next != nil {
+		w.partition = append(w.partition, w.next)
+		w.next = nil
+	}
+	partitionBy := w.functions[0].PartitionBy()
+	for !w.exhausted {
+		hasNext, err := w.s.Next()
+		if err != nil {
+			return err
+		}
+		if !hasNext {
+			w.exhausted = true
+			break
+		}
+		row := make(map[string]any, len(w.fields)+len(w.fldNames))
+		for _, fldName := range w.fields {
+			if row[fldName], err = w.s.GetVal(fldName); err != nil {
+				return err
+			}
+		}
+		if len(w.partition) > 0 {
+			same, err := sameValues(w.partition[0], row, partitionBy)
+			if err != nil {
+				return err
+			}
+			if !same {
+				w.next = row
+				break
+			}
+		}
+		w.partition = append(w.partition, row)
+	}
+	if len(w.partition) == 0 {
+		return nil
+	}
+	peers, err := w.peerGroups()
+	if err != nil {
+		return err
+	}
+	for i, fn := range w.functions {
+		if err := w.compute(fn, w.fldNames[i], peers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peerGroups
+// the index of the group of records ordered the same as each record, they
+// are numbered from 0 in the partition order
+func (w *WindowScan) peerGroups() ([]int, error) {
+	orderFields := make([]string, len(w.functions[0].OrderBy()))
+	for i, field := range w.functions[0].OrderBy() {
+		orderFields[i] = field.FieldName()
+	}
+	peers := make([]int, len(w.partition))
+	for i := 1; i < len(w.partition); i++ {
+		same, err := sameValues(w.partition[i-1], w.partition[i], orderFields)
+		if err != nil {
+			return nil, err
+		}
+		peers[i] = peers[i-1]
+		if !same {
+			peers[i]++
+		}
+	}
+	return peers, nil
+}
+
+// compute
+// stores the value of fn for every record of the partition in fldName
+func (w *WindowScan) compute(fn *query.WindowFunction, fldName string, peers []int) error {
+	rows := w.partition
+	switch fn.Function() {
+	case query.RowNumber:
+		for i, row := range rows {
+			row[fldName] = i + 1
+		}
+	case query.Rank:
+		for i, row := range rows {
+			if i == 0 || peers[i] != peers[i-1] {
+				row[fldName] = i + 1
+			} else {
+				row[fldName] = rows[i-1][fldName]
+			}
+		}
+	case query.DenseRank:
+		for i, row := range rows {
+			row[fldName] = peers[i] + 1
+		}
+	case query.Lag, query.Lead:
+		offset := fn.Offset()
+		if fn.Function() == query.Lag {
+			offset = -offset
+		}
+		//read the argument before any computed field is written
+		values := make([]any, len(rows))
+		for i, row := range rows {
+			values[i] = row[fn.FieldName()]
+		}
+		for i, row := range rows {
+			if j := i + offset; j >= 0 && j < len(rows) {
+				row[fldName] = values[j]
+			} else {
+				row[fldName] = fn.Default()
+			}
+		}
+	case query.Sum, query.Avg:
+		return w.computeAggregate(fn, fldName, peers)
+	default:
+		return fmt.Errorf("unknown window function %s", fn.Function())
+	}
+	return nil
+}
+
+// computeAggregate
+// sum and avg of the non null values in the frame of every record, null
+// when there are none. Avg is truncated to an int
+func (w *WindowScan) computeAggregate(fn *query.WindowFunction, fldName string, peers []int) error {
+	rows := w.partition
+	//prefix sums, sums[i] and counts[i] cover the records before i
+	sums := make([]int, len(rows)+1)
+	counts := make([]int, len(rows)+1)
+	for i, row := range rows {
+		sums[i+1], counts[i+1] = sums[i], counts[i]
+		val := row[fn.FieldName()]
+		if val == nil {
+			continue
+		}
+		intVal, ok := val.(int)
+		if !ok {
+			return fmt.Errorf("%s of %s needs int values, got %v", fn.Function(), fn.FieldName(), val)
+		}
+		sums[i+1] += intVal
+		counts[i+1]++
+	}
+	//the last record of every peer group
+	lastPeer := make([]int, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		if i == len(rows)-1 || peers[i] != peers[i+1] {
+			lastPeer[i] = i
+		} else {
+			lastPeer[i] = lastPeer[i+1]
+		}
+	}
+	for i, row := range rows {
+		start, end := 0, len(rows)-1
+		if frame := fn.Frame(); frame != nil {
+			start, end = frameBounds(i, frame, len(rows))
+		} else if len(fn.OrderBy()) > 0 {
+			end = lastPeer[i]
+		}
+		count := 0
+		if start <= end {
+			count = counts[end+1] - counts[start]
+		}
+		if count == 0 {
+			row[fldName] = nil
+			continue
+		}
+		sum := sums[end+1] - sums[start]
+		if fn.Function() == query.Sum {
+			row[fldName] = sum
+		} else {
+			row[fldName] = sum / count
+		}
+	}
+	return nil
+}
+
+// frameBounds
+// indexes of the first and the last record of the frame of record i, cut
+// off at the ends of the partition. The frame is empty when start > end
+func frameBounds(i int, frame *query.Frame, size int) (int, int) {
+	start, end := 0, size-1
+	if frame.Start() != query.UnboundedPreceding {
+		start = max(i+frame.Start(), 0)
+	}
+	if frame.End() != query.UnboundedFollowing {
+		end = min(i+frame.End(), size-1)
+	}
+	return start, end
+}
+
+// sameValues
+// true when both records have equal values for fields, nulls are equal
+func sameValues(row1 map[string]any, row2 map[string]any, fields []string) (bool, error) {
+	for _, fldName := range fields {
+		result, err := query.CompareValues(row1[fldName], row2[fldName])
+		if err != nil || result != 0 {
+			return false, err
+		}
+	}
+	return true, nil
+}
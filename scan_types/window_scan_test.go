@@ -0,0 +1,81 @@
+package scan_types
+
+import (
+	assertPkg "github.com/stretchr/testify/assert"
+	"jadb/query"
+	"jadb/record"
+	"jadb/tx"
+	"testing"
+)
+
+func TestWindowScan(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(assert)
+
+	txn, err := tx.NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	schema := record.NewSchema()
+	schema.AddIntField("dept")
+	schema.AddIntField("salary")
+	layout := record.NewLayout(schema)
+	ts, err := NewTableScan(txn, "window_input", layout)
+	assert.NoError(err)
+	//sorted on dept and salary, nulls first
+	for _, row := range [][]any{{1, 10}, {1, 20}, {1, 20}, {1, 40}, {2, nil}, {2, 5}} {
+		assert.NoError(ts.Insert())
+		assert.NoError(ts.SetVal("dept", row[0]))
+		assert.NoError(ts.SetVal("salary", row[1]))
+	}
+	ts.Close()
+
+	partitionBy := []string{"dept"}
+	orderBy := []*query.OrderField{query.NewOrderField("salary", false)}
+	window := func(fn query.WindowFunctionType, fldName string, offset int, defaultVal any, frame *query.Frame) *query.WindowFunction {
+		return query.NewWindowFunction(fn, fldName, offset, defaultVal, partitionBy, orderBy, frame)
+	}
+	fldNames := []string{"row_number", "rank", "dense_rank", "lag", "lead", "running", "moving", "ahead"}
+	functions := []*query.WindowFunction{
+		window(query.RowNumber, "", 1, nil, nil),
+		window(query.Rank, "", 1, nil, nil),
+		window(query.DenseRank, "", 1, nil, nil),
+		window(query.Lag, "salary", 1, nil, nil),
+		window(query.Lead, "salary", 2, 0, nil),
+		window(query.Sum, "salary", 1, nil, nil),
+		window(query.Sum, "salary", 1, nil, query.NewFrame(-1, 1)),
+		window(query.Avg, "salary", 1, nil, query.NewFrame(1, 2)),
+	}
+	input, err := NewTableScan(txn, "window_input", layout)
+	assert.NoError(err)
+	s := NewWindowScan(input, schema.Fields(), fldNames, functions)
+	assert.True(s.HasField("rank"))
+	assert.True(s.HasField("salary"))
+
+	expected := map[string][]any{
+		"row_number": {1, 2, 3, 4, 1, 2},
+		"rank":       {1, 2, 2, 4, 1, 2},
+		"dense_rank": {1, 2, 2, 3, 1, 2},
+		"lag":        {nil, 10, 20, 20, nil, nil},
+		"lead":       {20, 40, 0, 0, 0, 0},
+		//up to the last record with the same salary
+		"running": {10, 50, 50, 90, nil, 5},
+		"moving":  {30, 50, 80, 60, 5, 5},
+		"ahead":   {20, 30, 40, nil, 5, nil},
+	}
+	for pass := 0; pass < 2; pass++ {
+		actual := make(map[string][]any)
+		for hasNext, err := s.Next(); hasNext || err != nil; hasNext, err = s.Next() {
+			assert.NoError(err)
+			for _, fldName := range fldNames {
+				val, err := s.GetVal(fldName)
+				assert.NoError(err)
+				actual[fldName] = append(actual[fldName], val)
+			}
+		}
+		assert.Equal(expected, actual)
+		assert.NoError(s.BeforeFirst())
+	}
+	s.Close()
+
+	assert.NoError(txn.Rollback())
+	clearEnv(t, env)
+}
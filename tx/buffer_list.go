@@ -22,19 +22,28 @@ func NewBufferList(bm *buffer.Manager) (*BufferList, error) {
 	}, nil
 }
 
+// getBuffer
+// blocks that were not pinned through the transaction are pinned until
+// the transaction ends
 func (list *BufferList) getBuffer(block file.BlockId) (*buffer.Buffer, error) {
+	if pinned, ok := list.buffers[block]; ok {
+		return pinned.buffer, nil
+	}
 	if err := list.pin(block); err != nil {
 		return nil, err
 	}
 	return list.buffers[block].buffer, nil
 }
 
+// pin
+// the buffer manager is only asked once per block, repeated pins
+// are counted here
 func (list *BufferList) pin(block file.BlockId) error {
-	buff, err := list.bm.Pin(&block)
-	if err != nil {
-		return err
-	}
 	if _, ok := list.buffers[block]; !ok {
+		buff, err := list.bm.Pin(&block)
+		if err != nil {
+			return err
+		}
 		list.buffers[block] = &pinnedBuffer{0, buff}
 	}
 	list.buffers[block].pins++
@@ -43,9 +52,8 @@ func (list *BufferList) pin(block file.BlockId) error {
 
 func (list *BufferList) unpin(block file.BlockId) {
 	if pinned, ok := list.buffers[block]; ok {
-		pins := pinned.pins
-		pins--
-		if pins <= 0 {
+		pinned.pins--
+		if pinned.pins <= 0 {
 			list.bm.Unpin(pinned.buffer)
 			delete(list.buffers, block)
 		}
@@ -15,6 +15,9 @@ const (
 	ROLLBACK
 	SET_INT
 	SET_STRING
+	SET_BYTES
+	TRUNCATE
+	SET_BLOCK
 )
 
 type LogRecord interface {
@@ -40,6 +43,12 @@ func CreateLogRecord(data []byte) (LogRecord, error) {
 		return NewSetIntRecordFromPage(page)
 	case SET_STRING:
 		return NewSetStringRecordFromPage(page)
+	case SET_BYTES:
+		return NewSetBytesRecordFromPage(page)
+	case TRUNCATE:
+		return NewTruncateRecordFromPage(page)
+	case SET_BLOCK:
+		return NewSetBlockRecordFromPage(page)
 	}
 	return nil, fmt.Errorf("unexpected LogRecordType")
 }
@@ -2,6 +2,8 @@ package tx
 
 import (
 	"jadb/buffer"
+	"jadb/constants"
+	"jadb/file"
 	"jadb/log"
 )
 
@@ -10,13 +12,17 @@ type RecoveryManager struct {
 	txNum int
 	lm    *log.Manager
 	bm    *buffer.Manager
+	// logged
+	// the records of changes the transaction logged and did not undo
+	logged int
 }
 
 func NewRecoveryManager(tx *Transaction, txNum int, lm *log.Manager, bm *buffer.Manager) (*RecoveryManager, error) {
 	return &RecoveryManager{
-		tx,
-		txNum,
-		lm, bm,
+		tx:    tx,
+		txNum: txNum,
+		lm:    lm,
+		bm:    bm,
 	}, nil
 }
 
@@ -75,6 +81,33 @@ func (rm *RecoveryManager) doRollback() error {
 	return nil
 }
 
+// rollbackTo
+// undoes the changes of the transaction logged after the first savepoint
+// of them, newest first
+func (rm *RecoveryManager) rollbackTo(savepoint int) error {
+	iterator, err := rm.lm.GetIterator()
+	if err != nil {
+		return err
+	}
+	for rm.logged > savepoint && iterator.HasNext() {
+		record, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		logRecord, err := CreateLogRecord(record)
+		if err != nil {
+			return err
+		}
+		if logRecord.TxNumber() == rm.txNum {
+			if err := logRecord.Undo(rm.tx); err != nil {
+				return err
+			}
+			rm.logged--
+		}
+	}
+	return nil
+}
+
 func (rm *RecoveryManager) recover() error {
 	if err := rm.doRecover(); err != nil {
 		return err
@@ -125,11 +158,95 @@ func (rm *RecoveryManager) doRecover() error {
 func (rm *RecoveryManager) setInt(buff *buffer.Buffer, offset int, newVal int) (int, error) {
 	oldVal := buff.Contents().GetInt(offset)
 	logRecord := NewSetIntRecord(rm.txNum, buff.Block(), offset, oldVal, newVal)
-	return logRecord.WriteToLog(rm.lm)
+	return rm.write(logRecord)
+}
+
+// setBytes
+// logs the oldLength bytes at offset being replaced by newVal
+func (rm *RecoveryManager) setBytes(buff *buffer.Buffer, offset int, oldLength int, newVal []byte) (int, error) {
+	oldVal := buff.Contents().GetRaw(offset, oldLength)
+	logRecord := NewSetBytesRecord(rm.txNum, buff.Block(), offset, oldVal, newVal)
+	return rm.write(logRecord)
 }
 
 func (rm *RecoveryManager) setString(buff *buffer.Buffer, offset int, newVal string) (int, error) {
 	oldVal := buff.Contents().GetString(offset)
 	logRecord := NewSetStringRecord(rm.txNum, buff.Block(), offset, oldVal, newVal)
-	return logRecord.WriteToLog(rm.lm)
+	return rm.write(logRecord)
+}
+
+// setBlock
+// logs the bytes of the block that image changes, as runs of a record per
+// block. Runs closer than the bytes a run costs are joined. A block too
+// full for its record to fit in a page of the log takes more records
+func (rm *RecoveryManager) setBlock(buff *buffer.Buffer, image []byte) (int, error) {
+	old := buff.Contents().Contents()
+	//the page boundary, the length of the record and its fields before the runs
+	piece := len(old) - 6*constants.IntSize - file.MaxLength(len(buff.Block().GetFileName()))
+	lsn := -1
+	var runs []blockRun
+	size := 0
+	logRuns := func() error {
+		if len(runs) == 0 {
+			return nil
+		}
+		var err error
+		lsn, err = rm.write(NewSetBlockRecord(rm.txNum, buff.Block(), runs))
+		runs, size = nil, 0
+		return err
+	}
+	for pos := 0; pos < len(old); {
+		if old[pos] == image[pos] {
+			pos++
+			continue
+		}
+		end := pos + 1
+		for gap := 0; end < len(old) && gap < 2*constants.IntSize; end++ {
+			if old[end] == image[end] {
+				gap++
+			} else {
+				gap = 0
+			}
+		}
+		for end > pos && old[end-1] == image[end-1] {
+			end--
+		}
+		for pos < end {
+			if size+2*constants.IntSize >= piece {
+				if err := logRuns(); err != nil {
+					return -1, err
+				}
+			}
+			length := min(end-pos, piece-size-2*constants.IntSize)
+			runs = append(runs, blockRun{pos, append([]byte(nil), old[pos:pos+length]...)})
+			size += 2*constants.IntSize + length
+			pos += length
+		}
+	}
+	if err := logRuns(); err != nil {
+		return -1, err
+	}
+	return lsn, nil
+}
+
+// truncate
+// logs the file being cut down from oldSize to newSize blocks, and flushes
+// the log so the records of the cleared blocks are on disk before they are
+func (rm *RecoveryManager) truncate(filename string, oldSize int, newSize int) error {
+	lsn, err := rm.write(NewTruncateRecord(rm.txNum, filename, oldSize, newSize))
+	if err != nil {
+		return err
+	}
+	return rm.lm.Flush(lsn)
+}
+
+// write
+// logs a change of the transaction
+func (rm *RecoveryManager) write(logRecord LogRecord) (int, error) {
+	lsn, err := logRecord.WriteToLog(rm.lm)
+	if err != nil {
+		return -1, err
+	}
+	rm.logged++
+	return lsn, nil
 }
@@ -0,0 +1,115 @@
+package tx
+
+import (
+	"fmt"
+	"jadb/constants"
+	"jadb/file"
+	"jadb/log"
+)
+
+// blockRun
+// bytes of a block as they were before, starting at offset
+type blockRun struct {
+	offset int
+	oldVal []byte
+}
+
+// SetBlockRecord
+// the before-image of a block rewritten as a whole by SetBlock. Only the
+// runs of bytes that changed are kept, so clearing or copying a block takes
+// one record instead of one for every int
+type SetBlockRecord struct {
+	txNum    int
+	filename string
+	block    *file.BlockId
+	runs     []blockRun
+}
+
+func NewSetBlockRecord(txNum int, block *file.BlockId, runs []blockRun) *SetBlockRecord {
+	return &SetBlockRecord{
+		txNum,
+		block.GetFileName(),
+		block,
+		runs,
+	}
+}
+
+func NewSetBlockRecordFromPage(page *file.Page) (*SetBlockRecord, error) {
+	txNumOffset := constants.IntSize
+	txNum := page.GetInt(txNumOffset)
+	filenameOffset := txNumOffset + constants.IntSize
+	filename := page.GetString(filenameOffset)
+	blockNumOffset := filenameOffset + file.MaxLength(len(filename))
+	block := file.NewBlock(filename, page.GetInt(blockNumOffset))
+	runCountOffset := blockNumOffset + constants.IntSize
+	runs := make([]blockRun, page.GetInt(runCountOffset))
+	runOffset := runCountOffset + constants.IntSize
+	for i := range runs {
+		oldVal := append([]byte(nil), page.GetBytes(runOffset+constants.IntSize)...)
+		runs[i] = blockRun{page.GetInt(runOffset), oldVal}
+		runOffset += 2*constants.IntSize + len(oldVal)
+	}
+	return &SetBlockRecord{
+		txNum,
+		filename,
+		block,
+		runs,
+	}, nil
+}
+
+func (record *SetBlockRecord) Op() LogRecordType {
+	return SET_BLOCK
+}
+
+// Undo
+// writes the runs back into the block
+func (record *SetBlockRecord) Undo(tx *Transaction) error {
+	if err := tx.Pin(record.block); err != nil {
+		return err
+	}
+	defer tx.Unpin(record.block)
+	for _, run := range record.runs {
+		if err := tx.SetRaw(record.block, run.offset, run.oldVal, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (record *SetBlockRecord) TxNumber() int {
+	return record.txNum
+}
+
+func (record *SetBlockRecord) ToString() string {
+	return fmt.Sprintf("<SETBLOCK %d %s %d %d>",
+		record.txNum, record.filename,
+		record.block.GetBlockNumber(), len(record.runs))
+}
+
+func (record *SetBlockRecord) WriteToLog(lm *log.Manager) (int, error) {
+	recordTypeOffset := 0
+	txNumOffset := recordTypeOffset + constants.IntSize
+	filenameOffset := txNumOffset + constants.IntSize
+	blockNumberOffset := filenameOffset + file.MaxLength(len(record.filename))
+	runCountOffset := blockNumberOffset + constants.IntSize
+	runsOffset := runCountOffset + constants.IntSize
+	recordLength := runsOffset
+	for _, run := range record.runs {
+		recordLength += 2*constants.IntSize + len(run.oldVal)
+	}
+	page := file.NewPageWithBuffer(make([]byte, recordLength))
+	page.SetInt(recordTypeOffset, int(SET_BLOCK))
+	page.SetInt(txNumOffset, record.txNum)
+	if err := page.SetString(filenameOffset, record.filename); err != nil {
+		return -1, err
+	}
+	page.SetInt(blockNumberOffset, record.block.GetBlockNumber())
+	page.SetInt(runCountOffset, len(record.runs))
+	runOffset := runsOffset
+	for _, run := range record.runs {
+		page.SetInt(runOffset, run.offset)
+		page.SetBytes(runOffset+constants.IntSize, run.oldVal)
+		runOffset += 2*constants.IntSize + len(run.oldVal)
+	}
+	return lm.Append(page.Contents())
+}
@@ -0,0 +1,103 @@
+package tx
+
+import (
+	"fmt"
+	"jadb/constants"
+	"jadb/file"
+	"jadb/log"
+)
+
+// SetBytesRecord
+// the bytes of a value written by SetBool, SetDouble, SetTime or SetBytes
+// as they were in the block before and after, so one record type undoes
+// all of them
+type SetBytesRecord struct {
+	txNum       int
+	filename    string
+	block       *file.BlockId
+	blockOffset int
+	oldVal      []byte
+	newVal      []byte
+}
+
+func NewSetBytesRecord(txNum int, block *file.BlockId, offset int, oldVal []byte, newVal []byte) *SetBytesRecord {
+	return &SetBytesRecord{
+		txNum,
+		block.GetFileName(),
+		block,
+		offset,
+		oldVal,
+		newVal,
+	}
+}
+
+func NewSetBytesRecordFromPage(page *file.Page) (*SetBytesRecord, error) {
+	txNumOffset := constants.IntSize
+	txNum := page.GetInt(txNumOffset)
+	filenameOffset := txNumOffset + constants.IntSize
+	filename := page.GetString(filenameOffset)
+	blockNumOffset := filenameOffset + file.MaxLength(len(filename))
+	block := file.NewBlock(filename, page.GetInt(blockNumOffset))
+	blockOffsetOffset := blockNumOffset + constants.IntSize
+	blockOffset := page.GetInt(blockOffsetOffset)
+	oldValOffset := blockOffsetOffset + constants.IntSize
+	oldVal := append([]byte(nil), page.GetBytes(oldValOffset)...)
+	newValOffset := oldValOffset + constants.IntSize + len(oldVal)
+	newVal := append([]byte(nil), page.GetBytes(newValOffset)...)
+	return &SetBytesRecord{
+		txNum,
+		filename,
+		block,
+		blockOffset,
+		oldVal,
+		newVal,
+	}, nil
+}
+
+func (record *SetBytesRecord) Op() LogRecordType {
+	return SET_BYTES
+}
+
+func (record *SetBytesRecord) Undo(tx *Transaction) error {
+	if err := tx.Pin(record.block); err != nil {
+		return err
+	}
+	if err := tx.setRaw(record.block, record.blockOffset, record.oldVal, 0, false); err != nil {
+		return err
+	}
+	tx.Unpin(record.block)
+	return nil
+}
+
+func (record *SetBytesRecord) TxNumber() int {
+	return record.txNum
+}
+
+func (record *SetBytesRecord) ToString() string {
+	return fmt.Sprintf("<SETBYTES %d %s %d %d %x %x>",
+		record.txNum, record.filename,
+		record.block.GetBlockNumber(), record.blockOffset,
+		record.oldVal, record.newVal)
+}
+
+func (record *SetBytesRecord) WriteToLog(lm *log.Manager) (int, error) {
+	recordTypeOffset := 0
+	txNumOffset := recordTypeOffset + constants.IntSize
+	filenameOffset := txNumOffset + constants.IntSize
+	blockNumberOffset := filenameOffset + file.MaxLength(len(record.filename))
+	blockOffsetOffset := blockNumberOffset + constants.IntSize
+	oldValOffset := blockOffsetOffset + constants.IntSize
+	newValOffset := oldValOffset + constants.IntSize + len(record.oldVal)
+	recordLength := newValOffset + constants.IntSize + len(record.newVal)
+	page := file.NewPageWithBuffer(make([]byte, recordLength))
+	page.SetInt(recordTypeOffset, int(SET_BYTES))
+	page.SetInt(txNumOffset, record.txNum)
+	if err := page.SetString(filenameOffset, record.filename); err != nil {
+		return -1, err
+	}
+	page.SetInt(blockNumberOffset, record.block.GetBlockNumber())
+	page.SetInt(blockOffsetOffset, record.blockOffset)
+	page.SetBytes(oldValOffset, record.oldVal)
+	page.SetBytes(newValOffset, record.newVal)
+	return lm.Append(page.Contents())
+}
@@ -1,12 +1,15 @@
 package tx
 
 import (
+	"errors"
 	"fmt"
 	"jadb/buffer"
 	"jadb/concurrency"
+	"jadb/constants"
 	"jadb/file"
 	"jadb/log"
 	"sync"
+	"time"
 )
 
 var nextTxNum = 1
@@ -19,6 +22,7 @@ type Transaction struct {
 	txNum   int
 	cm      *concurrency.Manager
 	buffers *BufferList
+	removed []string
 }
 
 func NewTransaction(fm *file.Manager, lm *log.Manager, bm *buffer.Manager, lt *concurrency.LockTable) (*Transaction, error) {
@@ -48,12 +52,19 @@ func NewTransaction(fm *file.Manager, lm *log.Manager, bm *buffer.Manager, lt *c
 	return tx, nil
 }
 
+// Commit
+// the files removed by the transaction are deleted once the commit is
+// logged, before its locks are released
 func (tx *Transaction) Commit() error {
 	if err := tx.rm.commit(); err != nil {
 		return err
 	}
-	tx.cm.Release()
 	tx.buffers.unpinAll()
+	err := tx.removeFiles()
+	tx.cm.Release()
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Transaction %d committed\n", tx.txNum)
 	return nil
 }
@@ -62,12 +73,27 @@ func (tx *Transaction) Rollback() error {
 	if err := tx.rm.rollback(); err != nil {
 		return err
 	}
+	tx.removed = nil
 	tx.cm.Release()
 	tx.buffers.unpinAll()
 	fmt.Printf("Transaction %d rolledback\n", tx.txNum)
 	return nil
 }
 
+// Savepoint
+// the changes the transaction made so far, for RollbackTo to undo the ones
+// made after
+func (tx *Transaction) Savepoint() int {
+	return tx.rm.logged
+}
+
+// RollbackTo
+// undoes the changes made since the savepoint and goes on with the
+// transaction, its locks kept, for a statement failing part way
+func (tx *Transaction) RollbackTo(savepoint int) error {
+	return tx.rm.rollbackTo(savepoint)
+}
+
 func (tx *Transaction) Recover() error {
 	if err := tx.bm.FlushAll(tx.txNum); err != nil {
 		return err
@@ -156,6 +182,153 @@ func (tx *Transaction) SetString(block *file.BlockId, offset int, newVal string,
 	return nil
 }
 
+func (tx *Transaction) GetBool(block *file.BlockId, offset int) (bool, error) {
+	page, err := tx.readPage(block)
+	if err != nil {
+		return false, err
+	}
+	return page.GetBool(offset), nil
+}
+
+func (tx *Transaction) GetDouble(block *file.BlockId, offset int) (float64, error) {
+	page, err := tx.readPage(block)
+	if err != nil {
+		return 0, err
+	}
+	return page.GetDouble(offset), nil
+}
+
+func (tx *Transaction) GetTime(block *file.BlockId, offset int) (time.Time, error) {
+	page, err := tx.readPage(block)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return page.GetTime(offset), nil
+}
+
+// GetBytes
+// a copy of the bytes, which stay valid after the block is unpinned
+func (tx *Transaction) GetBytes(block *file.BlockId, offset int) ([]byte, error) {
+	page, err := tx.readPage(block)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), page.GetBytes(offset)...), nil
+}
+
+// readPage
+// the page of the block after locking it for reading
+func (tx *Transaction) readPage(block *file.BlockId) (*file.Page, error) {
+	if err := tx.cm.SLock(*block); err != nil {
+		return nil, err
+	}
+	buff, err := tx.buffers.getBuffer(*block)
+	if err != nil {
+		return nil, err
+	}
+	return buff.Contents(), nil
+}
+
+func (tx *Transaction) SetBool(block *file.BlockId, offset int, newVal bool, log bool) error {
+	image := file.NewPage(constants.BoolSize)
+	image.SetBool(0, newVal)
+	return tx.setRaw(block, offset, image.Contents(), constants.BoolSize, log)
+}
+
+func (tx *Transaction) SetDouble(block *file.BlockId, offset int, newVal float64, log bool) error {
+	image := file.NewPage(constants.DoubleSize)
+	image.SetDouble(0, newVal)
+	return tx.setRaw(block, offset, image.Contents(), constants.DoubleSize, log)
+}
+
+func (tx *Transaction) SetTime(block *file.BlockId, offset int, newVal time.Time, log bool) error {
+	image := file.NewPage(constants.IntSize)
+	image.SetTime(0, newVal)
+	return tx.setRaw(block, offset, image.Contents(), constants.IntSize, log)
+}
+
+func (tx *Transaction) SetBytes(block *file.BlockId, offset int, newVal []byte, log bool) error {
+	image := file.NewPage(constants.IntSize + len(newVal))
+	image.SetBytes(0, newVal)
+	oldLength := constants.IntSize
+	if err := tx.cm.XLock(*block); err != nil {
+		return err
+	}
+	buff, err := tx.buffers.getBuffer(*block)
+	if err != nil {
+		return err
+	}
+	//a length read from a block that was never written is cut to the block
+	oldLength += min(max(buff.Contents().GetInt(offset), 0), tx.BlockSize()-offset-constants.IntSize)
+	return tx.setRaw(block, offset, image.Contents(), oldLength, log)
+}
+
+// GetRaw
+// a copy of the length bytes at offset
+func (tx *Transaction) GetRaw(block *file.BlockId, offset int, length int) ([]byte, error) {
+	page, err := tx.readPage(block)
+	if err != nil {
+		return nil, err
+	}
+	return page.GetRaw(offset, length), nil
+}
+
+// SetRaw
+// writes data at offset as it is, the bytes it replaces are logged for undo
+func (tx *Transaction) SetRaw(block *file.BlockId, offset int, data []byte, log bool) error {
+	return tx.setRaw(block, offset, data, len(data), log)
+}
+
+// setRaw
+// writes image at offset, logging it together with the oldLength bytes it
+// replaces. A long image is logged in pieces of a quarter block, so that
+// every log record fits in a page of the log
+func (tx *Transaction) setRaw(block *file.BlockId, offset int, image []byte, oldLength int, log bool) error {
+	if err := tx.cm.XLock(*block); err != nil {
+		return err
+	}
+	buff, err := tx.buffers.getBuffer(*block)
+	if err != nil {
+		return err
+	}
+	lsn := -1
+	if log {
+		piece := tx.BlockSize() / 4
+		for start := 0; start < max(len(image), oldLength); start += piece {
+			newVal := image[min(start, len(image)):min(start+piece, len(image))]
+			oldPiece := min(max(oldLength-start, 0), piece)
+			if lsn, err = tx.rm.setBytes(buff, offset+start, oldPiece, newVal); err != nil {
+				return err
+			}
+		}
+	}
+	buff.Contents().SetRaw(offset, image)
+	buff.SetModified(tx.txNum, lsn)
+	return nil
+}
+
+// SetBlock
+// replaces the whole block with image. What it replaces is logged as one
+// before-image of the block rather than a record for every value
+func (tx *Transaction) SetBlock(block *file.BlockId, image []byte, log bool) error {
+	if err := tx.cm.XLock(*block); err != nil {
+		return err
+	}
+	buff, err := tx.buffers.getBuffer(*block)
+	if err != nil {
+		return err
+	}
+	lsn := -1
+	if log {
+		if lsn, err = tx.rm.setBlock(buff, image); err != nil {
+			return err
+		}
+	}
+	buff.Contents().SetRaw(0, image)
+	buff.SetModified(tx.txNum, lsn)
+	return nil
+}
+
 func (tx *Transaction) Size(filename string) (int, error) {
 	dummyBlock := file.NewBlock(filename, -1)
 	if err := tx.cm.SLock(*dummyBlock); err != nil {
@@ -172,6 +345,73 @@ func (tx *Transaction) Append(filename string) (*file.BlockId, error) {
 	return tx.fm.Append(filename)
 }
 
+// Truncate
+// cuts the file down to its first size blocks right away. The blocks cut
+// off have to be cleared by the transaction with logging before, so a
+// rollback or recovery can append them again and undo the clearing
+func (tx *Transaction) Truncate(filename string, size int) error {
+	dummyBlock := file.NewBlock(filename, -1)
+	if err := tx.cm.XLock(*dummyBlock); err != nil {
+		return err
+	}
+	oldSize, err := tx.Size(filename)
+	if err != nil || oldSize <= size {
+		return err
+	}
+	if err := tx.rm.truncate(filename, oldSize, size); err != nil {
+		return err
+	}
+	if err := tx.bm.DiscardFrom(filename, size); err != nil {
+		return err
+	}
+	return tx.fm.Truncate(filename, size)
+}
+
+// Remove
+// deletes the file when the transaction commits, nothing happens to it if
+// the transaction rolls back. The file is locked until then so no other
+// transaction can use it
+func (tx *Transaction) Remove(filename string) error {
+	dummyBlock := file.NewBlock(filename, -1)
+	if err := tx.cm.XLock(*dummyBlock); err != nil {
+		return err
+	}
+	tx.removed = append(tx.removed, filename)
+	return nil
+}
+
+// removeFiles
+// deletes the removed files and forgets the buffers holding their blocks
+func (tx *Transaction) removeFiles() error {
+	removed := tx.removed
+	tx.removed = nil
+	for _, filename := range removed {
+		if err := tx.bm.Discard(filename); err != nil {
+			return err
+		}
+		if err := tx.fm.Remove(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scrub
+// checks the checksum of every block of every file of the database, the
+// number of blocks checked is returned with an error joining a
+// *file.CorruptionError for every corrupted block
+func (tx *Transaction) Scrub() (int, error) {
+	checked, corrupted, err := tx.fm.Scrub()
+	if err != nil {
+		return 0, err
+	}
+	errs := make([]error, len(corrupted))
+	for i, corruption := range corrupted {
+		errs[i] = corruption
+	}
+	return checked, errors.Join(errs...)
+}
+
 func (tx *Transaction) BlockSize() int {
 	return tx.fm.BlockSize()
 }
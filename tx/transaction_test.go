@@ -11,7 +11,9 @@ import (
 	"jadb/log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -255,3 +257,347 @@ func TestTransactionWriteAndRewrite(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTransactionTypedValues(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(t)
+
+	txn, err := NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(env.dbFile)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(block))
+	when := time.Date(2024, 5, 6, 7, 8, 9, 10000, time.UTC)
+	assert.NoError(txn.SetBool(block, 0, true, true))
+	assert.NoError(txn.SetDouble(block, 8, 1.25, true))
+	assert.NoError(txn.SetTime(block, 16, when, true))
+	assert.NoError(txn.SetBytes(block, 24, []byte{0xca, 0xfe}, true))
+	assert.NoError(txn.Commit())
+
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(block))
+	assert.NoError(txn.SetBool(block, 0, false, true))
+	assert.NoError(txn.SetDouble(block, 8, -3, true))
+	assert.NoError(txn.SetTime(block, 16, when.AddDate(1, 0, 0), true))
+	assert.NoError(txn.SetBytes(block, 24, []byte("a longer value"), true))
+	logIterator, err := env.lm.GetIterator()
+	assert.NoError(err)
+	recordBytes, err := logIterator.Next()
+	assert.NoError(err)
+	logRecord, err := CreateLogRecord(recordBytes)
+	assert.NoError(err)
+	assert.Equal(SET_BYTES, logRecord.Op())
+	assert.Equal(fmt.Sprintf("<SETBYTES %d %s %d %d %x %x>", txn.txNum, env.dbFile, block.GetBlockNumber(), 24,
+		"\x00\x00\x00\x00\x00\x00\x00\x02\xca\xfe", "\x00\x00\x00\x00\x00\x00\x00\x0ea longer value"),
+		logRecord.ToString())
+	assert.NoError(txn.Rollback())
+
+	//the rollback restores every value
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(block))
+	boolVal, err := txn.GetBool(block, 0)
+	assert.NoError(err)
+	assert.True(boolVal)
+	doubleVal, err := txn.GetDouble(block, 8)
+	assert.NoError(err)
+	assert.Equal(1.25, doubleVal)
+	timeVal, err := txn.GetTime(block, 16)
+	assert.NoError(err)
+	assert.Equal(when, timeVal)
+	bytesVal, err := txn.GetBytes(block, 24)
+	assert.NoError(err)
+	assert.Equal([]byte{0xca, 0xfe}, bytesVal)
+	assert.NoError(txn.Commit())
+
+	if err := os.RemoveAll(env.tempDir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransactionTruncate(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(t)
+	filename := "truncate.db"
+
+	txn, err := NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	for i := range 3 {
+		block, err := txn.Append(filename)
+		assert.NoError(err)
+		assert.NoError(txn.Pin(block))
+		assert.NoError(txn.SetInt(block, 8, 100+i, true))
+	}
+	assert.NoError(txn.Commit())
+
+	truncate := func(txn *Transaction) {
+		for i := 1; i < 3; i++ {
+			block := file.NewBlock(filename, i)
+			assert.NoError(txn.Pin(block))
+			assert.NoError(txn.SetInt(block, 8, 0, true))
+			txn.Unpin(block)
+		}
+		assert.NoError(txn.Truncate(filename, 1))
+		size, err := txn.Size(filename)
+		assert.NoError(err)
+		assert.Equal(1, size)
+	}
+	checkRestored := func(txn *Transaction) {
+		size, err := txn.Size(filename)
+		assert.NoError(err)
+		assert.Equal(3, size)
+		for i := range 3 {
+			block := file.NewBlock(filename, i)
+			assert.NoError(txn.Pin(block))
+			val, err := txn.GetInt(block, 8)
+			assert.NoError(err)
+			assert.Equal(100+i, val)
+			txn.Unpin(block)
+		}
+	}
+
+	//a rollback appends the blocks again and undoes their clearing
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	truncate(txn)
+	logIterator, err := env.lm.GetIterator()
+	assert.NoError(err)
+	recordBytes, err := logIterator.Next()
+	assert.NoError(err)
+	logRecord, err := CreateLogRecord(recordBytes)
+	assert.NoError(err)
+	assert.Equal(fmt.Sprintf("<TRUNCATE %d %s 3 1>", txn.txNum, filename), logRecord.ToString())
+	assert.NoError(txn.Rollback())
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	checkRestored(txn)
+	assert.NoError(txn.Commit())
+
+	//so does recovery after a crash, the truncation is on disk already
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	truncate(txn)
+	bm, err := buffer.NewBufferManager(env.fm, env.lm, 10)
+	assert.NoError(err)
+	lt := concurrency.NewLockTable()
+	recovery, err := NewTransaction(env.fm, env.lm, bm, lt)
+	assert.NoError(err)
+	assert.NoError(recovery.Recover())
+	assert.NoError(recovery.Commit())
+	txn, err = NewTransaction(env.fm, env.lm, bm, lt)
+	assert.NoError(err)
+	checkRestored(txn)
+	assert.NoError(txn.Commit())
+
+	if err := os.RemoveAll(env.tempDir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransactionSetBlock(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(t)
+	filename := "setblock.db"
+
+	txn, err := NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	sparse, err := txn.Append(filename)
+	assert.NoError(err)
+	full, err := txn.Append(filename)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(sparse))
+	assert.NoError(txn.Pin(full))
+	for i := range 10 {
+		assert.NoError(txn.SetInt(sparse, 40*i, i+1, true))
+	}
+	assert.NoError(txn.SetString(sparse, 420, "before", true))
+	image := make([]byte, env.blockSize)
+	for i := range image {
+		image[i] = byte(i%255 + 1)
+	}
+	assert.NoError(txn.SetRaw(full, 0, image, true))
+	assert.NoError(txn.Commit())
+
+	checkRestored := func(txn *Transaction) {
+		assert.NoError(txn.Pin(sparse))
+		assert.NoError(txn.Pin(full))
+		for i := range 10 {
+			val, err := txn.GetInt(sparse, 40*i)
+			assert.NoError(err)
+			assert.Equal(i+1, val)
+		}
+		str, err := txn.GetString(sparse, 420)
+		assert.NoError(err)
+		assert.Equal("before", str)
+		raw, err := txn.GetRaw(full, 0, env.blockSize)
+		assert.NoError(err)
+		assert.Equal(image, raw)
+	}
+
+	//clearing a block with a few values takes one record, a full block
+	//takes two as its image does not fit in a page of the log
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(sparse))
+	assert.NoError(txn.Pin(full))
+	savepoint := txn.Savepoint()
+	assert.NoError(txn.SetBlock(sparse, make([]byte, env.blockSize), true))
+	assert.Equal(savepoint+1, txn.Savepoint())
+	logIterator, err := env.lm.GetIterator()
+	assert.NoError(err)
+	recordBytes, err := logIterator.Next()
+	assert.NoError(err)
+	logRecord, err := CreateLogRecord(recordBytes)
+	assert.NoError(err)
+	assert.Equal(fmt.Sprintf("<SETBLOCK %d %s 0 11>", txn.txNum, filename), logRecord.ToString())
+	assert.NoError(txn.SetBlock(full, make([]byte, env.blockSize), true))
+	assert.Equal(savepoint+3, txn.Savepoint())
+	val, err := txn.GetInt(sparse, 40)
+	assert.NoError(err)
+	assert.Equal(0, val)
+	assert.NoError(txn.Rollback())
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	checkRestored(txn)
+	assert.NoError(txn.Commit())
+
+	//recovery undoes the records of a transaction that did not finish
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(sparse))
+	assert.NoError(txn.Pin(full))
+	assert.NoError(txn.SetBlock(sparse, make([]byte, env.blockSize), true))
+	assert.NoError(txn.SetBlock(full, make([]byte, env.blockSize), true))
+	assert.NoError(env.bm.FlushAll(txn.txNum))
+	bm, err := buffer.NewBufferManager(env.fm, env.lm, 10)
+	assert.NoError(err)
+	lt := concurrency.NewLockTable()
+	recovery, err := NewTransaction(env.fm, env.lm, bm, lt)
+	assert.NoError(err)
+	assert.NoError(recovery.Recover())
+	assert.NoError(recovery.Commit())
+	txn, err = NewTransaction(env.fm, env.lm, bm, lt)
+	assert.NoError(err)
+	checkRestored(txn)
+	assert.NoError(txn.Commit())
+
+	if err := os.RemoveAll(env.tempDir); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransactionRollbackTo(t *testing.T) {
+	assert := assertPkg.New(t)
+	env := initEnv(t)
+	filename := "savepoint.db"
+
+	txn, err := NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	block, err := txn.Append(filename)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(block))
+	assert.NoError(txn.SetInt(block, 0, 10, true))
+	savepoint := txn.Savepoint()
+	assert.NoError(txn.SetInt(block, 0, 20, true))
+	assert.NoError(txn.SetString(block, 8, "gone", true))
+
+	//the changes after the savepoint are undone, the transaction goes on
+	assert.NoError(txn.RollbackTo(savepoint))
+	assert.Equal(savepoint, txn.Savepoint())
+	val, err := txn.GetInt(block, 0)
+	assert.NoError(err)
+	assert.Equal(10, val)
+	str, err := txn.GetString(block, 8)
+	assert.NoError(err)
+	assert.Equal("", str)
+	assert.NoError(txn.SetInt(block, 16, 30, true))
+	assert.NoError(txn.Commit())
+
+	txn, err = NewTransaction(env.fm, env.lm, env.bm, env.lt)
+	assert.NoError(err)
+	assert.NoError(txn.Pin(block))
+	val, err = txn.GetInt(block, 0)
+	assert.NoError(err)
+	assert.Equal(10, val)
+	val, err = txn.GetInt(block, 16)
+	assert.NoError(err)
+	assert.Equal(30, val)
+	assert.NoError(txn.Commit())
+
+	if err := os.RemoveAll(env.tempDir); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkCommit(b *testing.B) {
+	cases := []struct {
+		name    string
+		options log.Options
+	}{
+		{"Commit", log.Options{Durability: log.DurableCommit}},
+		{"Group", log.Options{Durability: log.DurableGroup}},
+		{"GroupWindow", log.Options{Durability: log.DurableGroup, GroupWindow: 200 * time.Microsecond, MaxGroup: 16}},
+		{"None", log.Options{Durability: log.DurableNone}},
+	}
+	//commits print, which is not what is measured
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() { os.Stdout = stdout }()
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			directory := filepath.Join(os.TempDir(), "bench")
+			defer os.RemoveAll(directory)
+			fm, err := file.NewFileManager(directory, 500)
+			if err != nil {
+				b.Fatal(err)
+			}
+			lm, err := log.NewLogManagerWithOptions(fm, "bench.log", c.options)
+			if err != nil {
+				b.Fatal(err)
+			}
+			//commits mostly wait on the disk, so many of them run at once,
+			//each on a block of its own
+			parallelism := 16
+			committers := parallelism * runtime.GOMAXPROCS(0)
+			bm, err := buffer.NewBufferManager(fm, lm, committers)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for range committers {
+				if _, err := fm.Append("bench.db"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			lt := concurrency.NewLockTable()
+			var next atomic.Int64
+			b.SetParallelism(parallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				block := file.NewBlock("bench.db", int(next.Add(1)-1))
+				for i := 0; pb.Next(); i++ {
+					txn, err := NewTransaction(fm, lm, bm, lt)
+					if err == nil {
+						err = txn.Pin(block)
+					}
+					if err == nil {
+						err = txn.SetInt(block, 8, i, true)
+					}
+					if err == nil {
+						err = txn.Commit()
+					}
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			b.ReportMetric(float64(lm.Syncs())/float64(b.N), "syncs/commit")
+			if stats := lm.GroupStats(); stats.Syncs > 0 {
+				b.ReportMetric(stats.Average(), "flushes/sync")
+				b.ReportMetric(float64(stats.Largest), "largest")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package tx
+
+import (
+	"fmt"
+	"jadb/constants"
+	"jadb/file"
+	"jadb/log"
+)
+
+// TruncateRecord
+// a file cut down from oldSize to newSize blocks. The blocks were cleared
+// with logging before, so undo only has to append empty blocks again for
+// the records of the clearing to be undone into
+type TruncateRecord struct {
+	txNum    int
+	filename string
+	oldSize  int
+	newSize  int
+}
+
+func NewTruncateRecord(txNum int, filename string, oldSize int, newSize int) *TruncateRecord {
+	return &TruncateRecord{
+		txNum,
+		filename,
+		oldSize,
+		newSize,
+	}
+}
+
+func NewTruncateRecordFromPage(page *file.Page) (*TruncateRecord, error) {
+	txNumOffset := constants.IntSize
+	txNum := page.GetInt(txNumOffset)
+	filenameOffset := txNumOffset + constants.IntSize
+	filename := page.GetString(filenameOffset)
+	oldSizeOffset := filenameOffset + file.MaxLength(len(filename))
+	oldSize := page.GetInt(oldSizeOffset)
+	newSizeOffset := oldSizeOffset + constants.IntSize
+	newSize := page.GetInt(newSizeOffset)
+	return &TruncateRecord{
+		txNum,
+		filename,
+		oldSize,
+		newSize,
+	}, nil
+}
+
+func (record *TruncateRecord) Op() LogRecordType {
+	return TRUNCATE
+}
+
+// Undo
+// appends empty blocks until the file has its old size again
+func (record *TruncateRecord) Undo(tx *Transaction) error {
+	size, err := tx.Size(record.filename)
+	if err != nil {
+		return err
+	}
+	for ; size < record.oldSize; size++ {
+		if _, err := tx.Append(record.filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (record *TruncateRecord) TxNumber() int {
+	return record.txNum
+}
+
+func (record *TruncateRecord) ToString() string {
+	return fmt.Sprintf("<TRUNCATE %d %s %d %d>", record.txNum, record.filename, record.oldSize, record.newSize)
+}
+
+func (record *TruncateRecord) WriteToLog(lm *log.Manager) (int, error) {
+	recordTypeOffset := 0
+	txNumOffset := recordTypeOffset + constants.IntSize
+	filenameOffset := txNumOffset + constants.IntSize
+	oldSizeOffset := filenameOffset + file.MaxLength(len(record.filename))
+	newSizeOffset := oldSizeOffset + constants.IntSize
+	recordLength := newSizeOffset + constants.IntSize
+	page := file.NewPageWithBuffer(make([]byte, recordLength))
+	page.SetInt(recordTypeOffset, int(TRUNCATE))
+	page.SetInt(txNumOffset, record.txNum)
+	if err := page.SetString(filenameOffset, record.filename); err != nil {
+		return -1, err
+	}
+	page.SetInt(oldSizeOffset, record.oldSize)
+	page.SetInt(newSizeOffset, record.newSize)
+	return lm.Append(page.Contents())
+}
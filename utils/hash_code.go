@@ -3,21 +3,36 @@ package utils
 import (
 	"encoding/binary"
 	"hash/fnv"
+	"jadb/record"
+	"math"
+	"time"
 )
 
 func HashCode(key any) (uint32, error) {
 	hashCode := fnv.New32a()
-	switch key.(type) {
+	var buffer []byte
+	switch k := key.(type) {
 	case int64:
-		buffer := make([]byte, 8)
-		binary.BigEndian.PutUint64(buffer, uint64(key.(int64)))
-		if _, err := hashCode.Write(buffer); err != nil {
-			return 0, err
+		buffer = binary.BigEndian.AppendUint64(nil, uint64(k))
+	case int:
+		buffer = binary.BigEndian.AppendUint64(nil, uint64(k))
+	case float64:
+		buffer = binary.BigEndian.AppendUint64(nil, math.Float64bits(k))
+	case time.Time:
+		buffer = binary.BigEndian.AppendUint64(nil, uint64(k.UnixMicro()))
+	case bool:
+		if k {
+			buffer = []byte{1}
+		} else {
+			buffer = []byte{0}
 		}
 	case string:
-		if _, err := hashCode.Write([]byte(key.(string))); err != nil {
-			return 0, err
-		}
+		buffer = []byte(k)
+	case record.Blob:
+		buffer = []byte(k)
+	}
+	if _, err := hashCode.Write(buffer); err != nil {
+		return 0, err
 	}
 	return hashCode.Sum32(), nil
 }